@@ -1,72 +1,283 @@
 package main
 
 import (
-	"encoding/json"
-	"errors"
+	"flag"
 	"fmt"
 	"git.solver4all.com/azaryc2s/op"
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"strings"
 )
 
+var (
+	configs    op.ArrayStringFlags
+	targets    op.ArrayFloatFlags
+	compare    *bool
+	trajectory *bool
+)
+
 func main() {
-	if len(os.Args) < 2 {
+	flag.Var(&configs, "config", "Only analyze the solutions, whose configuration key contains the value. Can be repeated")
+	compare = flag.Bool("compare", false, "Print one row per instance with the solutions of all configurations side by side")
+	trajectory = flag.Bool("trajectory", false, "Print the primal, dual and primal-dual integrals and the times to the target gaps of the trajectories of the solutions")
+	flag.Var(&targets, "target", "Target gap in percent for the times of the trajectory mode. Can be repeated, by default 10, 1 and 0")
+	flag.Parse()
+	if len(targets) == 0 {
+		targets = op.ArrayFloatFlags{10, 1, 0}
+	}
+
+	if flag.NArg() < 1 {
 		log.Printf("No arguments passed!")
 		return
 	}
-	dirName := os.Args[1]
+	dirName := flag.Arg(0)
 	dir, err := ioutil.ReadDir(dirName)
 	if err != nil {
-		log.Printf("Couldn't open directory %s: %s\n", os.Args[1], err.Error())
+		log.Printf("Couldn't open directory %s: %s\n", dirName, err.Error())
 		return
 	}
-	fmt.Printf("Name,Optimal,Time,CMax_Obj,UBound,Gap,Dimension,Comment\n")
+	var (
+		instances []*op.Instance
+		selected  [][]op.Solution
+	)
 	for _, f := range dir {
 		fileName := dirName + "/" + f.Name()
-		if strings.Contains(fileName, ".json") {
-			inst := op.Instance{}
-			instStr, err := ioutil.ReadFile(fileName)
+		if strings.Contains(fileName, ".json") && !strings.HasSuffix(fileName, ".solutions.json") {
+			inst, err := op.LoadInstanceFile(fileName)
 			if err != nil {
 				log.Printf("Couldn't read %s: %s\n", f.Name(), err.Error())
 				return
 			}
-			err = json.Unmarshal(instStr, &inst)
-			if err != nil {
-				log.Printf("Couldn't parse %s: %s\n", f.Name(), err.Error())
-				return
+			if err := op.LoadSidecar(op.SidecarFileName(fileName), inst); err != nil && !os.IsNotExist(err) {
+				log.Printf("Couldn't read the solutions of %s: %s\n", f.Name(), err.Error())
 			}
-			var sol op.Solution
-			if inst.Solution != nil {
-				sol = *inst.Solution
+			instances = append(instances, inst)
+			selected = append(selected, analyze(inst))
+		}
+	}
+	if *compare {
+		printComparison(instances, selected)
+		return
+	}
+	if *trajectory {
+		printTrajectories(instances, selected)
+		return
+	}
+	families := cutFamilies(selected)
+	fmt.Printf("Name,Config,Optimal,Time,CMax_Obj,UBound,Gap,Dimension,Vehicles,%s", statisticsHeader)
+	for _, family := range families {
+		fmt.Printf(",Cuts_%s", family)
+	}
+	fmt.Printf(",Comment\n")
+	for i, inst := range instances {
+		sols := selected[i]
+		if len(sols) == 0 {
+			//the instance is listed even without a solution
+			sols = []op.Solution{analyzeSolution(inst, op.Solution{})}
+		}
+		for _, sol := range sols {
+			key := ""
+			if sol.Config.Command != "" {
+				key = sol.Key()
 			}
-			_, err = getMaxSequenceLength(inst, sol)
-			if err != nil {
-				sol.Comment += fmt.Sprintf("ANALYZER: Error = %s", err.Error())
+			fmt.Printf("%s,%s,%t,%s,%g,%g,%.4f,%d,%d,%s,%s\n", inst.Name, key, sol.Optimal, sol.Time, sol.Obj, sol.UBound, gap(sol), inst.Dimension, inst.GetVehicles(), statisticsColumns(sol.Statistics, families), sol.Comment)
+		}
+	}
+}
+
+//the verified copies of the solutions of the instance, that match the config filters
+func analyze(inst *op.Instance) []op.Solution {
+	var sols []op.Solution
+	for _, sol := range inst.Solutions {
+		if matches(sol.Key()) {
+			sols = append(sols, analyzeSolution(inst, *sol))
+		}
+	}
+	return sols
+}
+
+func analyzeSolution(inst *op.Instance, sol op.Solution) op.Solution {
+	if problems := inst.Validate(); len(problems) > 0 {
+		sol.Comment += fmt.Sprintf("ANALYZER: Invalid instance = %s", problems[0])
+		sol.Verification = &op.Verification{}
+		return sol
+	}
+	verification := op.VerifySolution(inst, &sol)
+	if !verification.Valid {
+		sol.Comment += fmt.Sprintf("ANALYZER: Error = %s", strings.Join(verification.Problems, "; "))
+	}
+	sol.Verification = &verification
+	return sol
+}
+
+func matches(key string) bool {
+	if len(configs) == 0 {
+		return true
+	}
+	for _, config := range configs {
+		if strings.Contains(key, config) {
+			return true
+		}
+	}
+	return false
+}
+
+func gap(sol op.Solution) float64 {
+	return 100.0 * ((sol.Obj - sol.UBound) / sol.UBound)
+}
+
+const statisticsHeader = "MasterCallbacks,NodeCallbacks,MasterSolves,Subproblems,SubproblemTime,Nodes,RootBound,FirstIncumbent,BestIncumbent,Threads,Backend,Flags"
+
+//the cut families of all solutions in alphabetical order
+func cutFamilies(selected [][]op.Solution) []string {
+	var families []string
+	seen := make(map[string]bool)
+	for _, sols := range selected {
+		for _, sol := range sols {
+			if sol.Statistics == nil {
+				continue
+			}
+			for _, cut := range sol.Statistics.Cuts {
+				if !seen[cut.Family] {
+					seen[cut.Family] = true
+					families = append(families, cut.Family)
+				}
 			}
-			gap := 100.0 * (float64(sol.Obj-sol.UBound) / float64(sol.UBound))
-			fmt.Printf("%s,%t,%s,%d,%d,%.4f,%d,%s\n", inst.Name, sol.Optimal, sol.Time, sol.Obj, sol.UBound, gap, inst.Dimension, sol.Comment)
 		}
 	}
+	sort.Strings(families)
+	return families
+}
 
+//the columns of the statisticsHeader followed by the cut counts of the families. They are empty for solutions without
+//statistics
+func statisticsColumns(stats *op.Statistics, families []string) string {
+	if stats == nil {
+		return strings.Repeat(",", strings.Count(statisticsHeader, ",")+len(families))
+	}
+	columns := fmt.Sprintf("%d,%d,%d,%d,%s,%d,%g,%s,%s,%d,%s,%s", stats.MasterCallbacks, stats.NodeCallbacks, stats.MasterSolves, stats.Subproblems, stats.SubproblemTime, stats.Nodes, stats.RootBound, stats.FirstIncumbent, stats.BestIncumbent, stats.Threads, stats.Backend, strings.Join(stats.Flags, " "))
+	for _, family := range families {
+		count := 0
+		for _, cut := range stats.Cuts {
+			if cut.Family == family {
+				count = cut.Count
+			}
+		}
+		columns += fmt.Sprintf(",%d", count)
+	}
+	return columns
 }
 
-func getMaxSequenceLength(inst op.Instance, sol op.Solution) (int, error) {
-	sum := 0
-	edgeWeights := op.CalcEdgeDist(inst.NodeCoordinates, inst.EdgeWeightType)
-	used := make([]bool, inst.Dimension)
-	for i := 0; i < len(sol.Route); i++ {
-		a := sol.Route[i]
-		b := sol.Route[(i+1)%len(sol.Route)]
-		sum += edgeWeights[a][b]
-		if used[a] {
-			return -1, errors.New(fmt.Sprintf("Node %d visited twice!", a))
+//one row per instance with the objective, optimality, time and gap of every configuration and the configurations
+//with the best valid objective
+func printComparison(instances []*op.Instance, selected [][]op.Solution) {
+	var keys []string
+	index := make(map[string]int)
+	for _, sols := range selected {
+		for _, sol := range sols {
+			if _, ok := index[sol.Key()]; !ok {
+				index[sol.Key()] = len(keys)
+				keys = append(keys, sol.Key())
+			}
+		}
+	}
+	fmt.Printf("Name,Dimension,Vehicles")
+	for _, key := range keys {
+		fmt.Printf(",%s Obj,%s Optimal,%s Time,%s Gap", key, key, key, key)
+	}
+	fmt.Printf(",Best\n")
+	for i, inst := range instances {
+		row := make([]*op.Solution, len(keys))
+		for k := range selected[i] {
+			row[index[selected[i][k].Key()]] = &selected[i][k]
+		}
+		fmt.Printf("%s,%d,%d", inst.Name, inst.Dimension, inst.GetVehicles())
+		var (
+			best    []string
+			bestObj float64
+		)
+		for k, sol := range row {
+			if sol == nil {
+				fmt.Printf(",,,,")
+				continue
+			}
+			fmt.Printf(",%g,%t,%s,%.4f", sol.Obj, sol.Optimal, sol.Time, gap(*sol))
+			if !sol.Verification.Valid {
+				continue
+			}
+			better := sol.Obj > bestObj
+			if sol.Minimizes() {
+				better = sol.Obj < bestObj
+			}
+			if len(best) > 0 && op.NearlyEqual(sol.Obj, bestObj) {
+				best = append(best, keys[k])
+			} else if len(best) == 0 || better {
+				best, bestObj = []string{keys[k]}, sol.Obj
+			}
 		}
-		used[a] = true
+		fmt.Printf(",%s\n", strings.Join(best, " "))
 	}
-	if sum > inst.TMax {
-		return -1, errors.New(fmt.Sprintf("Route length %d exceeds the max allowed length %d!", sum, inst.TMax))
+}
+
+//one row per solution with the integrals of its trajectory and the times, at which the gaps reached the targets. The
+//primal and dual gaps are measured against the best valid objective of the analyzed solutions of the instance
+func printTrajectories(instances []*op.Instance, selected [][]op.Solution) {
+	fmt.Printf("Name,Config,Points,End,PrimalIntegral,DualIntegral,PrimalDualIntegral")
+	for _, target := range targets {
+		fmt.Printf(",TimeToGap_%g,TimeToPrimalGap_%g", target, target)
+	}
+	fmt.Printf("\n")
+	for i, inst := range instances {
+		best, hasBest := bestObj(selected[i])
+		for _, sol := range selected[i] {
+			traj := sol.Trajectory
+			if len(traj) == 0 {
+				fmt.Printf("%s,%s,0%s\n", inst.Name, sol.Key(), strings.Repeat(",", 4+2*len(targets)))
+				continue
+			}
+			fmt.Printf("%s,%s,%d,%g,", inst.Name, sol.Key(), len(traj), traj[len(traj)-1].Time)
+			primalGap := func(p op.TrajectoryPoint) float64 {
+				return p.PrimalGap(best)
+			}
+			if hasBest {
+				fmt.Printf("%g,%g,", op.PrimalIntegral(traj, best), op.DualIntegral(traj, best))
+			} else {
+				fmt.Printf(",,")
+			}
+			fmt.Printf("%g", op.PrimalDualIntegral(traj))
+			for _, target := range targets {
+				fmt.Printf(",%s", timeToTarget(traj, op.TrajectoryPoint.PrimalDualGap, target))
+				if hasBest {
+					fmt.Printf(",%s", timeToTarget(traj, primalGap, target))
+				} else {
+					fmt.Printf(",")
+				}
+			}
+			fmt.Printf("\n")
+		}
+	}
+}
+
+//the time to the target gap in percent or an empty column, if the trajectory doesn't reach it
+func timeToTarget(traj []op.TrajectoryPoint, gap func(p op.TrajectoryPoint) float64, target float64) string {
+	if t, ok := op.TimeToTarget(traj, gap, target/100); ok {
+		return fmt.Sprintf("%g", t)
+	}
+	return ""
+}
+
+//the best objective of the valid solutions
+func bestObj(sols []op.Solution) (float64, bool) {
+	best, found := 0.0, false
+	for _, sol := range sols {
+		if !sol.Verification.Valid {
+			continue
+		}
+		if !found || (sol.Minimizes() && sol.Obj < best) || (!sol.Minimizes() && sol.Obj > best) {
+			best, found = sol.Obj, true
+		}
 	}
-	return sum, nil
+	return best, found
 }
@@ -0,0 +1,1340 @@
+/* Copyright 2021, Arkadiusz Zarychta, arkadiusz.zarychta@h-brs.de */
+/* Copyright 2021, Gurobi Optimization, LLC */
+
+package bch
+
+import (
+	"context"
+	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"git.solver4all.com/azaryc2s/op/tsp"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	Y_BOUNDS_CONT = "CONT"
+	Y_BOUNDS_BIN  = "BIN"
+	SEC           = "SEC"
+	OP            = "OP"
+	LBBD          = "LBBD"
+	BCH           = "BCH"
+	TSP           = "TSP"
+	BEND_V0       = "BEND_V0"
+	BEND_V1       = "BEND_V1"
+	BEND_V2       = "BEND_V2"
+	TW            = "TW"
+	LEN           = "LEN"
+)
+
+/* Define structure to pass data to the callback function */
+
+type MasterCallbackData struct {
+	CurrentSolObj float64
+	NewBestSol    bool
+	NodeSequence  []int32
+	TourLength    float64
+	Routes        [][]int32 //the routes of all vehicles of a TOP. NodeSequence is only used for a single vehicle
+}
+
+//add the variables and the constraints of the master to the empty model
+func (r *run) buildModel(model mip.Model) error {
+	var err error
+
+	/* Add variables X_i - one for every node*/
+	log.Println("Adding variables X_i...")
+	r.startX = 0
+	r.varCount = 0
+	for i := 0; i < r.N; i++ {
+		name := fmt.Sprintf("X_%d", i)
+		price := r.pInst.Prices[i]
+		if r.sol.GetObjective() == op.OBJ_PCTSP {
+			//the prize is only constrained
+			price = 0
+		}
+		err = model.AddVar(price, 0.0, 1.0, mip.BINARY, name)
+		if err != nil {
+			return err
+		}
+		r.varCount++
+	}
+	r.startY = r.varCount
+
+	/* Add variables Y_ij - one for every pair of nodes where j > i*/
+	log.Println("Adding variables Y_i_j...")
+	for i := 0; i < r.N; i++ {
+		for j := i + 1; j < r.N; j++ {
+			name := fmt.Sprintf("Y_%d_%d", i, j)
+			var bounds int8
+			if r.opts.YBounds == Y_BOUNDS_BIN {
+				bounds = mip.BINARY
+			} else if r.opts.YBounds == Y_BOUNDS_CONT {
+				bounds = mip.CONTINUOUS
+			}
+			lb, ub := 0.0, 1.0
+			if r.isDepotEdge(i, j) {
+				lb = 1.0
+				if r.vehicles > 1 {
+					//every route of a TOP is closed with its own copy of the edge between the depots
+					ub = float64(r.vehicles)
+					if bounds == mip.BINARY {
+						bounds = mip.INTEGER
+					}
+				}
+			} else if !r.usableEdge(i, j) {
+				ub = 0.0
+			}
+			//the PCTSP minimizes the length as its negative, the PTP subtracts the weighted length from the prize
+			cost := 0.0
+			if r.sol.GetObjective() == op.OBJ_PCTSP {
+				cost = -r.edgeDist.Dist(i, j)
+			} else if r.sol.GetObjective() == op.OBJ_PTP {
+				cost = -r.opts.Lambda * r.edgeDist.Dist(i, j)
+			}
+			err = model.AddVar(cost, lb, ub, bounds, name)
+			if err != nil {
+				return err
+			}
+			r.varCount++
+		}
+	}
+
+	if r.pInst.HasClusters() {
+		/* Add variables Z_c - one for every cluster*/
+		log.Println("Adding variables Z_c...")
+		err = r.addClusterVars(model)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Change objective sense to maximization
+	err = model.SetIntAttr(mip.INT_ATTR_MODELSENSE, mip.MAXIMIZE)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Creating and setting a constraint for the depots to always be used")
+	{
+		ind := []int{r.startX + r.startDepot}
+		val := []float64{1.0}
+		name := fmt.Sprintf("must_depot")
+		err = model.AddConstr(mip.Int32Slice(ind), val, mip.EQUAL, 1, name)
+		if err != nil {
+			log.Println("Error adding must_depot")
+			return err
+		}
+		if r.endDepot != r.startDepot {
+			ind = []int{r.startX + r.endDepot}
+			err = model.AddConstr(mip.Int32Slice(ind), val, mip.EQUAL, 1, "must_end_depot")
+			if err != nil {
+				log.Println("Error adding must_end_depot")
+				return err
+			}
+		}
+	}
+
+	log.Println("Creating and setting constraints for nodes to always be connected to 2 active edges")
+	{
+		for i := 0; i < r.N; i++ {
+			var (
+				ind []int32
+				val []float64
+			)
+			for j := i + 1; j < r.N; j++ {
+				ind = append(ind, int32(r.getYIndex(i, j)))
+				val = append(val, 1.0)
+			}
+			for j := 0; j < i; j++ {
+				ind = append(ind, int32(r.getYIndex(j, i)))
+				val = append(val, 1.0)
+			}
+			if r.vehicles > 1 && (i == r.startDepot || i == r.endDepot) {
+				err = r.addDepotDegreeTOP(model, i, ind, val)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			ind = append(ind, int32(r.startX+i)) //X_i
+			val = append(val, -2.0)
+			err = model.AddConstr(ind, val, mip.EQUAL, 0.0, fmt.Sprintf("node_2_%d", i))
+			if err != nil {
+				log.Printf("Error adding node_2_%d\n", i)
+				return err
+			}
+		}
+	}
+
+	if r.sol.GetObjective() == op.OBJ_PCTSP {
+		log.Println("Creating and setting constraint for the min prize")
+		var (
+			ind []int32
+			val []float64
+		)
+		for i := 0; i < r.N; i++ {
+			ind = append(ind, int32(r.startX+i))
+			val = append(val, r.pInst.Prices[i])
+		}
+		for c := 0; c < len(r.pInst.Clusters); c++ {
+			ind = append(ind, int32(r.startZ+c))
+			val = append(val, r.pInst.ClusterPrices[c])
+		}
+		err = model.AddConstr(ind, val, mip.GREATER_EQUAL, r.opts.MinPrize, "min_prize")
+		if err != nil {
+			log.Printf("Error adding constraint for the min prize")
+			return err
+		}
+	}
+
+	if r.sol.HasTravelBudget() {
+		log.Println("Creating and setting constraint for Tmax")
+		ind, val := r.getBudgetConstr()
+		//all vehicles together can't travel more than their budgets, each route is checked in the callback
+		err = model.AddConstr(ind, val, mip.LESS_EQUAL, r.pInst.TMax*float64(r.vehicles), "travel_budget")
+		if err != nil {
+			log.Printf("Error adding constraint for travel budget")
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *run) cutoffMasterSol(model mip.Model, tourLength float64, tour []int32, subtours [][]int32, objVal float64) {
+	// The master solution cannot be correct. Calculate values for the cut
+	var err error
+	for i := 0; i < len(r.opts.Cuts); i++ {
+		cut := r.opts.Cuts[i]
+		log.Printf("The Master solution with obj %g cannot be correct - Adding a benders cut %s to cut it off\n", objVal, cut)
+		if cut == SEC {
+			secInd, secVal, op, rhs := r.getSECs(r.withoutDepot(subtours, tour))
+			for i := 0; i < len(secInd); i++ {
+				err = model.AddConstr(secInd[i], secVal[i], op, rhs[i], fmt.Sprintf("%s_%d", cut, r.cutCounts[cut]))
+				if err != nil {
+					log.Println(err)
+				} else {
+					r.countCuts(cut, 1)
+				}
+			}
+		}
+		if cut == BEND_V0 {
+			ind, val, op, rhs := r.getBendersCutV0(tour)
+			// Add the benders cut
+			err = model.AddConstr(ind, val, op, rhs, fmt.Sprintf("%s_%d", cut, r.cutCounts[cut]))
+			if err != nil {
+				log.Printf("Error adding benders cut nr %d: %s\n", r.cutCounts[cut], err.Error())
+			} else {
+				r.countCuts(cut, 1)
+			}
+		}
+		if cut == BEND_V1 {
+			ind, val, op, rhs := r.getBendersCutV1(tour, tourLength)
+			// Add the benders cut
+			err = model.AddConstr(ind, val, op, rhs, fmt.Sprintf("%s_%d", cut, r.cutCounts[cut]))
+			if err != nil {
+				log.Printf("Error adding benders cut nr %d: %s\n", r.cutCounts[cut], err.Error())
+			} else {
+				r.countCuts(cut, 1)
+			}
+		}
+		if cut == BEND_V2 {
+			ind, val, op, rhs := r.getBendersCutV2(tour, tourLength, r.pInst.TMax)
+			for i := 0; i < len(ind); i++ {
+				// Add the benders cut
+				err = model.AddConstr(ind[i], val[i], op, rhs[i], fmt.Sprintf("%s_%d", cut, r.cutCounts[cut]))
+				if err != nil {
+					log.Printf("Error adding benders cut nr %d\n", r.cutCounts[cut])
+					return
+				}
+				r.countCuts(cut, 1)
+			}
+		}
+	}
+}
+
+//calculate a heuristic tour with greedy strategy and set it as such for gurobi
+func (r *run) setHeuristicSol(model mip.Model, tour []int32, tourLength float64, tourObj float64, objVal float64) {
+	heurSol, newTourLength, heurObj := tour, tourLength, tourObj
+	if r.sol.HasTravelBudget() {
+		heurSol, newTourLength, heurObj = r.shortenTour(tour, r.edgeDist, r.pInst.Prices, tourLength, r.pInst.TMax, tourObj)
+	}
+
+	if op.Exceeds(heurObj, r.cbData.CurrentSolObj) {
+		r.cbData.CurrentSolObj = heurObj
+		r.cbData.NodeSequence = heurSol
+		r.cbData.TourLength = newTourLength
+
+		if op.NearlyEqual(objVal, heurObj) {
+			//The current master-solution has the same objval as the calculated sequences from ATSP, so the value has been used already before we get the chance to set the solution!
+			log.Printf("The current master-solution has the same objval %g as the calculated sequence from TSP", heurObj)
+			r.cbData.NewBestSol = false
+		} else if objVal > heurObj {
+			//The heuristic solution is worse than the current objval, which means we will cut it off and start over
+			log.Printf("Found new best solution with value %g, while the master solution was invalid", heurObj)
+			r.cbData.NewBestSol = true
+		} else {
+			//The heuristic solution was better, than the master solution (this can happen??) HOW come??
+			log.Printf("Found new best solution with value %g, which is even better than the current master solution!", heurObj)
+			r.cbData.NewBestSol = true
+		}
+	}
+
+	if r.cbData.NewBestSol {
+		log.Printf("Currently setting new heuristic solution: %v with obj-value %.2f\n", r.cbData.NodeSequence, r.cbData.CurrentSolObj)
+		if !r.checkSolutionValidity(r.cbData.NodeSequence, r.cbData.CurrentSolObj, r.cbData.TourLength).Valid {
+			log.Printf("Heuristic solution seems to be invalid!\n")
+		}
+		solution := make([]float64, r.varCount)
+
+		//set the objective (X_i values)
+		for i := 0; i < len(r.cbData.NodeSequence); i++ {
+			solution[int32(r.startX)+r.cbData.NodeSequence[i]] = 1.0
+		}
+
+		//set the constraints (Y_ij values)
+		for i := 0; i < len(r.cbData.NodeSequence); i++ {
+			y := op.GetEdgeIndex(int(r.cbData.NodeSequence[i]), int(r.cbData.NodeSequence[(i+1)%len(r.cbData.NodeSequence)]), r.N, r.startY)
+			solution[y] = 1.0
+		}
+		if r.pInst.HasClusters() {
+			r.setClusterValues(solution)
+		}
+
+		//set the solution
+		err := model.SetDblAttrArray(mip.DBL_ATTR_START, 0, solution)
+
+		//check the error and objv
+		if err != nil {
+			log.Printf("Couldn't set the heuristic solution: %s\n", err.Error())
+		} else {
+			r.cbData.NewBestSol = false
+			log.Printf("New best starting solution with value : %g set!\n", r.cbData.CurrentSolObj)
+		}
+	}
+}
+
+func (r *run) solveByLBBD(ctx context.Context, model mip.Model) {
+	var err error
+	startTime := time.Now()
+	solValid := false
+	r.cbData = MasterCallbackData{NodeSequence: nil, NewBestSol: false, CurrentSolObj: r.getInitialSolObj(), TourLength: 0}
+	r.warmStartSweep(model)
+	err = model.SetCallbackFunc(r.collectStatistics(r.masterCallback), &r.cbData)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	defer r.finishSolution()
+	//the options don't allow checkpoints of the sweep, whose budget constraints and cuts only fit smaller budgets
+	if r.opts.Checkpoint != "" && !r.sweeping {
+		model = checkpointModel{Model: model, run: r}
+		if r.resume != nil {
+			err = r.restoreCheckpoint(model)
+			if err != nil {
+				log.Printf("At %s: %s\n", r.name, err.Error())
+				return
+			}
+			startTime = r.optimizeStart
+		}
+		r.lastCheckpoint = time.Now()
+		defer func() {
+			if !solValid {
+				//the last state is kept, so the LBBD can be resumed after it was stopped
+				r.writeCheckpoint()
+			}
+		}()
+	}
+	for !solValid {
+		if ctx.Err() != nil {
+			r.interruptLBBD(model)
+			break
+		}
+		r.iteration++
+		// Optimize model
+		err = r.optimizeMaster(ctx, model)
+		if err != nil {
+			log.Printf("At %s: %s\n", r.name, err.Error())
+			return
+		}
+
+		// Capture solution information
+		optimstatus, err := model.GetIntAttr(mip.INT_ATTR_STATUS)
+		if err != nil {
+			r.sol.Comment += fmt.Sprintf("Couldn't retrieve optimization status: %s. ", err.Error())
+			log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+			return
+		}
+
+		if optimstatus == mip.OPTIMAL || optimstatus == mip.TIME_LIMIT {
+			objval, err := model.GetDblAttr(mip.DBL_ATTR_OBJVAL)
+			if err != nil {
+				r.sol.Comment += fmt.Sprintf("Couldn't retrieve the obj-value: %s. ", err.Error())
+				log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+				return
+			}
+
+			solA, err := model.GetDblAttrArray(mip.DBL_ATTR_X, 0, int32(r.varCount))
+			if err != nil {
+				r.sol.Comment += fmt.Sprintf("Couldn't retrieve the array with the decision variables: %s. ", err.Error())
+				log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+				return
+			}
+			if r.opts.SubStrat == OP {
+				xMat := r.extractNodeArray(solA)
+				d, p, s, indx := r.transformToOP(xMat)
+				subStart := time.Now()
+				opTour, heurObj, heurTourLength, _, _, err := op.SolveOPPath(r.subEnv, d, p, s, r.pInst.TMax, localIndex(indx, r.startDepot), localIndex(indx, r.endDepot))
+				r.countSubproblem(subStart)
+
+				//translate op tour to global indxs
+				for k := 0; k < len(opTour); k++ {
+					opTour[k] = indx[opTour[k]]
+				}
+				if op.Exceeds(objval, heurObj) {
+					activeNodes := extractActiveNodes(xMat)
+					ind, val, op, rhs := r.getBendersCutOP(activeNodes, heurObj)
+					// Add the benders cut
+					err = model.AddConstr(ind, val, op, rhs, fmt.Sprintf("%s_%d", OP, r.cutCounts[OP]))
+					if err != nil {
+						log.Println(err)
+					} else {
+						r.countCuts(OP, 1)
+					}
+					r.setHeuristicSol(model, mip.Int32Slice(opTour), heurTourLength, heurObj, objval)
+				} else {
+					//the OP-solution does not invalidate the master solution
+					r.cbData.NodeSequence = mip.Int32Slice(opTour)
+					r.cbData.CurrentSolObj = objval
+					r.cbData.TourLength = heurTourLength
+					solValid = true
+					r.sol.Optimal = true
+				}
+
+			}
+			if r.opts.SubStrat == TSP && r.pInst.HasTimeWindows() {
+				tour, tourLength, conflict := r.solveSubproblemTW(solA)
+
+				if tour == nil {
+					ind, val, oper, rhs := r.getBendersCutV0(conflict)
+					err = model.AddConstr(ind, val, oper, rhs, fmt.Sprintf("%s_%d", TW, r.cutCounts[TW]))
+					if err != nil {
+						log.Printf("Error adding time window cut nr %d: %s\n", r.cutCounts[TW], err.Error())
+					} else {
+						r.countCuts(TW, 1)
+					}
+					heurSol, heurTourLength, heurObj := r.shortenTourTW(extractActiveNodes(r.extractNodeArray(solA)), conflict)
+					if heurSol != nil {
+						r.setHeuristicSol(model, heurSol, heurTourLength, heurObj, objval)
+					}
+				} else {
+					//the sequence keeps all time windows, so the master solution is valid
+					r.cbData.NodeSequence = tour
+					r.cbData.CurrentSolObj = objval
+					r.cbData.TourLength = tourLength
+					solValid = true
+					r.sol.Optimal = true
+				}
+			} else if r.opts.SubStrat == TSP && !r.sol.HasTravelBudget() {
+				tour, tourLength, _ := r.solveSubproblem(solA)
+				tourObj := r.getObjectiveValue(tour, tourLength)
+
+				if tour != nil && op.Exceeds(tourLength, r.getMasterLength(solA)) {
+					ind, val, oper, rhs := r.getLengthCut(tour, tourLength)
+					err = model.AddConstr(ind, val, oper, rhs, fmt.Sprintf("%s_%d", LEN, r.cutCounts[LEN]))
+					if err != nil {
+						log.Printf("Error adding length cut nr %d: %s\n", r.cutCounts[LEN], err.Error())
+					} else {
+						r.countCuts(LEN, 1)
+					}
+					r.setHeuristicSol(model, tour, tourLength, tourObj, objval)
+				} else {
+					//the master solution estimates the length of the tour correctly
+					r.cbData.NodeSequence = tour
+					r.cbData.CurrentSolObj = tourObj
+					r.cbData.TourLength = tourLength
+					solValid = true
+					r.sol.Optimal = true
+				}
+			} else if r.opts.SubStrat == TSP {
+				tour, tourLength, subtours := r.solveSubproblem(solA)
+
+				if tour != nil && tourLength >= 0 && op.Exceeds(tourLength+r.getServiceTime(tour), r.pInst.TMax) {
+					r.cutoffMasterSol(model, tourLength, tour, subtours, objval)
+					r.setHeuristicSol(model, tour, tourLength, objval, objval)
+				} else {
+					//the TSP-solution does not invalidate the master solution
+					r.cbData.NodeSequence = tour
+					r.cbData.CurrentSolObj = objval
+					r.cbData.TourLength = tourLength
+					solValid = true
+					r.sol.Optimal = true
+				}
+			}
+
+			r.setSolutionObj(r.cbData.CurrentSolObj, objval)
+			if r.cbData.NodeSequence != nil {
+				r.noteIncumbent(r.cbData.CurrentSolObj)
+			}
+			r.recordTrajectory(objval, r.nodeCount, true)
+			if !solValid {
+				r.checkpointIfDue()
+			}
+
+			if optimstatus == mip.TIME_LIMIT {
+				r.sol.Comment += "Time limit reached"
+				break
+			}
+		} else if optimstatus == mip.INTERRUPTED {
+			r.interruptLBBD(model)
+			break
+		} else if optimstatus == mip.INF_OR_UNBD {
+			fmt.Printf("Model for %s is infeasible or unbounded\n", r.name)
+			break
+		} else {
+			r.sol.Comment += "For some reason the optimization stopped before the time limit without an optimal solution"
+			break
+		}
+
+	}
+	r.sol.Time = time.Since(startTime).String()
+	log.Print("\n---OPTIMIZATION DONE---\n\t Generating and writing result now\n")
+	r.sol.Route = make([]int, len(r.cbData.NodeSequence))
+	for i := 0; i < len(r.cbData.NodeSequence); i++ {
+		r.sol.Route[i] = int(r.cbData.NodeSequence[i])
+	}
+	r.sol.RouteCost = r.cbData.TourLength
+}
+
+//keep the best tour so far with the bound of the master, if the earlier ones weren't tighter. A master cancelled before
+//its optimization or changed by the cuts since has no bound, so the one of the last finished master is kept
+func (r *run) interruptLBBD(model mip.Model) {
+	bound, err := model.GetDblAttr(mip.DBL_ATTR_OBJBOUND)
+	if err == nil {
+		r.recordTrajectory(bound, r.nodeCount, true)
+		r.setSolutionObj(r.cbData.CurrentSolObj, math.Min(bound, r.bestBound))
+	} else if len(r.trajectory) > 0 {
+		r.setSolutionObj(r.cbData.CurrentSolObj, r.bestBound)
+	}
+	r.sol.Comment += "The solve was interrupted"
+	r.sol.Status = op.STATUS_INTERRUPTED
+}
+
+func (r *run) solveByBCH(ctx context.Context, model mip.Model) {
+	var err error
+	/* Set callback function */
+
+	r.cbData = MasterCallbackData{NodeSequence: nil, NewBestSol: false, CurrentSolObj: r.getInitialSolObj(), TourLength: 0}
+	r.warmStartSweep(model)
+	callback := r.masterCallback
+	if r.vehicles > 1 {
+		callback = r.masterCallbackTOP
+	} else if r.pInst.HasTimeWindows() {
+		callback = r.masterCallbackTW
+	} else if !r.sol.HasTravelBudget() {
+		callback = r.masterCallbackLength
+	}
+	if r.sweeping {
+		//the cuts are reused for the smaller budgets
+		callback = r.recordCuts(callback)
+	}
+	err = model.SetCallbackFunc(r.collectStatistics(callback), &r.cbData)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	startTime := time.Now()
+	// Optimize model
+	err = r.optimizeMaster(ctx, model)
+	if err != nil {
+		log.Printf("At %s: %s\n", r.name, err.Error())
+		return
+	}
+
+	r.sol.Time = time.Since(startTime).String()
+	log.Print("\n---OPTIMIZATION DONE---\n\t Generating and writing result now\n")
+	defer r.finishSolution()
+
+	// Capture solution information
+	optimstatus, err := model.GetIntAttr(mip.INT_ATTR_STATUS)
+	if err != nil {
+		r.sol.Comment += fmt.Sprintf("Couldn't retrieve optimization status: %s. ", err.Error())
+		log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+		return
+	}
+
+	if optimstatus == mip.OPTIMAL {
+		r.sol.Optimal = true
+	} else if optimstatus == mip.INF_OR_UNBD {
+		fmt.Printf("Model for %s is infeasible or unbounded\n", r.name)
+	} else if optimstatus == mip.TIME_LIMIT {
+		r.sol.Comment += "Time limit reached"
+	} else if optimstatus == mip.INTERRUPTED {
+		r.sol.Comment += "The solve was interrupted"
+		r.sol.Status = op.STATUS_INTERRUPTED
+	} else {
+		r.sol.Comment += "For some reason the optimization stopped before the time limit without an optimal solution"
+	}
+
+	objval, err := model.GetDblAttr(mip.DBL_ATTR_OBJVAL)
+	if err != nil && optimstatus == mip.INTERRUPTED {
+		//interrupted before the first solution, so only the bound is kept
+		objval, err = r.cbData.CurrentSolObj, nil
+	}
+	if err != nil {
+		r.sol.Comment += fmt.Sprintf("Couldn't retrieve the obj-value: %s. ", err.Error())
+		log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+		return
+	}
+
+	ub := 0.0
+	ub, err = model.GetDblAttr(mip.DBL_ATTR_OBJBOUND)
+	if err != nil {
+		r.sol.Comment += fmt.Sprintf("Couldn't retrieve the lower-bound-value: %s. ", err.Error())
+		log.Println(err)
+	}
+	r.setSolutionObj(objval, ub)
+	r.recordTrajectory(ub, r.nodeCount, true)
+
+	if r.vehicles > 1 {
+		for _, route := range r.cbData.Routes {
+			solRoute := make([]int, len(route))
+			for i := 0; i < len(route); i++ {
+				solRoute[i] = int(route[i])
+			}
+			r.sol.Routes = append(r.sol.Routes, solRoute)
+		}
+		r.sol.RouteCost = r.cbData.TourLength
+		return
+	}
+	r.sol.Route = make([]int, len(r.cbData.NodeSequence))
+	for i := 0; i < len(r.cbData.NodeSequence); i++ {
+		r.sol.Route[i] = int(r.cbData.NodeSequence[i])
+	}
+	r.sol.RouteCost = r.cbData.TourLength
+}
+
+//verify the route with its obj-value and length against the instance and log the result
+func (r *run) checkSolutionValidity(route []int32, obj float64, length float64) op.Verification {
+	check := op.Solution{Obj: r.toSolutionObj(obj), RouteCost: length, Route: make([]int, len(route)), Objective: r.sol.Objective, MinPrize: r.sol.MinPrize, Lambda: r.sol.Lambda}
+	for i := 0; i < len(route); i++ {
+		check.Route[i] = int(route[i])
+	}
+	return r.verifySolution(&check)
+}
+
+//verify the solution against the instance and log the result
+func (r *run) verifySolution(check *op.Solution) op.Verification {
+	verification := op.VerifySolution(&r.pInst, check)
+	if verification.Valid {
+		log.Println("The computed solution is valid!")
+	}
+	for _, problem := range verification.Problems {
+		log.Printf("The computed solution is invalid: %s\n", problem)
+	}
+	return verification
+}
+
+func (r *run) transformToTSP(xMat []float64) ([][]float64, []int) {
+	indx := make([]int, 0)
+	for i := 0; i < len(xMat); i++ {
+		if xMat[i] > 0.5 {
+			indx = append(indx, i)
+		}
+	}
+	d := make([][]float64, len(indx))
+	for j := 0; j < len(d); j++ {
+		a := indx[j]
+		d[j] = make([]float64, len(indx))
+		for k := 0; k < len(d); k++ {
+			if j == k {
+				continue
+			}
+			b := indx[k]
+			d[j][k] = r.edgeDist.Dist(a, b)
+		}
+	}
+	return d, indx
+}
+
+func (r *run) transformToOP(xMat []float64) (d [][]float64, p []float64, s []float64, indx []int) {
+	indx = make([]int, 0)
+	for i := 0; i < len(xMat); i++ {
+		if xMat[i] > 0.5 {
+			indx = append(indx, i)
+		}
+	}
+	d = make([][]float64, len(indx))
+	p = make([]float64, len(indx))
+	s = make([]float64, len(indx))
+	for j := 0; j < len(d); j++ {
+		a := indx[j]
+		d[j] = make([]float64, len(indx))
+		p[j] = r.pInst.Prices[a]
+		s[j] = r.pInst.GetServiceTime(a)
+		for k := 0; k < len(d); k++ {
+			if j == k {
+				continue
+			}
+			b := indx[k]
+			d[j][k] = r.edgeDist.Dist(a, b)
+		}
+	}
+	return d, p, s, indx
+}
+
+func (r *run) solveSubproblem(solArray []float64) ([]int32, float64, [][]int32) {
+	defer r.countSubproblem(time.Now())
+	xMat := r.extractNodeArray(solArray)
+	d, indx := r.transformToTSP(xMat)
+
+	var (
+		tour       []int32
+		tourLength float64
+		subtours   [][]int32
+	)
+	if len(d) == 2 {
+		//there are only 2 nodes assigned, we dont need to solve the tsp
+		tourLength = d[0][1] * 2
+		tour = []int32{0, 1}
+	} else {
+		tour, tourLength, subtours = tsp.SolveTSPPath(r.subEnv, r.name, d, localIndex(indx, r.startDepot), localIndex(indx, r.endDepot))
+		if tour == nil || tourLength < 0 {
+			log.Println("For d the tour was nil. Why??:")
+			op.Print2DArray(d)
+			return nil, -1, nil
+		}
+	}
+
+	//translate tsp tour to global indxs
+	for k := 0; k < len(tour); k++ {
+		tour[k] = int32(indx[tour[k]])
+	}
+	tour = r.orientTour(tour)
+
+	//translate tsp sub-tours to global indxs
+	for j := 0; j < len(subtours); j++ {
+		for k := 0; k < len(subtours[j]); k++ {
+			subtours[j][k] = int32(indx[subtours[j][k]])
+		}
+	}
+
+	return tour, tourLength, subtours
+}
+
+func findIntSubtour(edges [][]int) (result []int) {
+	n := len(edges)
+	seen := make([]bool, n)
+	tour := make([]int, n)
+
+	start := 0
+	bestlen := n + 1
+	bestind := -1
+	i := 0
+	node := 0
+	for start < n {
+		for node = 0; node < n; node++ {
+			if !seen[node] {
+				break
+			}
+		}
+		if node == n {
+			break
+		}
+		isConnected := false
+		subStart := node
+		for leng := 0; leng < n; leng++ {
+			tour[start+leng] = node
+			seen[node] = true
+			for i = 0; i < n; i++ {
+				if edges[node][i] == 1 && !seen[i] {
+					node = i
+					isConnected = true
+					break
+				}
+			}
+			if i == n {
+				leng++
+				if isConnected && leng > 2 && edges[node][subStart] == 1 && leng < bestlen {
+					bestlen = leng
+					bestind = start
+				}
+				start += leng
+				break
+			}
+		}
+	}
+	if bestind >= 0 && bestlen <= n {
+		return tour[bestind : bestind+bestlen]
+	}
+	return nil
+
+}
+
+func (r *run) getBendersCutV0(tour []int32) (ind []int32, val []float64, op int8, rhs float64) {
+	//simply forbid the set of vertices (where X_i = 1)
+	for j := 0; j < len(tour); j++ {
+		ind = append(ind, int32(r.startX)+tour[j]) //this corresponds to the X_i Variables, since those start at 0 and end at n-1
+		val = append(val, 1.0)
+	}
+	rhs = float64(len(tour) - 1)
+	return ind, val, mip.LESS_EQUAL, rhs
+}
+
+/*CALCULATE AND ADD THE COMPLICATED BENDERS CUT
+sum(Y_ij * d_ij) - sum_j(X_j * Theta_j)  >= TSP(V') - sum_j(Theta_j)
+{i,j,k in V' ; i < j < k ; Y_ij = Y_jk = 1}
+V' = subset of V with the nodes that are to be visited (for which the tsp is calculated)*/
+func (r *run) getBendersCutV1(tour []int32, tourLength float64) (ind []int32, val []float64, op int8, rhs float64) {
+	for i := 0; i < len(tour); i++ {
+		for j := i + 1; j < len(tour); j++ {
+			ind = append(ind, int32(r.getYIndex(int(tour[i]), int(tour[j]))))
+			val = append(val, r.edgeDist.Dist(int(tour[i]), int(tour[j])))
+		}
+	}
+
+	edgeSum := 0.0
+	for i := 1; i < len(tour); i++ {
+		//u := i - 1
+		//w := (i + 1) % len(tour)
+		max := 0.0
+		min := -1.0
+		for j := 0; j < len(tour); j++ {
+			next := r.edgeDist.Dist(int(tour[i]), int(tour[j]))
+			if next > max {
+				max = next
+			}
+			if min < 0 || next < min {
+				min = next
+			}
+		}
+		l := max*2 //2x the distance to the furthest node
+		//l := edgeDist.Dist(u, i) + edgeDist.Dist(i, w)
+		edgeSum += l
+		ind = append(ind, int32(r.startX)+tour[i])
+		val = append(val, -l) //we move it on the left side, so minus
+	}
+	return ind, val, mip.GREATER_EQUAL, tourLength - edgeSum
+}
+
+/*CALCULATE AND ADD THE further+ improved BENDERS CUTs
+it holds, that TSP(V') - L_sum <= TSP(V' \ {v_1,...,v_k})
+L_sum = 2* ( (l_1+...+l_k+1) - max(l_1,...,l_k+1))
+but it also already holds for L_sum = (l_1+...+l_k+1)
+*/
+func (r *run) getBendersCutV2(tour []int32, tourLength float64, tmax float64) (ind [][]int32, val [][]float64, oper int8, rhs []float64) {
+	//the service at the removed nodes is saved too
+	service := r.getServiceTime(tour)
+	for i := 1; i < len(tour); i++ {
+		count := 0
+		st := i - 1
+		removedService := 0.0
+
+		currentMax := r.edgeDist.Dist(int(tour[st]), int(tour[i]))
+		var edgesSet []float64
+		edgesSet = append(edgesSet, currentMax) //start with the edge y_j-1_j for the node x_j
+		nodesLeft := make([]int32, len(tour))
+		nodeVal := make([]float64, len(tour))
+		//at the start, we add all nodes from the tour
+		for t := 0; t < len(tour); t++ {
+			nodesLeft[t] = tour[t]
+			nodeVal[t] = 1.0
+		}
+		for j := i; j < len(tour); j++ {
+			k := (j + 1) % len(tour)
+			djk := r.edgeDist.Dist(int(tour[j]), int(tour[k]))
+			edgesSet = append(edgesSet, djk) //add the edge y_j_k for the node x_j
+			removedService += r.getNodeServiceTime(tour[j])
+			if djk > currentMax {
+				currentMax = djk
+			}
+			Lsum1 := 0.0
+			//Variant 1 - remove the longest edge and run over all remaining edges twice
+			for s := 0; s < len(edgesSet); s++ {
+				Lsum1 += edgesSet[s]
+			}
+			Lsum3 := Lsum1 //Variant 3 - simply sum the edges
+			Lsum1 = 2 * (Lsum1 - currentMax)
+
+			Lsum := math.Min(Lsum1, Lsum3)
+
+			//Variant 2 - sum the edges between the nodes, and add the shortest one to the rest + direct edge back
+			Lsum2 := 0.0
+			for s := 1; s < len(edgesSet)-1; s++ {
+				Lsum2 += edgesSet[s]
+			}
+			if r.edgeDist.Dist(int(tour[st]), int(tour[i])) <= djk {
+				Lsum2 += r.edgeDist.Dist(int(tour[st]), int(tour[i]))
+				Lsum2 += r.edgeDist.Dist(int(tour[j]), int(tour[st]))
+			} else {
+				Lsum2 += djk
+				Lsum2 += r.edgeDist.Dist(int(tour[i]), int(tour[k]))
+			}
+
+			Lsum = math.Min(Lsum, Lsum2)
+
+			if !op.Exceeds(tourLength+service-removedService-Lsum, tmax) {
+				//At this point it should hold: TSP(V) - Lsum <= TSP(V\{v_k,...,v_j})
+				//and since we are already under tmax with our lower bound
+				//it could be (maybe) possible to construct a viable TSP if we removed node x_j
+				//so we stop here and forbid the node set V\{v_i,...,v_j-1} since it was still > tmax even for our lower bound
+
+				nodesLeft = append(nodesLeft[:i], nodesLeft[j:]...)
+				nodeVal = append(nodeVal[:i], nodeVal[j:]...)
+				break
+			} else {
+				count++
+			}
+		}
+		ind = append(ind, nodesLeft)
+		val = append(val, nodeVal)
+		rhs = append(rhs, float64(len(nodesLeft)-1))
+	}
+	return ind, val, mip.LESS_EQUAL, rhs
+}
+
+func (r *run) getBendersCutOP(nodes []int, score float64) (ind []int32, val []float64, op int8, rhs float64) {
+	//priceSum := 0
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
+		price := r.pInst.Prices[node]
+
+		ind = append(ind, int32(node))
+		val = append(val, price)
+		//priceSum += price
+	}
+	return ind, val, mip.LESS_EQUAL, score
+}
+
+func (r *run) getSECs(subtours [][]int32) (secInd [][]int32, secVal [][]float64, op int8, rhs []float64) {
+	for _, stour := range subtours {
+		var (
+			ind []int32
+			val []float64
+		)
+
+		/* Add a subtour elimination constraint */
+		for i := 0; i < len(stour); i++ {
+			for j := i + 1; j < len(stour); j++ {
+				if stour[j] > stour[i] {
+					ind = append(ind, int32(r.getYIndex(int(stour[i]), int(stour[j]))))
+				} else {
+					ind = append(ind, int32(r.getYIndex(int(stour[j]), int(stour[i]))))
+				}
+
+			}
+		}
+		for i := 0; i < len(ind); i++ {
+			val = append(val, 1.0)
+		}
+
+		secInd = append(secInd, ind)
+		secVal = append(secVal, val)
+		rhs = append(rhs, float64(len(stour)-1))
+	}
+	return secInd, secVal, mip.LESS_EQUAL, rhs
+}
+
+func (r *run) masterCallback(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+	myData := usrdata.(*MasterCallbackData)
+
+	if where == mip.CB_MIPSOL {
+		r.masterCbCount++
+		solA, err := cbdata.GetDblArray(mip.CB_MIPSOL_SOL, r.varCount)
+		if err != nil {
+			r.sol.Comment += fmt.Sprintf("Couldn't retrieve the array in the callback with the decision variables: %s. ", err.Error())
+			log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+			return 0
+		}
+		objval, err := cbdata.GetDbl(mip.CB_MIPSOL_OBJ)
+		if err != nil {
+			r.sol.Comment += fmt.Sprintf("Couldn't retrieve the obj_value in the callback: %s. ", err.Error())
+			log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+			return 0
+		}
+
+		if !op.Exceeds(objval, myData.CurrentSolObj) {
+			log.Printf("Our current best solution %g is at least as good as the current master solution %g, so we do not solve the subproblem at this point", myData.CurrentSolObj, objval)
+			return 0
+		}
+
+		var (
+			heurObj        float64
+			heurSol        []int32
+			heurTourLength float64
+			opTour         []int
+			tspTour        []int32
+			tspTourLength  float64
+			tspSubtours    [][]int32
+			objSolValid    bool
+		)
+
+		//look for violated integer SECs first, before we solve the TSP. If we find any we add those first instead
+		{
+			edges := r.extractEdgeMatrix(solA)
+			subSolNodes := r.extractNodeArray(solA)
+			nodeCount := 0
+			for i := 0; i < len(subSolNodes); i++ {
+				if subSolNodes[i] > 0.5 { //Counting the nodes to be visited
+					nodeCount++
+				}
+			}
+
+			subtour := findIntSubtour(edges)
+			if subtour == nil || len(subtour) >= nodeCount {
+				if r.opts.YBounds == Y_BOUNDS_BIN || len(subtour) == nodeCount {
+					//we don't need to check any further or solve the tsp
+					heurSol = r.orientTour(mip.Int32Slice(subtour))
+					heurObj = objval
+					heurTourLength = op.GetTourLength(subtour, r.edgeDist)
+					objSolValid = true
+				}
+			}
+		}
+
+		//we stop after the integer SECs for the LBBD strategy
+		if r.opts.Strategy == LBBD {
+			return 0
+		}
+
+		if !objSolValid {
+			if r.opts.SubStrat == OP {
+				//no integer subtours found, we solve the op for the selected nodes to cutoff the solution
+				xMat := r.extractNodeArray(solA)
+
+				d, p, s, indx := r.transformToOP(xMat)
+				subStart := time.Now()
+				opTour, heurObj, heurTourLength, _, _, err = op.SolveOPPath(r.subEnv, d, p, s, r.pInst.TMax, localIndex(indx, r.startDepot), localIndex(indx, r.endDepot))
+				r.countSubproblem(subStart)
+
+				//translate op tour to global indxs
+				for k := 0; k < len(opTour); k++ {
+					opTour[k] = indx[opTour[k]]
+				}
+
+				heurSol = mip.Int32Slice(opTour)
+				activeNodes := extractActiveNodes(xMat)
+				ind, val, op, rhs := r.getBendersCutOP(activeNodes, heurObj)
+				// Add the benders cut
+				err = cbdata.Lazy(ind, val, op, rhs)
+				if err != nil {
+					log.Println(err)
+				} else {
+					r.countCuts(OP, 1)
+				}
+			} else if r.opts.SubStrat == TSP {
+				//no integer subtours found, we solve the tsp
+				tspTour, tspTourLength, tspSubtours = r.solveSubproblem(solA)
+
+				if tspTour != nil && op.Exceeds(tspTourLength+r.getServiceTime(tspTour), r.pInst.TMax) {
+
+					for i := 0; i < len(r.opts.Cuts); i++ {
+						cut := r.opts.Cuts[i]
+						log.Printf("The Master solution with obj %g cannot be correct - Adding a %s to cut it off\n", objval, cut)
+						if cut == SEC {
+							if depotFree := r.withoutDepot(tspSubtours, tspTour); len(depotFree) > 0 {
+								secInd, secVal, op, rhs := op.GetSECs(depotFree, r.N, r.startY)
+								for j := 0; j < len(secInd); j++ {
+									err = cbdata.Lazy(secInd[j], secVal[j], op, rhs[j])
+									if err != nil {
+										log.Println(err)
+									} else {
+										r.countCuts(cut, 1)
+									}
+								}
+							}
+						}
+						if cut == BEND_V0 {
+							ind, val, op, rhs := r.getBendersCutV0(tspTour)
+							// Add the benders cut
+							err = cbdata.Lazy(ind, val, op, rhs)
+							if err != nil {
+								log.Println(err)
+							} else {
+								r.countCuts(cut, 1)
+							}
+						}
+						if cut == BEND_V1 {
+							ind, val, op, rhs := r.getBendersCutV1(tspTour, tspTourLength)
+							// Add the benders cut
+							err = cbdata.Lazy(ind, val, op, rhs)
+							if err != nil {
+								log.Println(err)
+							} else {
+								r.countCuts(cut, 1)
+							}
+						}
+						if cut == BEND_V2 {
+							ind, val, op, rhs := r.getBendersCutV2(tspTour, tspTourLength, r.pInst.TMax)
+							for i := 0; i < len(ind); i++ {
+								// Add the benders cut
+								err = cbdata.Lazy(ind[i], val[i], op, rhs[i])
+								if err != nil {
+									log.Println(err)
+								} else {
+									r.countCuts(cut, 1)
+								}
+							}
+						}
+					}
+
+					//calculate a heuristic tour with greedy strategy
+					heurSol, heurTourLength, heurObj = r.shortenTour(tspTour, r.edgeDist, r.pInst.Prices, tspTourLength, r.pInst.TMax, objval)
+				} else {
+					//the TSP-solution does not invalidate the master solution
+					heurSol = tspTour
+					heurObj = objval
+					heurTourLength = tspTourLength
+				}
+			}
+		}
+
+		//log.Printf("Current tour: %v\n", heurSol)
+		if op.Exceeds(heurObj, myData.CurrentSolObj) {
+			myData.CurrentSolObj = heurObj
+			myData.NodeSequence = heurSol
+			myData.TourLength = heurTourLength
+
+			if op.NearlyEqual(objval, heurObj) {
+				//The current master-solution has the same objval as the calculated sequences from ATSP, so the value has been used already before we get the chance to set the solution!
+				log.Printf("The current master-solution has the same objval %g as the calculated sequence from TSP", heurObj)
+				myData.NewBestSol = false
+			} else if objval > heurObj {
+				//The heuristic solution is worse than the current objval, which means we added some benders cuts
+				log.Printf("Found new best solution with value %g, while the master solution was invalid", heurObj)
+				myData.NewBestSol = true
+			} else {
+				//The heuristic solution was better, than the master solution (this can happen??) HOW come??
+				log.Printf("Found new best solution with value %g, which is even better than the current master solution!", heurObj)
+				myData.NewBestSol = true
+			}
+		}
+
+	}
+
+	if where == mip.CB_MIPNODE {
+		if myData.NewBestSol {
+			objbst, err := cbdata.GetDbl(mip.CB_MIPNODE_OBJBST)
+			if err != nil {
+				r.sol.Comment += fmt.Sprintf("Couldn't retrieve the obj_best in the callback: %s. ", err.Error())
+				log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+				return 0
+			}
+			if !op.Exceeds(myData.CurrentSolObj, objbst) {
+				log.Printf("Current obj is already better than the heuristic solution. Skipping...\n")
+				myData.NewBestSol = false
+				return 0
+			}
+			log.Printf("Currently setting new heuristic solution: %v with obj-value %.2f\n", myData.NodeSequence, myData.CurrentSolObj)
+			solution := make([]float64, r.varCount)
+
+			//set the objective (X_i values)
+			for i := 0; i < len(myData.NodeSequence); i++ {
+				solution[int32(r.startX)+myData.NodeSequence[i]] = 1.0
+			}
+
+			//set the constraints (Y_ij values)
+			for i := 0; i < len(myData.NodeSequence); i++ {
+				y := r.getYIndex(int(myData.NodeSequence[i]), int(myData.NodeSequence[(i+1)%len(myData.NodeSequence)]))
+				solution[y] = 1.0
+			}
+			if r.pInst.HasClusters() {
+				r.setClusterValues(solution)
+			}
+
+			//set the solution
+			val, err := cbdata.Solution(solution)
+
+			//check the error and objv
+			if err != nil {
+				log.Printf("Couldn't set the heuristic solution: %s\n", err.Error())
+			} else {
+				myData.NewBestSol = false
+				log.Printf("New best solution with value : %g set!\n", val)
+			}
+		}
+	}
+	return 0
+}
+
+func (r *run) shortenTour(tour []int32, edgeDist op.DistanceMatrix, prices []float64, tourLength float64, tmax float64, tourObj float64) ([]int32, float64, float64) {
+	//oldTourLength := tourLength
+	service := r.getServiceTime(tour)
+	for op.Exceeds(tourLength+service, tmax) {
+		bestValRatio := 0.0
+		bestValLoss := 0.0
+		bestLengthGained := 0.0
+		bestServiceGained := 0.0
+		bestValAt := 1
+		for j := 1; j < len(tour); j++ {
+			if tour[j] == int32(r.endDepot) {
+				continue
+			}
+			i := j - 1
+			k := (j + 1) % len(tour)
+			lengthGain := edgeDist.Dist(int(tour[i]), int(tour[j])) + edgeDist.Dist(int(tour[j]), int(tour[k])) - edgeDist.Dist(int(tour[i]), int(tour[k]))
+			serviceGain := r.getNodeServiceTime(tour[j])
+			valLoss := prices[tour[j]]
+			if r.pInst.HasClusters() {
+				valLoss = r.getRemovalLoss(tour, tour[j])
+			}
+			valRatio := (lengthGain + serviceGain) / valLoss
+			if valRatio > bestValRatio {
+				bestValRatio = valRatio
+				bestLengthGained = lengthGain
+				bestServiceGained = serviceGain
+				bestValLoss = valLoss
+				bestValAt = j
+			}
+		}
+		tour = append(tour[:bestValAt], tour[bestValAt+1:]...)
+		tourLength -= bestLengthGained
+		service -= bestServiceGained
+		tourObj -= bestValLoss
+		//TODO: apply 3opt after each removal and check if the tour got shorter? (2opt can only remove crossing edges, which we cannot have?)
+	}
+	//log.Printf("Shortened the tour from %d to %d\n", oldTourLength, tourLength)
+	//checkSolutionValidity(tour, edgeDist, prices, tmax, tourObj)
+	return tour, tourLength, tourObj
+}
+
+//complete the solution with the statistics and its verification and keep it as the result of the solve
+func (r *run) finishSolution() {
+	if r.sweeping {
+		//the sweep finishes the solution with all breakpoints at once in the end
+		return
+	}
+	r.restoreNodeIds()
+	r.sol.Statistics = r.collectedStatistics()
+	r.sol.Trajectory = r.trajectory
+	verification := r.verifySolution(&r.sol)
+	r.sol.Verification = &verification
+	result := r.sol
+	r.result = &result
+}
+
+func (r *run) getYIndex(i, j int) int {
+	return op.GetEdgeIndex(i, j, r.N, r.startY)
+}
+
+func (r *run) extractNodeArray(solA []float64) []float64 {
+	return solA[r.startX:r.startY]
+}
+
+func extractActiveNodes(xMat []float64) []int {
+	var activeNodes []int
+	for i := 0; i < len(xMat); i++ {
+		if xMat[i] > 0.5 {
+			activeNodes = append(activeNodes, i)
+		}
+	}
+	return activeNodes
+}
+
+func (r *run) extractEdgeMatrix(solA []float64) [][]int {
+	yMat := make([][]int, r.N)
+	for i := 0; i < r.N; i++ {
+		yMat[i] = make([]int, r.N)
+	}
+	for i := 0; i < r.N; i++ {
+		for j := i + 1; j < r.N; j++ {
+			if solA[r.getYIndex(i, j)] > 0.95 {
+				//log.Printf("Rounding %.5f to 1\n",solA[getYIndex(i, j)])
+				yMat[i][j] = 1
+				yMat[j][i] = 1
+			}
+		}
+	}
+	return yMat
+}
+
+//the coefficients of the travel budget constraint: the edges and the service at the nodes
+func (r *run) getBudgetConstr() (ind []int32, val []float64) {
+	for i := 0; i < r.N; i++ {
+		for j := i + 1; j < r.N; j++ {
+			ind = append(ind, int32(r.getYIndex(i, j)))
+			val = append(val, r.edgeDist.Dist(i, j))
+		}
+	}
+	//the service at the nodes takes time from the budget too
+	for i := 0; i < r.N; i++ {
+		if s := r.getNodeServiceTime(int32(i)); s != 0 {
+			ind = append(ind, int32(r.startX+i))
+			val = append(val, s)
+		}
+	}
+	return ind, val
+}
+
+//the time spent at the nodes of the tour, which counts against the budget
+func (r *run) getServiceTime(tour []int32) float64 {
+	service := 0.0
+	for _, node := range tour {
+		service += r.getNodeServiceTime(node)
+	}
+	return service
+}
+
+//the service time of the node. A path ends with the arrival at the end depot, so its service doesn't count
+func (r *run) getNodeServiceTime(node int32) float64 {
+	if int(node) == r.endDepot && r.startDepot != r.endDepot {
+		return 0
+	}
+	return r.pInst.GetServiceTime(int(node))
+}
+
+func (r *run) isDepotEdge(i, j int) bool {
+	return r.startDepot != r.endDepot && ((i == r.startDepot && j == r.endDepot) || (i == r.endDepot && j == r.startDepot))
+}
+
+//the position of the node in the subset of nodes, which are passed to the subproblem
+func localIndex(indx []int, node int) int {
+	for k := 0; k < len(indx); k++ {
+		if indx[k] == node {
+			return k
+		}
+	}
+	return -1
+}
+
+//replace the subtours containing the start depot by the remaining nodes of the subproblem, which form a subtour too.
+//The SEC of a subtour through the depot would forbid the feasible tour through the same nodes
+func (r *run) withoutDepot(subtours [][]int32, nodes []int32) [][]int32 {
+	var res [][]int32
+	for _, stour := range subtours {
+		inSubtour := make(map[int32]bool)
+		for _, node := range stour {
+			inSubtour[node] = true
+		}
+		if !inSubtour[int32(r.startDepot)] {
+			res = append(res, stour)
+			continue
+		}
+		var rest []int32
+		for _, node := range nodes {
+			if !inSubtour[node] {
+				rest = append(rest, node)
+			}
+		}
+		if len(rest) > 1 {
+			res = append(res, rest)
+		}
+	}
+	return res
+}
+
+//rotate the tour to begin at the start depot and end at the end depot
+func (r *run) orientTour(tour []int32) []int32 {
+	route := make([]int, len(tour))
+	for i := 0; i < len(tour); i++ {
+		route[i] = int(tour[i])
+	}
+	op.OrientRoute(route, r.startDepot, r.endDepot)
+	for i := 0; i < len(tour); i++ {
+		tour[i] = int32(route[i])
+	}
+	return tour
+}
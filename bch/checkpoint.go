@@ -0,0 +1,207 @@
+package bch
+
+import (
+	"encoding/json"
+	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+/* The LBBD adds its cuts to the master as constraints, so the master of an iteration is the model of the instance with
+   all cuts added before. A checkpoint keeps these cuts with the incumbent and the statistics after an iteration. A
+   resumed solve builds the model of the instance again, adds the cuts and continues with the next iteration. The model
+   has to be the same, so the checkpoint only fits the content of the instance and the options it was written for. */
+
+//a constraint added to the master
+type checkpointCut struct {
+	Name  string    `json:"name"`
+	Ind   []int32   `json:"ind"`
+	Val   []float64 `json:"val"`
+	Sense int8      `json:"sense"`
+	Rhs   float64   `json:"rhs"`
+}
+
+//the options, that change the model of the master
+type checkpointConfig struct {
+	Strategy   string   `json:"strategy"`
+	Sweep      bool     `json:"sweep"`
+	SubStrat   string   `json:"sub_strategy"`
+	Cuts       []string `json:"cuts"`
+	YBounds    string   `json:"y_bounds"`
+	Objective  string   `json:"objective"`
+	MinPrize   float64  `json:"min_prize"`
+	Lambda     float64  `json:"lambda"`
+	Preprocess bool     `json:"preprocess"`
+}
+
+//the state of the LBBD after an iteration
+type checkpoint struct {
+	InstanceName string           `json:"instance_name"`
+	InstanceHash string           `json:"instance_hash"`
+	Config       checkpointConfig `json:"config"`
+	Variables    int              `json:"variables"` //the variables of the master without the cuts
+
+	Iteration int             `json:"iteration"`
+	Cuts      []checkpointCut `json:"cuts"`
+
+	//the incumbent in the nodes and the objective of the master
+	Route  []int32 `json:"route"`
+	Obj    float64 `json:"obj"`
+	Length float64 `json:"length"`
+
+	Time            float64               `json:"time"` //seconds since the start of the first solve
+	CutCounts       []op.CutCount         `json:"cut_counts"`
+	MasterSolves    int                   `json:"master_solves"`
+	MasterCallbacks int                   `json:"master_callbacks"`
+	Subproblems     int                   `json:"subproblems"`
+	SubproblemTime  float64               `json:"subproblem_time"` //seconds
+	Nodes           int64                 `json:"nodes"`
+	RootBound       *float64              `json:"root_bound"`
+	BestBound       float64               `json:"best_bound"` //the bound of the master, which the trajectory keeps in the objective of the solution
+	Trajectory      []op.TrajectoryPoint  `json:"trajectory"`
+	Incumbent       *checkpointIncumbents `json:"incumbent_times"`
+}
+
+//the times of the first and the best incumbent in seconds since the start of the first solve
+type checkpointIncumbents struct {
+	First float64 `json:"first"`
+	Best  float64 `json:"best"`
+	Obj   float64 `json:"obj"`
+}
+
+//the master of the LBBD, that remembers the constraints added to it for the checkpoints
+type checkpointModel struct {
+	mip.Model
+	run *run
+}
+
+func (m checkpointModel) AddConstr(ind []int32, val []float64, sense int8, rhs float64, name string) error {
+	err := m.Model.AddConstr(ind, val, sense, rhs, name)
+	if err == nil {
+		m.run.masterCuts = append(m.run.masterCuts, checkpointCut{Name: name, Ind: ind, Val: val, Sense: sense, Rhs: rhs})
+	}
+	return err
+}
+
+func (o Options) checkpointConfig() checkpointConfig {
+	return checkpointConfig{Strategy: o.Strategy, Sweep: o.Sweep, SubStrat: o.SubStrat, Cuts: o.Cuts, YBounds: o.YBounds, Objective: o.Objective, MinPrize: o.MinPrize, Lambda: o.Lambda, Preprocess: o.Preprocess}
+}
+
+//read the checkpoint of the options and check, that it was written for the model
+func (r *run) loadCheckpoint(instHash string) error {
+	file, err := os.Open(r.opts.Checkpoint)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var cp checkpoint
+	err = json.NewDecoder(file).Decode(&cp)
+	if err != nil {
+		return fmt.Errorf("%s: %s", r.opts.Checkpoint, err.Error())
+	}
+	if cp.InstanceHash != instHash {
+		return fmt.Errorf("the checkpoint %s belongs to another content of the instance %s", r.opts.Checkpoint, cp.InstanceName)
+	}
+	if !reflect.DeepEqual(cp.Config, r.opts.checkpointConfig()) || cp.Variables != r.varCount {
+		return fmt.Errorf("the checkpoint %s was written with other options: %+v", r.opts.Checkpoint, cp.Config)
+	}
+	r.resume = &cp
+	return nil
+}
+
+//add the cuts of the checkpoint to the master and continue with its incumbent and statistics
+func (r *run) restoreCheckpoint(model mip.Model) error {
+	cp := r.resume
+	for _, cut := range cp.Cuts {
+		err := model.AddConstr(cut.Ind, cut.Val, cut.Sense, cut.Rhs, cut.Name)
+		if err != nil {
+			return fmt.Errorf("adding the cut %s of the checkpoint: %s", cut.Name, err.Error())
+		}
+	}
+	r.iteration = cp.Iteration
+	for _, count := range cp.CutCounts {
+		r.cutCounts[count.Family] = count.Count
+	}
+	r.masterSolves = cp.MasterSolves
+	r.masterCbCount = cp.MasterCallbacks
+	r.subproblems = cp.Subproblems
+	r.subproblemTime = seconds(cp.SubproblemTime)
+	r.nodeCount = cp.Nodes
+	if cp.RootBound != nil {
+		r.rootBound, r.rootBoundSet = *cp.RootBound, true
+	}
+	r.trajectory, r.bestBound = cp.Trajectory, cp.BestBound
+	//the times of the statistics go on from the ones of the checkpoint
+	r.optimizeStart = time.Now().Add(-seconds(cp.Time))
+	if cp.Incumbent != nil {
+		r.incumbentObj, r.incumbentSet = cp.Incumbent.Obj, true
+		r.firstIncumbent, r.bestIncumbent = seconds(cp.Incumbent.First), seconds(cp.Incumbent.Best)
+	}
+	if len(cp.Route) > 0 {
+		r.cbData = MasterCallbackData{NodeSequence: cp.Route, CurrentSolObj: cp.Obj, TourLength: cp.Length, NewBestSol: true}
+		r.setHeuristicSol(model, cp.Route, cp.Length, cp.Obj, cp.Obj)
+	}
+	log.Printf("Resuming the LBBD after iteration %d with %d cuts and the incumbent %g from %s\n", cp.Iteration, len(cp.Cuts), cp.Obj, r.opts.Checkpoint)
+	return nil
+}
+
+//write a checkpoint, if the last one is older than the interval
+func (r *run) checkpointIfDue() {
+	if r.opts.Checkpoint == "" || time.Since(r.lastCheckpoint) < r.opts.CheckpointInterval {
+		return
+	}
+	r.writeCheckpoint()
+}
+
+//write the checkpoint of the current iteration. It replaces the last one only after it's written completely, so a
+//process, that dies meanwhile, leaves the last one intact
+func (r *run) writeCheckpoint() {
+	if r.opts.Checkpoint == "" {
+		return
+	}
+	r.lastCheckpoint = time.Now()
+	cp := checkpoint{InstanceName: r.name, InstanceHash: r.instHash, Config: r.opts.checkpointConfig(), Variables: r.varCount,
+		Iteration: r.iteration, Cuts: r.masterCuts, Route: r.cbData.NodeSequence, Obj: r.cbData.CurrentSolObj, Length: r.cbData.TourLength,
+		Time: time.Since(r.optimizeStart).Seconds(), CutCounts: r.collectedStatistics().Cuts, MasterSolves: r.masterSolves, MasterCallbacks: r.masterCbCount,
+		Subproblems: r.subproblems, SubproblemTime: r.subproblemTime.Seconds(), Nodes: r.nodeCount, Trajectory: r.trajectory, BestBound: r.bestBound}
+	if r.rootBoundSet {
+		cp.RootBound = &r.rootBound
+	}
+	if r.incumbentSet {
+		cp.Incumbent = &checkpointIncumbents{First: r.firstIncumbent.Seconds(), Best: r.bestIncumbent.Seconds(), Obj: r.incumbentObj}
+	}
+	err := saveCheckpoint(r.opts.Checkpoint, &cp)
+	if err != nil {
+		log.Printf("At %s: writing the checkpoint: %s\n", r.name, err.Error())
+		return
+	}
+	log.Printf("Wrote the checkpoint of iteration %d with %d cuts to %s\n", r.iteration, len(r.masterCuts), r.opts.Checkpoint)
+}
+
+func saveCheckpoint(fileName string, cp *checkpoint) error {
+	file, err := ioutil.TempFile(filepath.Dir(fileName), filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+	err = op.EncodeJson(file, cp)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), fileName)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,156 @@
+package bch
+
+import (
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//a master, that only records the constraints added to it
+type cutModel struct {
+	mip.Model
+	cuts []checkpointCut
+}
+
+func (m *cutModel) AddConstr(ind []int32, val []float64, sense int8, rhs float64, name string) error {
+	m.cuts = append(m.cuts, checkpointCut{Name: name, Ind: ind, Val: val, Sense: sense, Rhs: rhs})
+	return nil
+}
+
+//a run of the LBBD writing its checkpoints to the file
+func checkpointRun(t *testing.T, fileName string) *run {
+	inst := op.Instance{Name: "checkpoint", Dimension: 3, EdgeWeightType: op.EUC_2D, NodeCoordinates: [][]float64{{0, 0}, {1, 1}, {3, 4}}, Prices: []float64{0, 2, 0}, TMax: 10}
+	hash, err := inst.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Strategy: LBBD, Cuts: []string{SEC}, Checkpoint: fileName, CheckpointInterval: time.Hour}
+	return &run{opts: opts.withDefaults(), name: inst.Name, pInst: inst, instHash: hash, varCount: 6, cutCounts: make(map[string]int)}
+}
+
+func TestCheckpoint(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "lbbd.checkpoint")
+	r := checkpointRun(t, fileName)
+	r.iteration, r.masterSolves, r.subproblems, r.nodeCount = 4, 5, 7, 42
+	r.cutCounts[SEC] = 2
+	r.masterCuts = []checkpointCut{
+		{Name: "sec_0", Ind: []int32{3, 4}, Val: []float64{1, 1}, Sense: mip.LESS_EQUAL, Rhs: 1},
+		{Name: "sec_1", Ind: []int32{4, 5}, Val: []float64{1, 1}, Sense: mip.LESS_EQUAL, Rhs: 1},
+	}
+	r.rootBound, r.rootBoundSet = 2, true
+	r.incumbentObj, r.incumbentSet, r.firstIncumbent, r.bestIncumbent = 2, true, time.Second, 2*time.Second
+	r.trajectory = []op.TrajectoryPoint{{Time: 1, Incumbent: 2, HasIncumbent: true, Bound: 3, Nodes: 1}}
+	r.bestBound = 3
+	r.optimizeStart = time.Now().Add(-time.Minute)
+	r.writeCheckpoint()
+
+	//the next checkpoint isn't due before the interval
+	r.iteration = 5
+	r.checkpointIfDue()
+
+	resumed := checkpointRun(t, fileName)
+	if err := resumed.loadCheckpoint(resumed.instHash); err != nil {
+		t.Fatal(err)
+	}
+	model := &cutModel{}
+	if err := resumed.restoreCheckpoint(model); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(model.cuts, r.masterCuts) {
+		t.Errorf("restored the cuts %v, expected %v", model.cuts, r.masterCuts)
+	}
+	if resumed.iteration != 4 {
+		t.Errorf("resumed after iteration %d, expected the iteration 4 of the last checkpoint", resumed.iteration)
+	}
+	if !reflect.DeepEqual(resumed.cutCounts, r.cutCounts) || resumed.masterSolves != r.masterSolves || resumed.subproblems != r.subproblems ||
+		resumed.nodeCount != r.nodeCount || resumed.rootBound != r.rootBound || !resumed.rootBoundSet || resumed.bestBound != r.bestBound ||
+		!reflect.DeepEqual(resumed.trajectory, r.trajectory) {
+		t.Errorf("restored the statistics %+v, expected %+v", resumed.collectedStatistics(), r.collectedStatistics())
+	}
+	if !resumed.incumbentSet || resumed.incumbentObj != r.incumbentObj || resumed.firstIncumbent != r.firstIncumbent || resumed.bestIncumbent != r.bestIncumbent {
+		t.Errorf("restored the incumbent %g after %s and %s", resumed.incumbentObj, resumed.firstIncumbent, resumed.bestIncumbent)
+	}
+	//the time goes on from the one of the checkpoint
+	if elapsed := time.Since(resumed.optimizeStart); elapsed < time.Minute || elapsed > 2*time.Minute {
+		t.Errorf("resumed after %s, expected a minute", elapsed)
+	}
+
+	//only the checkpoint itself is left, not the temporary files it was written to
+	files, err := ioutil.ReadDir(filepath.Dir(fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("%d files next to the checkpoint", len(files)-1)
+	}
+}
+
+func TestLoadCheckpointMismatch(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "lbbd.checkpoint")
+	checkpointRun(t, fileName).writeCheckpoint()
+	broken := filepath.Join(dir, "broken.checkpoint")
+	if err := ioutil.WriteFile(broken, []byte(`{"iteration": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T, r *run)
+	}{
+		{"other instance", func(t *testing.T, r *run) {
+			r.pInst.Prices[1] = 3
+			hash, err := r.pInst.Hash()
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.instHash = hash
+		}},
+		{"other strategy", func(t *testing.T, r *run) { r.opts.Strategy = BCH }},
+		{"sweep", func(t *testing.T, r *run) { r.opts.Sweep = true }},
+		{"other cuts", func(t *testing.T, r *run) { r.opts.Cuts = []string{SEC, BEND_V0} }},
+		{"other objective", func(t *testing.T, r *run) { r.opts.Objective = op.OBJ_PCTSP }},
+		{"other variables", func(t *testing.T, r *run) { r.varCount++ }},
+		{"broken file", func(t *testing.T, r *run) { r.opts.Checkpoint = broken }},
+		{"missing file", func(t *testing.T, r *run) { r.opts.Checkpoint = filepath.Join(dir, "missing.checkpoint") }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := checkpointRun(t, fileName)
+			test.change(t, r)
+			if err := r.loadCheckpoint(r.instHash); err == nil {
+				t.Errorf("loaded the checkpoint")
+			}
+			if r.resume != nil {
+				t.Errorf("resumes the checkpoint")
+			}
+		})
+	}
+}
+
+//a checkpoint, that can't replace its file, leaves the file and no temporary file
+func TestSaveCheckpointFailure(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "lbbd.checkpoint")
+	if err := os.Mkdir(fileName, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(fileName, "cuts"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveCheckpoint(fileName, &checkpoint{Iteration: 1}); err == nil {
+		t.Errorf("replaced the directory with the checkpoint")
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || !files[0].IsDir() {
+		t.Errorf("%d files instead of the directory", len(files))
+	}
+}
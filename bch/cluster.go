@@ -0,0 +1,77 @@
+package bch
+
+import (
+	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"log"
+)
+
+/* In the set orienteering problem (Set-OP) the prizes belong to clusters of nodes. The variable Z_c of a cluster can
+   only be 1, if at least one of its nodes is visited, and collects the prize of the cluster once. The subproblems and
+   the cuts only depend on the selected nodes, so they stay the same as for the OP. */
+
+//add the variables Z_c and the constraints linking them to the X_i of the nodes in the cluster
+func (r *run) addClusterVars(model mip.Model) error {
+	r.startZ = r.varCount
+	for c := 0; c < len(r.pInst.Clusters); c++ {
+		price := r.pInst.ClusterPrices[c]
+		if r.sol.GetObjective() == op.OBJ_PCTSP {
+			//the prize is only constrained
+			price = 0
+		}
+		err := model.AddVar(price, 0.0, 1.0, mip.BINARY, fmt.Sprintf("Z_%d", c))
+		if err != nil {
+			return err
+		}
+		r.varCount++
+	}
+	for c, cluster := range r.pInst.Clusters {
+		ind := []int32{int32(r.startZ + c)}
+		val := []float64{1.0}
+		for _, node := range cluster {
+			ind = append(ind, int32(r.startX+node))
+			val = append(val, -1.0)
+		}
+		err := model.AddConstr(ind, val, mip.LESS_EQUAL, 0.0, fmt.Sprintf("cluster_%d", c))
+		if err != nil {
+			log.Printf("Error adding cluster_%d\n", c)
+			return err
+		}
+	}
+	return nil
+}
+
+//set the variables Z_c of the solution for the clusters with at least one visited node
+func (r *run) setClusterValues(solution []float64) {
+	for c, cluster := range r.pInst.Clusters {
+		for _, node := range cluster {
+			if solution[r.startX+node] > 0.5 {
+				solution[r.startZ+c] = 1.0
+				break
+			}
+		}
+	}
+}
+
+//the prize of the nodes in the tour together with the prize of the clusters they visit
+func (r *run) getTourPrize(tour []int32) float64 {
+	visited := make([]bool, r.N)
+	prize := 0.0
+	for _, node := range tour {
+		visited[node] = true
+		prize += r.pInst.Prices[node]
+	}
+	return prize + r.pInst.GetClusterPrize(visited)
+}
+
+//the prize the tour loses without the node: its price and the prize of its clusters, that no other node visits
+func (r *run) getRemovalLoss(tour []int32, node int32) float64 {
+	without := make([]int32, 0, len(tour))
+	for _, other := range tour {
+		if other != node {
+			without = append(without, other)
+		}
+	}
+	return r.getTourPrize(tour) - r.getTourPrize(without)
+}
@@ -0,0 +1,161 @@
+package bch
+
+import (
+	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"log"
+	"math"
+)
+
+/* The PCTSP and the PTP count the length of the tour in the objective, which the master estimates with its edges.
+   The estimate is only correct, if the edges form the shortest tour through the selected nodes. Otherwise the TSP
+   through the nodes is solved and a length cut makes the master pay at least its length for these nodes.
+   The model is always maximized, so the length of the PCTSP is minimized as its negative. */
+
+//the objective of the maximized model for the tour through the nodes with the given length
+func (r *run) getObjectiveValue(tour []int32, tourLength float64) float64 {
+	prize := r.getTourPrize(tour)
+	if r.sol.GetObjective() == op.OBJ_PCTSP {
+		return -tourLength
+	}
+	return r.sol.ObjectiveValue(prize, tourLength)
+}
+
+//the objective value of the maximized model before any solution was found. With the length it can be negative
+func (r *run) getInitialSolObj() float64 {
+	if r.sol.HasTravelBudget() {
+		return 0
+	}
+	return -math.MaxFloat64
+}
+
+//the objective of the solution for the objective value of the maximized model
+func (r *run) toSolutionObj(obj float64) float64 {
+	if r.sol.GetObjective() == op.OBJ_PCTSP {
+		return -obj
+	}
+	return obj
+}
+
+//set the objective value and the bounds of the solution from the best objective value and bound of the maximized model
+func (r *run) setSolutionObj(obj float64, bound float64) {
+	r.sol.Obj = r.toSolutionObj(obj)
+	if r.sol.GetObjective() == op.OBJ_PCTSP {
+		r.sol.LBound = r.toSolutionObj(bound)
+		r.sol.UBound = r.sol.Obj
+		return
+	}
+	r.sol.LBound = r.sol.Obj
+	r.sol.UBound = bound
+}
+
+//the length of the tour the master solution estimates with its edges
+func (r *run) getMasterLength(solA []float64) float64 {
+	length := 0.0
+	for i := 0; i < r.N; i++ {
+		for j := i + 1; j < r.N; j++ {
+			length += r.edgeDist.Dist(i, j) * solA[r.getYIndex(i, j)]
+		}
+	}
+	return length
+}
+
+/*CALCULATE THE LENGTH CUT
+sum(Y_ij * d_ij) - sum_j(X_j * Theta_j) >= TSP(V') - sum_j(Theta_j)
+over all edges of the model. Removing node j from a tour through (a subset of) V' saves at most Theta_j = 2 * the
+distance to the furthest node of V', so every tour visiting the nodes of V' (and maybe others) is at least as long.*/
+func (r *run) getLengthCut(tour []int32, tourLength float64) (ind []int32, val []float64, oper int8, rhs float64) {
+	for i := 0; i < r.N; i++ {
+		for j := i + 1; j < r.N; j++ {
+			ind = append(ind, int32(r.getYIndex(i, j)))
+			val = append(val, r.edgeDist.Dist(i, j))
+		}
+	}
+	thetaSum := 0.0
+	for _, node := range tour {
+		if int(node) == r.startDepot || int(node) == r.endDepot {
+			continue
+		}
+		theta := 0.0
+		for _, other := range tour {
+			if r.edgeDist.Dist(int(node), int(other)) > theta {
+				theta = r.edgeDist.Dist(int(node), int(other))
+			}
+		}
+		theta *= 2
+		thetaSum += theta
+		ind = append(ind, int32(r.startX)+node)
+		val = append(val, -theta) //we move it on the left side, so minus
+	}
+	return ind, val, mip.GREATER_EQUAL, tourLength - thetaSum
+}
+
+func (r *run) masterCallbackLength(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+	if where != mip.CB_MIPSOL {
+		//the heuristic solutions are set like for the OP
+		return r.masterCallback(model, cbdata, where, usrdata)
+	}
+	myData := usrdata.(*MasterCallbackData)
+
+	r.masterCbCount++
+	solA, err := cbdata.GetDblArray(mip.CB_MIPSOL_SOL, r.varCount)
+	if err != nil {
+		r.sol.Comment += fmt.Sprintf("Couldn't retrieve the array in the callback with the decision variables: %s. ", err.Error())
+		log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+		return 0
+	}
+	objval, err := cbdata.GetDbl(mip.CB_MIPSOL_OBJ)
+	if err != nil {
+		r.sol.Comment += fmt.Sprintf("Couldn't retrieve the obj_value in the callback: %s. ", err.Error())
+		log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+		return 0
+	}
+
+	if !op.Exceeds(objval, myData.CurrentSolObj) {
+		log.Printf("Our current best solution %g is at least as good as the current master solution %g, so we do not solve the subproblem at this point", myData.CurrentSolObj, objval)
+		return 0
+	}
+
+	//subtours without the depots are cut off first, before we solve the TSP
+	if subtours := r.depotFreeSubtours(r.extractEdgeMatrix(solA)); len(subtours) > 0 {
+		secInd, secVal, oper, rhs := op.GetSECs(subtours, r.N, r.startY)
+		for i := 0; i < len(secInd); i++ {
+			err = cbdata.Lazy(secInd[i], secVal[i], oper, rhs[i])
+			if err != nil {
+				log.Println(err)
+			} else {
+				r.countCuts(SEC, 1)
+			}
+		}
+		return 0
+	}
+
+	tour, tourLength, _ := r.solveSubproblem(solA)
+	if tour == nil {
+		return 0
+	}
+	heurObj := r.getObjectiveValue(tour, tourLength)
+	if masterLength := r.getMasterLength(solA); op.Exceeds(tourLength, masterLength) {
+		log.Printf("The Master solution with obj %g estimates a length of %g instead of %g - Adding a length cut to cut it off\n", objval, masterLength, tourLength)
+		ind, val, oper, rhs := r.getLengthCut(tour, tourLength)
+		err = cbdata.Lazy(ind, val, oper, rhs)
+		if err != nil {
+			log.Println(err)
+		} else {
+			r.countCuts(LEN, 1)
+		}
+	}
+
+	if op.Exceeds(heurObj, myData.CurrentSolObj) {
+		myData.CurrentSolObj = heurObj
+		myData.NodeSequence = tour
+		myData.TourLength = tourLength
+		//the tour only has to be set as a solution, if it's better than the master solution
+		myData.NewBestSol = !op.NearlyEqual(objval, heurObj)
+		if myData.NewBestSol {
+			log.Printf("Found new best solution with value %g, while the master solution has the value %g", heurObj, objval)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package bch
+
+import (
+	"git.solver4all.com/azaryc2s/op"
+	"log"
+)
+
+//remove the nodes, that no route within the budget can visit, from pInst and count the edges, that no route can use.
+//Removing nodes can make the shortest paths longer, so it's repeated until all nodes are usable.
+func (r *run) preprocess(dist op.DistanceMatrix) (op.DistanceMatrix, error) {
+	r.origInst = r.pInst
+	n := r.pInst.Dimension
+	r.nodeIds = make([]int, n)
+	for i := 0; i < n; i++ {
+		r.nodeIds[i] = i
+	}
+	r.reach = r.pInst.GetReachability(dist)
+	for nodes := r.reach.UsableNodes(); len(nodes) < r.pInst.Dimension; nodes = r.reach.UsableNodes() {
+		reduced, err := r.pInst.SubInstance(nodes)
+		if err != nil {
+			return nil, err
+		}
+		for k, node := range nodes {
+			r.nodeIds[k] = r.nodeIds[node]
+		}
+		r.nodeIds = r.nodeIds[:len(nodes)]
+		r.pInst = *reduced
+		dist, err = r.pInst.GetDistanceMatrix()
+		if err != nil {
+			return nil, err
+		}
+		r.reach = r.pInst.GetReachability(dist)
+	}
+
+	reduction := op.Reduction{Nodes: n, Edges: n * (n - 1) / 2}
+	kept := make([]bool, n)
+	for _, node := range r.nodeIds {
+		kept[node] = true
+	}
+	for node := 0; node < n; node++ {
+		if !kept[node] {
+			reduction.RemovedNodes = append(reduction.RemovedNodes, node)
+		}
+	}
+	m := r.pInst.Dimension
+	reduction.RemovedEdges = reduction.Edges - m*(m-1)/2
+	for i := 0; i < m; i++ {
+		for j := i + 1; j < m; j++ {
+			if !r.reach.EdgeUsable(i, j) {
+				reduction.RemovedEdges++
+			}
+		}
+	}
+	r.sol.Reduction = &reduction
+	log.Printf("Preprocessing removed %d of %d nodes and %d of %d edges\n", len(reduction.RemovedNodes), reduction.Nodes, reduction.RemovedEdges, reduction.Edges)
+	return dist, nil
+}
+
+//the edge can be part of a route within the budget
+func (r *run) usableEdge(i, j int) bool {
+	return r.reach == nil || r.reach.EdgeUsable(i, j)
+}
+
+//translate the routes of the solution back to the nodes of the input instance, which is the one written with it
+func (r *run) restoreNodeIds() {
+	if r.nodeIds == nil {
+		return
+	}
+	restore := func(route []int) []int {
+		if route == nil {
+			return nil
+		}
+		res := make([]int, len(route))
+		for k, node := range route {
+			res[k] = r.nodeIds[node]
+		}
+		return res
+	}
+	r.sol.Route = restore(r.sol.Route)
+	for v := range r.sol.Routes {
+		r.sol.Routes[v] = restore(r.sol.Routes[v])
+	}
+	for k := range r.sol.Front {
+		r.sol.Front[k].Route = restore(r.sol.Front[k].Route)
+	}
+	r.pInst = r.origInst
+	r.nodeIds = nil
+}
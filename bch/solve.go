@@ -0,0 +1,336 @@
+/* Copyright 2021, Arkadiusz Zarychta, arkadiusz.zarychta@h-brs.de */
+
+// Package bch solves orienteering problems with a branch-and-check (BCH) or a logic-based Benders decomposition
+// (LBBD) of the selection of the nodes and their sequence. The state of a solve is kept apart from the Solver, so one
+// Solver can be used for several solves, also at the same time.
+package bch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/host"
+	"github.com/shirou/gopsutil/mem"
+	"sync"
+	"time"
+)
+
+// Options configure a Solver. The empty strings select the defaults
+type Options struct {
+	Strategy   string   // BCH (default) or LBBD
+	SubStrat   string   // TSP (default) or OP
+	Cuts       []string // the cuts added for master solutions exceeding the budget: SEC, BEND_V0, BEND_V1 or BEND_V2
+	YBounds    string   // CONT (default) or BIN
+	Objective  string   // op.OBJ_OP (default), op.OBJ_PCTSP or op.OBJ_PTP
+	MinPrize   float64  // the minimum prize of a PCTSP tour
+	Lambda     float64  // the weight of the length in the objective of the PTP
+	Sweep      bool     // compute the Pareto front of the prize versus the budget
+	Preprocess bool     // remove the nodes and edges, that no route within the budget can use, from the model
+	Backend    string   // the MIP backend of the master and the subproblems. By default the one selected by mip
+	LogFile    string   // the log file of the backend, also of the subproblems
+	ModelFile  string   // the file the model is written to before it's solved, if set
+
+	Params mip.Params // the parameters of the backend, e.g. its time limit and threads. The subproblems get them without the limits
+
+	Checkpoint         string        // the file the LBBD writes its checkpoints to, if set
+	CheckpointInterval time.Duration // the least time between two checkpoints. The last one is written when the LBBD stops
+	Resume             bool          // continue the LBBD from the checkpoint, that has to be written for the same instance and options
+}
+
+// Solver solves instances with its options
+type Solver struct {
+	Options Options
+}
+
+// NewSolver creates a Solver with the options
+func NewSolver(options Options) *Solver {
+	return &Solver{Options: options}
+}
+
+//the state of one solve
+type run struct {
+	opts Options
+	name string //the name of the instance in the log
+
+	N          int
+	startDepot int
+	endDepot   int
+	vehicles   int
+	startX     int
+	startY     int
+	startZ     int
+	varCount   int
+	edgeDist   *op.FlatDistances
+	subEnv     mip.Env //the environment of the models of the subproblems
+	sol        op.Solution
+	pInst      op.Instance
+	result     *op.Solution
+
+	//the backend can call the callback from several threads, so everything it touches is guarded by the mutex
+	mu            sync.Mutex
+	masterCbCount int
+	cbData        MasterCallbackData
+
+	origInst op.Instance      //the instance of the input. pInst only keeps the nodes left by the preprocessing
+	nodeIds  []int            //the node of origInst for every node of pInst
+	reach    *op.Reachability //the bounds for the edges of pInst
+
+	stats          op.Statistics
+	cutCounts      map[string]int
+	nodeCbCount    int
+	masterSolves   int
+	subproblems    int
+	subproblemTime time.Duration
+	nodeCount      int64
+	optimizeStart  time.Time
+	rootBound      float64
+	rootBoundSet   bool
+	incumbentObj   float64
+	incumbentSet   bool
+	firstIncumbent time.Duration
+	bestIncumbent  time.Duration
+	trajectory     []op.TrajectoryPoint
+	bestBound      float64
+
+	sweeping  bool
+	front     []op.FrontPoint
+	sweepCuts []lazyCut
+
+	instHash       string
+	iteration      int
+	masterCuts     []checkpointCut
+	lastCheckpoint time.Time
+	resume         *checkpoint
+}
+
+//the options with the defaults for the empty ones
+func (o Options) withDefaults() Options {
+	if o.Strategy == "" {
+		o.Strategy = BCH
+	}
+	if o.SubStrat == "" {
+		o.SubStrat = TSP
+	}
+	if o.YBounds == "" {
+		o.YBounds = Y_BOUNDS_CONT
+	}
+	if o.Objective == "" {
+		o.Objective = op.OBJ_OP
+	}
+	if o.Backend == "" {
+		o.Backend = mip.SelectedBackend()
+	}
+	return o
+}
+
+//the options, that can't be solved, independent of the instance
+func (o Options) validate() error {
+	if o.Strategy != BCH && o.Strategy != LBBD {
+		return fmt.Errorf("unsupported strategy: %s", o.Strategy)
+	}
+	if o.SubStrat != TSP && o.SubStrat != OP {
+		return fmt.Errorf("unsupported subproblem strategy: %s", o.SubStrat)
+	}
+	if o.YBounds != Y_BOUNDS_CONT && o.YBounds != Y_BOUNDS_BIN {
+		return fmt.Errorf("unsupported bounds of the Y-Variables: %s", o.YBounds)
+	}
+	for _, cut := range o.Cuts {
+		if cut != SEC && cut != BEND_V0 && cut != BEND_V1 && cut != BEND_V2 {
+			return fmt.Errorf("unsupported cut: %s", cut)
+		}
+	}
+	switch o.Objective {
+	case op.OBJ_OP, op.OBJ_PCTSP, op.OBJ_PTP:
+	default:
+		return fmt.Errorf("unsupported objective: %s", o.Objective)
+	}
+	if (o.Checkpoint != "" || o.Resume) && (o.Strategy != LBBD || o.Sweep) {
+		return fmt.Errorf("checkpoints are only supported by the %s without the sweep", LBBD)
+	}
+	if o.Resume && o.Checkpoint == "" {
+		return errors.New("resuming needs the file of the checkpoint")
+	}
+	return nil
+}
+
+// Solve solves the instance and returns its solution with the routes in the nodes of the instance. The instance isn't
+// changed. A cancelled context terminates the backend, so the solution keeps the best route and bound found until then
+// with the status op.STATUS_INTERRUPTED.
+func (s *Solver) Solve(ctx context.Context, inst *op.Instance) (*op.Solution, error) {
+	opts := s.Options.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	r := &run{opts: opts, name: inst.Name, pInst: *inst, cutCounts: make(map[string]int)}
+	if r.name == "" {
+		r.name = "the instance"
+	}
+	if opts.Checkpoint != "" {
+		//the checkpoint is only resumed for the same content of the instance
+		hash, err := inst.Hash()
+		if err != nil {
+			return nil, err
+		}
+		r.instHash = hash
+	}
+	r.pInst.Solutions = nil
+	if problems := r.pInst.Validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid instance - %s (%d problems)", problems[0], len(problems))
+	}
+
+	r.sol = op.Solution{Comment: "", System: sysInfo(), Objective: opts.Objective}
+	switch opts.Objective {
+	case op.OBJ_PCTSP:
+		r.sol.MinPrize = opts.MinPrize
+	case op.OBJ_PTP:
+		r.sol.Lambda = opts.Lambda
+	}
+
+	dist, err := r.pInst.GetDistanceMatrix()
+	if err != nil {
+		return nil, err
+	}
+	if !op.IsSymmetric(dist) {
+		return nil, errors.New("the edge weights are asymmetric, which the solver doesn't support. Use lp-asym instead")
+	}
+	if opts.Preprocess && r.sol.HasTravelBudget() {
+		dist, err = r.preprocess(dist)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.startDepot, r.endDepot = r.pInst.GetDepots()
+	r.vehicles = r.pInst.GetVehicles()
+	if err = r.checkSupported(); err != nil {
+		return nil, err
+	}
+	//a path is solved as a tour, that is closed by the fixed edge between the end and the start depot
+	r.edgeDist = op.NewFlatDistances(dist)
+	r.edgeDist.ClosePath(r.startDepot, r.endDepot)
+
+	// Create environment
+	env, err := mip.LoadBackendEnv(opts.Backend, opts.LogFile)
+	if err != nil {
+		return nil, err
+	}
+	defer env.Free()
+	if err = opts.Params.Apply(env); err != nil {
+		return nil, err
+	}
+	r.subEnv, err = mip.LoadBackendEnv(opts.Backend, opts.LogFile)
+	if err != nil {
+		return nil, err
+	}
+	defer r.subEnv.Free()
+	if err = subproblemParams(opts.Params).Apply(r.subEnv); err != nil {
+		return nil, err
+	}
+	threads, _ := env.GetIntParam(mip.INT_PAR_THREADS)
+	r.sol.Comment = fmt.Sprintf("Using %d threads", threads)
+	r.sol.Config = op.SolverConfig{Command: "solver", Strategy: opts.Strategy, SubStrat: opts.SubStrat, Cuts: opts.Cuts, YBounds: opts.YBounds, Sweep: opts.Sweep, Backend: opts.Backend + " " + env.Version()}
+	r.stats.Threads = int(threads)
+	r.stats.Backend = r.sol.Config.Backend
+
+	r.N = r.pInst.Dimension
+
+	/* Create an empty model */
+
+	model, err := env.NewModel("op")
+	if err != nil {
+		return nil, err
+	}
+	defer model.Free()
+
+	err = r.buildModel(model)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Resume {
+		err = r.loadCheckpoint(r.instHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.ModelFile != "" {
+		err = model.Write(opts.ModelFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	/* Must set LazyConstraints parameter when using lazy constraints */
+
+	err = model.SetIntParam(mip.INT_PAR_LAZYCONSTRAINTS, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.optimizeStart = time.Now()
+	if opts.Sweep {
+		r.sweepBudgets(ctx, model)
+	} else if opts.Strategy == BCH {
+		r.solveByBCH(ctx, model)
+	} else {
+		r.solveByLBBD(ctx, model)
+	}
+	if r.result == nil {
+		return nil, fmt.Errorf("the solve of %s stopped without a solution", r.name)
+	}
+	return r.result, nil
+}
+
+//the combinations of the instance and the options, that the model doesn't support
+func (r *run) checkSupported() error {
+	if r.vehicles > 1 && r.opts.Strategy != BCH {
+		return fmt.Errorf("the instance has %d vehicles, which is only supported by the %s strategy", r.vehicles, BCH)
+	}
+	if r.pInst.HasTimeWindows() && (r.vehicles > 1 || r.opts.SubStrat != TSP) {
+		return fmt.Errorf("time windows are only supported for a single vehicle with the %s subproblem", TSP)
+	}
+	if r.pInst.HasClusters() && (r.vehicles > 1 || r.pInst.HasTimeWindows() || r.opts.SubStrat != TSP) {
+		return fmt.Errorf("clusters are only supported for a single vehicle without time windows and with the %s subproblem", TSP)
+	}
+	if !r.sol.HasTravelBudget() && (r.vehicles > 1 || r.pInst.HasTimeWindows() || r.opts.SubStrat != TSP) {
+		return fmt.Errorf("the %s is only supported for a single vehicle without time windows and with the %s subproblem", r.opts.Objective, TSP)
+	}
+	if r.opts.Sweep && (r.vehicles > 1 || r.sol.GetObjective() != op.OBJ_OP) {
+		return fmt.Errorf("the sweep is only supported for a single vehicle and the %s objective", op.OBJ_OP)
+	}
+	return nil
+}
+
+//the parameters of the subproblems. Their cuts are only valid for optimal solutions, so the limits of the master are
+//left out
+func subproblemParams(params mip.Params) mip.Params {
+	var res mip.Params
+	for _, param := range params {
+		switch param.Name {
+		case mip.DBL_PAR_TIMELIMIT, mip.DBL_PAR_MIPGAP, mip.DBL_PAR_MIPGAPABS, mip.DBL_PAR_NODELIMIT, "SolutionLimit":
+			continue
+		}
+		res = append(res, param)
+	}
+	return res
+}
+
+//the system the solution is computed on
+func sysInfo() op.SysInfo {
+	var info op.SysInfo
+	if hostStat, err := host.Info(); err == nil {
+		info.Platform = hostStat.Platform
+	}
+	if cpuStat, err := cpu.Info(); err == nil && len(cpuStat) > 0 {
+		info.CPU = cpuStat[0].ModelName
+	}
+	if vmStat, err := mem.VirtualMemory(); err == nil {
+		info.RAM = fmt.Sprintf("%d GB", (vmStat.Total / 1024 / 1024 / 1024))
+	}
+	return info
+}
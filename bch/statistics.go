@@ -0,0 +1,174 @@
+package bch
+
+import (
+	"context"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"math"
+	"sort"
+	"time"
+)
+
+/* The statistics of the solve are collected while the model is solved and written with the solution. They cover the
+   whole run, so the sweep reports them summed over all budgets. The bounds and objectives are the ones of the
+   maximized model until they are written.
+   The trajectory gets a point whenever the incumbent changes, the bound moves by more than trajectoryBoundStep
+   relative to the last point or an LBBD iteration ends. Every solve of the LBBD master is a relaxation, so its bound
+   is only recorded while it's tighter than the ones before. The sweep only records the solve of the largest budget,
+   which is the one of the solution. */
+
+//the relative change of the bound, that is recorded in the trajectory. Smaller changes only make it longer
+const trajectoryBoundStep = 1e-3
+
+//count the constraints of the family of cuts added to the model
+func (r *run) countCuts(family string, n int) {
+	r.cutCounts[family] += n
+}
+
+//count the subproblem solved since the start
+func (r *run) countSubproblem(start time.Time) {
+	r.subproblems++
+	r.subproblemTime += time.Since(start)
+}
+
+//optimize the master and count its explored nodes. The bound after the first solve is the root bound, if no callback
+//reported it at the root node
+func (r *run) optimizeMaster(ctx context.Context, model mip.Model) error {
+	r.masterSolves++
+	//a cancelled context terminates the backend, which then keeps the incumbent and the bound found so far
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			model.Terminate()
+		case <-done:
+		}
+	}()
+	err := model.Optimize()
+	close(done)
+	<-stopped
+	if err != nil {
+		return err
+	}
+	if nodes, err := model.GetDblAttr(mip.DBL_ATTR_NODECOUNT); err == nil {
+		r.nodeCount += int64(nodes)
+	}
+	if r.masterSolves == 1 && !r.rootBoundSet {
+		if bound, err := model.GetDblAttr(mip.DBL_ATTR_OBJBOUND); err == nil && math.Abs(bound) < mip.INFINITY {
+			r.rootBound, r.rootBoundSet = bound, true
+		}
+	}
+	return nil
+}
+
+//remember the times of the first and the best solution with the objective of the maximized model
+func (r *run) noteIncumbent(obj float64) {
+	if r.incumbentSet && !op.Exceeds(obj, r.incumbentObj) {
+		return
+	}
+	elapsed := time.Since(r.optimizeStart)
+	if !r.incumbentSet {
+		r.firstIncumbent = elapsed
+	}
+	r.incumbentObj, r.incumbentSet, r.bestIncumbent = obj, true, elapsed
+}
+
+//the explored nodes of the current solve and the bound of the model, if the callback knows a finite one
+func callbackBound(cbdata mip.CallbackData, where int32) (float64, float64, bool) {
+	var nodcnt, objbnd int32
+	switch where {
+	case mip.CB_MIP:
+		nodcnt, objbnd = mip.CB_MIP_NODCNT, mip.CB_MIP_OBJBND
+	case mip.CB_MIPSOL:
+		nodcnt, objbnd = mip.CB_MIPSOL_NODCNT, mip.CB_MIPSOL_OBJBND
+	case mip.CB_MIPNODE:
+		nodcnt, objbnd = mip.CB_MIPNODE_NODCNT, mip.CB_MIPNODE_OBJBND
+	default:
+		return 0, 0, false
+	}
+	nodes, err := cbdata.GetDbl(nodcnt)
+	if err != nil {
+		return 0, 0, false
+	}
+	bound, err := cbdata.GetDbl(objbnd)
+	if err != nil || math.Abs(bound) >= mip.INFINITY {
+		return 0, 0, false
+	}
+	return nodes, bound, true
+}
+
+//add the incumbent and the bound of the maximized model to the trajectory. Without force the point is only added, if
+//they changed enough since the last one
+func (r *run) recordTrajectory(bound float64, nodes int64, force bool) {
+	if r.sweeping && len(r.front) > 0 {
+		return
+	}
+	if len(r.trajectory) > 0 && r.bestBound < bound {
+		bound = r.bestBound
+	}
+	r.bestBound = bound
+	point := op.TrajectoryPoint{Time: time.Since(r.optimizeStart).Seconds(), Bound: r.toSolutionObj(bound), Nodes: nodes}
+	if r.incumbentSet {
+		point.Incumbent, point.HasIncumbent = r.toSolutionObj(r.incumbentObj), true
+	}
+	if n := len(r.trajectory); n > 0 && !force {
+		last := r.trajectory[n-1]
+		if last.HasIncumbent == point.HasIncumbent && last.Incumbent == point.Incumbent && op.Gap(last.Bound, point.Bound) <= trajectoryBoundStep {
+			return
+		}
+	}
+	r.trajectory = append(r.trajectory, point)
+}
+
+//wrap the callback to serialize its calls, to count them and to record the root bound, the times of the incumbents and the trajectory.
+//The lock is held for the whole call, the backends don't call the callback again from within
+func (r *run) collectStatistics(callback mip.CallbackFunc) mip.CallbackFunc {
+	return func(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if where == mip.CB_MIPNODE {
+			r.nodeCbCount++
+		}
+		nodes, bound, hasBound := callbackBound(cbdata, where)
+		if hasBound && r.masterSolves == 1 && nodes == 0 {
+			//the bound of the first solve of the master as long as it's at the root node
+			r.rootBound, r.rootBoundSet = bound, true
+		}
+		res := callback(model, cbdata, where, usrdata)
+		if myData := usrdata.(*MasterCallbackData); myData.NodeSequence != nil || myData.Routes != nil {
+			r.noteIncumbent(myData.CurrentSolObj)
+		}
+		if hasBound && (where == mip.CB_MIP || where == mip.CB_MIPSOL) {
+			r.recordTrajectory(bound, r.nodeCount+int64(nodes), false)
+		}
+		return res
+	}
+}
+
+//the statistics collected so far with the cut families in alphabetical order
+func (r *run) collectedStatistics() *op.Statistics {
+	res := r.stats
+	res.Cuts = nil
+	for family, count := range r.cutCounts {
+		res.Cuts = append(res.Cuts, op.CutCount{Family: family, Count: count})
+	}
+	sort.Slice(res.Cuts, func(i, j int) bool {
+		return res.Cuts[i].Family < res.Cuts[j].Family
+	})
+	res.MasterCallbacks = r.masterCbCount
+	res.NodeCallbacks = r.nodeCbCount
+	res.MasterSolves = r.masterSolves
+	res.Subproblems = r.subproblems
+	res.SubproblemTime = r.subproblemTime.String()
+	res.Nodes = r.nodeCount
+	if r.rootBoundSet {
+		res.RootBound = r.toSolutionObj(r.rootBound)
+	}
+	if r.incumbentSet {
+		res.FirstIncumbent = r.firstIncumbent.String()
+		res.BestIncumbent = r.bestIncumbent.String()
+	}
+	return &res
+}
@@ -0,0 +1,141 @@
+package bch
+
+import (
+	"context"
+	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"log"
+	"math"
+	"time"
+)
+
+/* The sweep computes the Pareto front of the prize versus the budget with one model. After the optimal tour for a
+   budget is found, the budget is lowered just below its duration by another travel budget constraint and the model is
+   solved again, until no route fits into the budget. The cuts stay valid for smaller budgets: a set of nodes, that
+   doesn't fit into a budget, doesn't fit into a smaller one either. The lazy constraints of the BCH are only known to
+   the solve they were added to, so they are collected and added to the model for the following budgets.
+   The optimal tour of the larger budget, shortened to the smaller one, is the starting solution for the next solve. */
+
+type lazyCut struct {
+	ind  []int32
+	val  []float64
+	oper int8
+	rhs  float64
+}
+
+//callback data, that remembers the lazy constraints added through it
+type cutRecorder struct {
+	mip.CallbackData
+	run *run
+}
+
+func (c cutRecorder) Lazy(ind []int32, val []float64, sense int8, rhs float64) error {
+	err := c.CallbackData.Lazy(ind, val, sense, rhs)
+	if err == nil {
+		c.run.sweepCuts = append(c.run.sweepCuts, lazyCut{ind: ind, val: val, oper: sense, rhs: rhs})
+	}
+	return err
+}
+
+//wrap the callback to collect its lazy constraints for the following budgets
+func (r *run) recordCuts(callback mip.CallbackFunc) mip.CallbackFunc {
+	return func(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+		return callback(model, cutRecorder{CallbackData: cbdata, run: r}, where, usrdata)
+	}
+}
+
+func (r *run) sweepBudgets(ctx context.Context, model mip.Model) {
+	r.sweeping = true
+	startTime := time.Now()
+	tmax := r.pInst.TMax
+	base := r.sol
+	var first op.Solution
+	var comment string
+	//the budget is lowered by this fraction below the duration of the last route
+	gap := 2 * op.EPSILON
+	for step := 0; ; step++ {
+		if step > 0 && ctx.Err() != nil {
+			comment += "The sweep was cancelled, so the front might miss breakpoints. "
+			break
+		}
+		r.sol = base
+		if step > 0 {
+			log.Printf("Sweeping the budget %g with %d cuts from the larger budgets\n", r.pInst.TMax, len(r.sweepCuts))
+			ind, val := r.getBudgetConstr()
+			err := model.AddConstr(ind, val, mip.LESS_EQUAL, r.pInst.TMax, fmt.Sprintf("travel_budget_%d", step))
+			if err != nil {
+				log.Printf("At %s: %s\n", r.name, err.Error())
+				break
+			}
+			for k, cut := range r.sweepCuts {
+				err = model.AddConstr(cut.ind, cut.val, cut.oper, cut.rhs, fmt.Sprintf("SWEEP_%d_%d", step, k))
+				if err != nil {
+					log.Printf("Error adding sweep cut nr %d: %s\n", k, err.Error())
+				}
+			}
+			r.sweepCuts = nil
+		}
+
+		if r.opts.Strategy == BCH {
+			r.solveByBCH(ctx, model)
+		} else {
+			r.solveByLBBD(ctx, model)
+		}
+		if step == 0 {
+			first = r.sol
+		}
+		if len(r.sol.Route) == 0 {
+			log.Printf("No route fits into the budget %g, so the front is complete\n", r.pInst.TMax)
+			break
+		}
+
+		_, duration := r.pInst.ScheduleRoute(r.sol.Route, r.edgeDist)
+		if step > 0 && op.Exceeds(duration, r.pInst.TMax) {
+			//the MIP solver accepted the route within its tolerances, so the budget has to be lowered further below it
+			gap *= 10
+			r.pInst.TMax = duration - gap*math.Max(1, duration)
+			continue
+		}
+		point := op.FrontPoint{Budget: duration, Prize: r.sol.Obj, RouteCost: r.sol.RouteCost, Route: r.sol.Route, Optimal: r.sol.Optimal, Time: r.sol.Time}
+		if len(r.front) > 0 && op.NearlyEqual(point.Prize, r.front[len(r.front)-1].Prize) {
+			//the shorter route dominates the route of the larger budget with the same prize
+			r.front[len(r.front)-1] = point
+		} else {
+			r.front = append(r.front, point)
+		}
+		log.Printf("Found the breakpoint with budget %g and prize %g\n", point.Budget, point.Prize)
+		if !r.sol.Optimal {
+			comment += fmt.Sprintf("The route for the budget %g is not optimal, so the front might miss breakpoints. ", r.pInst.TMax)
+		}
+		r.pInst.TMax = duration - gap*math.Max(1, duration)
+	}
+
+	r.pInst.TMax = tmax
+	r.sol = first
+	r.sol.Front = r.front
+	if ctx.Err() != nil {
+		r.sol.Status = op.STATUS_INTERRUPTED
+	}
+	r.sol.Comment += fmt.Sprintf(". %sSwept %d breakpoints in %s", comment, len(r.front), time.Since(startTime).String())
+	r.sweeping = false
+	r.finishSolution()
+}
+
+//start the solve for the next budget with the tour of the last breakpoint, shortened to the budget
+func (r *run) warmStartSweep(model mip.Model) {
+	if !r.sweeping || len(r.front) == 0 {
+		return
+	}
+	last := r.front[len(r.front)-1]
+	tour, tourLength, tourObj := r.shortenTour(mip.Int32Slice(last.Route), r.edgeDist, r.pInst.Prices, last.RouteCost, r.pInst.TMax, last.Prize)
+	if len(tour) < 3 {
+		//the model needs two edges at every node, so it can't build shorter routes
+		return
+	}
+	if !r.checkSolutionValidity(tour, tourObj, tourLength).Valid {
+		//the shortened tour can still be too late for the time windows
+		return
+	}
+	r.setHeuristicSol(model, tour, tourLength, tourObj, last.Prize)
+}
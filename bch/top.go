@@ -0,0 +1,199 @@
+package bch
+
+import (
+	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"log"
+	"time"
+)
+
+/* The team orienteering problem (TOP) uses the aggregated model of the single vehicle with up to 2*vehicles edges at
+   the depots. It can't tell the routes of the vehicles apart, so every integer solution is checked by solving the
+   TOP on the selected nodes, which turns them into routes that each keep the budget. */
+
+//replace the degree constraint of a depot for multiple vehicles. Every route leaves the depot of a closed tour on two
+//edges, so its degree is between 2 and 2*vehicles. On a path every route is closed by its own copy of the edge
+//between the depots, so Y_st counts the routes and the other edges at each depot have to match it.
+func (r *run) addDepotDegreeTOP(model mip.Model, depot int, ind []int32, val []float64) error {
+	if r.startDepot == r.endDepot {
+		ind = append(ind, int32(r.startX+depot)) //X_depot
+		val = append(val, -2.0)
+		err := model.AddConstr(ind, val, mip.GREATER_EQUAL, 0.0, fmt.Sprintf("node_2_%d", depot))
+		if err != nil {
+			log.Printf("Error adding node_2_%d\n", depot)
+			return err
+		}
+		val[len(val)-1] = -2.0 * float64(r.vehicles)
+		err = model.AddConstr(ind, val, mip.LESS_EQUAL, 0.0, "depot_vehicles")
+		if err != nil {
+			log.Println("Error adding depot_vehicles")
+		}
+		return err
+	}
+	depotEdge := int32(r.getYIndex(r.startDepot, r.endDepot))
+	for k := 0; k < len(ind); k++ {
+		if ind[k] == depotEdge {
+			val[k] = -1.0
+		}
+	}
+	err := model.AddConstr(ind, val, mip.EQUAL, 0.0, fmt.Sprintf("node_2_%d", depot))
+	if err != nil {
+		log.Printf("Error adding node_2_%d\n", depot)
+	}
+	return err
+}
+
+func (r *run) masterCallbackTOP(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+	myData := usrdata.(*MasterCallbackData)
+
+	if where == mip.CB_MIPSOL {
+		r.masterCbCount++
+		solA, err := cbdata.GetDblArray(mip.CB_MIPSOL_SOL, r.varCount)
+		if err != nil {
+			r.sol.Comment += fmt.Sprintf("Couldn't retrieve the array in the callback with the decision variables: %s. ", err.Error())
+			log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+			return 0
+		}
+		objval, err := cbdata.GetDbl(mip.CB_MIPSOL_OBJ)
+		if err != nil {
+			r.sol.Comment += fmt.Sprintf("Couldn't retrieve the obj_value in the callback: %s. ", err.Error())
+			log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+			return 0
+		}
+
+		if !op.Exceeds(objval, myData.CurrentSolObj) {
+			log.Printf("Our current best solution %g is at least as good as the current master solution %g, so we do not solve the subproblem at this point", myData.CurrentSolObj, objval)
+			return 0
+		}
+
+		//subtours without the depots are cut off first, before we solve the TOP
+		if subtours := r.depotFreeSubtours(r.extractEdgeMatrix(solA)); len(subtours) > 0 {
+			secInd, secVal, oper, rhs := op.GetSECs(subtours, r.N, r.startY)
+			for i := 0; i < len(secInd); i++ {
+				err = cbdata.Lazy(secInd[i], secVal[i], oper, rhs[i])
+				if err != nil {
+					log.Println(err)
+				} else {
+					r.countCuts(SEC, 1)
+				}
+			}
+			return 0
+		}
+
+		//the selected nodes are split into routes by solving the TOP on them
+		xMat := r.extractNodeArray(solA)
+		d, p, s, indx := r.transformToOP(xMat)
+		subStart := time.Now()
+		routes, heurObj, lengths, _, _, err := op.SolveTOPPath(r.subEnv, d, p, s, r.pInst.TMax, r.vehicles, localIndex(indx, r.startDepot), localIndex(indx, r.endDepot))
+		r.countSubproblem(subStart)
+		if err != nil {
+			log.Printf("Couldn't solve the TOP for the selected nodes: %s\n", err.Error())
+			return 0
+		}
+
+		//translate the routes to global indxs
+		heurSol := make([][]int32, len(routes))
+		heurTourLength := 0.0
+		for v, route := range routes {
+			heurSol[v] = make([]int32, len(route))
+			for k := 0; k < len(route); k++ {
+				heurSol[v][k] = int32(indx[route[k]])
+			}
+			heurTourLength += lengths[v]
+		}
+
+		if op.Exceeds(objval, heurObj) {
+			//the vehicles can't collect the prize of all selected nodes within their budgets
+			log.Printf("The Master solution with obj %g cannot be correct - Adding a %s cut to cut it off\n", objval, OP)
+			ind, val, oper, rhs := r.getBendersCutOP(extractActiveNodes(xMat), heurObj)
+			err = cbdata.Lazy(ind, val, oper, rhs)
+			if err != nil {
+				log.Println(err)
+			} else {
+				r.countCuts(OP, 1)
+			}
+		}
+
+		if op.Exceeds(heurObj, myData.CurrentSolObj) {
+			myData.CurrentSolObj = heurObj
+			myData.Routes = heurSol
+			myData.TourLength = heurTourLength
+			//the routes only have to be set as a solution, if the master solution was cut off
+			myData.NewBestSol = op.Exceeds(objval, heurObj)
+			if myData.NewBestSol {
+				log.Printf("Found new best solution with value %g, while the master solution was invalid", heurObj)
+			}
+		}
+	}
+
+	if where == mip.CB_MIPNODE && myData.NewBestSol {
+		objbst, err := cbdata.GetDbl(mip.CB_MIPNODE_OBJBST)
+		if err != nil {
+			r.sol.Comment += fmt.Sprintf("Couldn't retrieve the obj_best in the callback: %s. ", err.Error())
+			log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+			return 0
+		}
+		if !op.Exceeds(myData.CurrentSolObj, objbst) {
+			log.Printf("Current obj is already better than the heuristic solution. Skipping...\n")
+			myData.NewBestSol = false
+			return 0
+		}
+		log.Printf("Currently setting new heuristic solution: %v with obj-value %.2f\n", myData.Routes, myData.CurrentSolObj)
+		solution := make([]float64, r.varCount)
+		for _, route := range myData.Routes {
+			//set the objective (X_i values)
+			for i := 0; i < len(route); i++ {
+				solution[int32(r.startX)+route[i]] = 1.0
+			}
+			//set the constraints (Y_ij values). The edge between the depots of a path is used by every route
+			for i := 0; i < len(route); i++ {
+				y := r.getYIndex(int(route[i]), int(route[(i+1)%len(route)]))
+				solution[y] += 1.0
+			}
+		}
+
+		//set the solution
+		val, err := cbdata.Solution(solution)
+		if err != nil {
+			log.Printf("Couldn't set the heuristic solution: %s\n", err.Error())
+		} else {
+			myData.NewBestSol = false
+			log.Printf("New best solution with value : %g set!\n", val)
+		}
+	}
+	return 0
+}
+
+//the connected components of the integer edges, that contain none of the depots and are closed to a subtour
+func (r *run) depotFreeSubtours(edges [][]int) (subtours [][]int32) {
+	seen := make([]bool, len(edges))
+	for i := 0; i < len(edges); i++ {
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+		component := []int32{int32(i)}
+		hasDepot := i == r.startDepot || i == r.endDepot
+		edgeCount := 0
+		for k := 0; k < len(component); k++ {
+			node := component[k]
+			for j := 0; j < len(edges); j++ {
+				if edges[node][j] != 1 {
+					continue
+				}
+				edgeCount++
+				if !seen[j] {
+					seen[j] = true
+					component = append(component, int32(j))
+					hasDepot = hasDepot || j == r.startDepot || j == r.endDepot
+				}
+			}
+		}
+		//every edge was counted from both of its nodes
+		if !hasDepot && len(component) > 2 && edgeCount/2 >= len(component) {
+			subtours = append(subtours, component)
+		}
+	}
+	return subtours
+}
@@ -0,0 +1,153 @@
+package bch
+
+import (
+	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"git.solver4all.com/azaryc2s/op/tsp"
+	"log"
+	"time"
+)
+
+/* With time windows (OPTW) the nodes of a master solution are only valid, if a sequence through them serves every node
+   within its time window and keeps the budget. A set of nodes without such a sequence is cut off together with all of
+   its supersets, which can't be sequenced either as long as the distances keep the triangle inequality. The cut uses
+   a minimal subset of the nodes without a sequence, which cuts off more master solutions than the whole set. */
+
+//look for a time-feasible sequence through the selected nodes of the master solution. If there is none, the
+//returned conflict is a minimal subset of the nodes without such a sequence
+func (r *run) solveSubproblemTW(solArray []float64) (tour []int32, tourLength float64, conflict []int32) {
+	nodes := extractActiveNodes(r.extractNodeArray(solArray))
+	tour, tourLength = r.sequenceTW(nodes)
+	if tour == nil {
+		return nil, -1, r.findConflictTW(nodes)
+	}
+	return tour, tourLength, nil
+}
+
+//a time-feasible sequence through the nodes from the start to the end depot in global indxs with its length or nil,
+//if there is none
+func (r *run) sequenceTW(nodes []int) ([]int32, float64) {
+	d := make([][]float64, len(nodes))
+	service := make([]float64, len(nodes))
+	windows := make([][]float64, len(nodes))
+	for j, a := range nodes {
+		d[j] = make([]float64, len(nodes))
+		for k, b := range nodes {
+			d[j][k] = r.edgeDist.Dist(a, b)
+		}
+		service[j] = r.pInst.GetServiceTime(a)
+		windows[j] = r.pInst.TimeWindows[a]
+	}
+	subStart := time.Now()
+	tour, _ := tsp.SolveTSPTW(d, service, windows, r.pInst.TMax, localIndex(nodes, r.startDepot), localIndex(nodes, r.endDepot))
+	r.countSubproblem(subStart)
+	if tour == nil {
+		return nil, -1
+	}
+	tourLength := 0.0
+	for k := 0; k < len(tour); k++ {
+		//the edge back to the start of a path is the one between the depots, which costs nothing
+		tourLength += d[tour[k]][tour[(k+1)%len(tour)]]
+		tour[k] = int32(nodes[tour[k]])
+	}
+	return tour, tourLength
+}
+
+//remove every node from the set, without which the set still has no time-feasible sequence
+func (r *run) findConflictTW(nodes []int) []int32 {
+	conflict := append([]int{}, nodes...)
+	for k := 0; k < len(conflict); {
+		if conflict[k] == r.startDepot || conflict[k] == r.endDepot {
+			k++
+			continue
+		}
+		reduced := append(append([]int{}, conflict[:k]...), conflict[k+1:]...)
+		if tour, _ := r.sequenceTW(reduced); tour == nil {
+			conflict = reduced
+		} else {
+			k++
+		}
+	}
+	return mip.Int32Slice(conflict)
+}
+
+//calculate a heuristic tour by dropping the cheapest node of the conflict until the remaining nodes can be sequenced
+func (r *run) shortenTourTW(nodes []int, conflict []int32) ([]int32, float64, float64) {
+	for {
+		cheapest := -1
+		for _, node := range conflict {
+			if int(node) == r.startDepot || int(node) == r.endDepot {
+				continue
+			}
+			if cheapest < 0 || r.pInst.Prices[node] < r.pInst.Prices[cheapest] {
+				cheapest = int(node)
+			}
+		}
+		if cheapest < 0 {
+			return nil, -1, 0
+		}
+		nodes = append(nodes[:localIndex(nodes, cheapest)], nodes[localIndex(nodes, cheapest)+1:]...)
+		if tour, tourLength := r.sequenceTW(nodes); tour != nil {
+			tourObj := 0.0
+			for _, node := range tour {
+				tourObj += r.pInst.Prices[node]
+			}
+			return tour, tourLength, tourObj
+		}
+		conflict = r.findConflictTW(nodes)
+	}
+}
+
+func (r *run) masterCallbackTW(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+	if where != mip.CB_MIPSOL {
+		//the heuristic solutions are set like for the OP
+		return r.masterCallback(model, cbdata, where, usrdata)
+	}
+	myData := usrdata.(*MasterCallbackData)
+
+	r.masterCbCount++
+	solA, err := cbdata.GetDblArray(mip.CB_MIPSOL_SOL, r.varCount)
+	if err != nil {
+		r.sol.Comment += fmt.Sprintf("Couldn't retrieve the array in the callback with the decision variables: %s. ", err.Error())
+		log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+		return 0
+	}
+	objval, err := cbdata.GetDbl(mip.CB_MIPSOL_OBJ)
+	if err != nil {
+		r.sol.Comment += fmt.Sprintf("Couldn't retrieve the obj_value in the callback: %s. ", err.Error())
+		log.Printf("At %s: %s\n", r.name, r.sol.Comment)
+		return 0
+	}
+
+	if !op.Exceeds(objval, myData.CurrentSolObj) {
+		log.Printf("Our current best solution %g is at least as good as the current master solution %g, so we do not solve the subproblem at this point", myData.CurrentSolObj, objval)
+		return 0
+	}
+
+	heurSol, heurTourLength, conflict := r.solveSubproblemTW(solA)
+	heurObj := objval
+	if heurSol == nil {
+		log.Printf("The Master solution with obj %g cannot be correct - Adding a time window cut for the nodes %v to cut it off\n", objval, conflict)
+		ind, val, oper, rhs := r.getBendersCutV0(conflict)
+		err = cbdata.Lazy(ind, val, oper, rhs)
+		if err != nil {
+			log.Println(err)
+		} else {
+			r.countCuts(TW, 1)
+		}
+		heurSol, heurTourLength, heurObj = r.shortenTourTW(extractActiveNodes(r.extractNodeArray(solA)), conflict)
+	}
+
+	if op.Exceeds(heurObj, myData.CurrentSolObj) {
+		myData.CurrentSolObj = heurObj
+		myData.NodeSequence = heurSol
+		myData.TourLength = heurTourLength
+		//the sequence only has to be set as a solution, if the master solution was cut off
+		myData.NewBestSol = op.Exceeds(objval, heurObj)
+		if myData.NewBestSol {
+			log.Printf("Found new best solution with value %g, while the master solution was invalid", heurObj)
+		}
+	}
+	return 0
+}
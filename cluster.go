@@ -0,0 +1,24 @@
+package op
+
+// HasClusters returns true if prizes belong to clusters of nodes (Set-OP)
+func (inst *Instance) HasClusters() bool {
+	return len(inst.Clusters) > 0
+}
+
+// GetClusterPrize returns the prize of the clusters with at least one visited node. Every cluster pays its prize once,
+// no matter how many of its nodes are visited.
+func (inst *Instance) GetClusterPrize(visited []bool) float64 {
+	prize := 0.0
+	for c, cluster := range inst.Clusters {
+		if c >= len(inst.ClusterPrices) {
+			break
+		}
+		for _, node := range cluster {
+			if node >= 0 && node < len(visited) && visited[node] {
+				prize += inst.ClusterPrices[c]
+				break
+			}
+		}
+	}
+	return prize
+}
@@ -2,21 +2,19 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	_ "git.solver4all.com/azaryc2s/op/mip/bnc"
+	_ "git.solver4all.com/azaryc2s/op/mip/grb"
 	"git.solver4all.com/azaryc2s/op/tsp"
 	"io/ioutil"
 	"log"
-	"math"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-var scaling float64
-
 func main() {
 	var (
 		err error
@@ -30,6 +28,14 @@ func main() {
 	if len(os.Args) > 2 {
 		calcTSP = os.Args[2]
 	}
+	var env mip.Env
+	if calcTSP == "tsp" {
+		env, err = mip.LoadEnv("atsp_gurobi.log")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer env.Free()
+	}
 
 FILES:
 	for _, f := range files {
@@ -42,30 +48,37 @@ FILES:
 		defer file.Close()
 		fileName = strings.ReplaceAll(fileName, ".oplib", ".json")
 
-		var name, comment, problemType, edgeWeightType string
-		var tmax int
+		var name, comment, problemType, edgeWeightType, edgeWeightFormat string
+		var tmax float64
 		var depots []int
 
 		scanner := bufio.NewScanner(file)
 		line := -1
-		nodeCount := 0
-		var edgeWeights [][]int
+		var edgeWeights, edgeWeightRows [][]float64
 		var coordinates [][]float64
-		var nodeScores []int
+		var nodeScores []float64
 		var metaData bool
-		var nodeCoordSection, depotSection, nodeScoreSection bool
+		var nodeCoordSection, depotSection, nodeScoreSection, edgeWeightSection bool
 		metaData = true
 		for scanner.Scan() {
 			line = line + 1
-			t := scanner.Text()
+			t := strings.Trim(scanner.Text(), " ")
+			if t == "EOF" {
+				break
+			}
+			if strings.HasSuffix(t, "_SECTION") {
+				metaData = false
+				edgeWeightSection = t == "EDGE_WEIGHT_SECTION"
+				nodeCoordSection = t == "NODE_COORD_SECTION" || t == "DISPLAY_DATA_SECTION"
+				nodeScoreSection = t == "NODE_SCORE_SECTION"
+				depotSection = t == "DEPOT_SECTION"
+				continue
+			}
 			if metaData {
-				t = strings.Trim(t," ")
-				if t == "NODE_COORD_SECTION" {
-					metaData = false
-					nodeCoordSection = true
+				lineSplit := strings.Split(t, ":")
+				if len(lineSplit) < 2 {
 					continue
 				}
-				lineSplit := strings.Split(t, ":")
 				lineSplit[0] = strings.Trim(lineSplit[0], " ")
 				lineSplit[1] = strings.Trim(lineSplit[1], " ")
 
@@ -85,7 +98,7 @@ FILES:
 					continue
 				}
 				if lineSplit[0] == "COST_LIMIT" {
-					tmax, err = strconv.Atoi(lineSplit[1])
+					tmax, err = strconv.ParseFloat(lineSplit[1], 64)
 					if err != nil {
 						fmt.Printf("Couldn't parse the cost limit! Skipping file: %s\n", err.Error())
 						continue FILES
@@ -94,41 +107,43 @@ FILES:
 				}
 				if lineSplit[0] == "EDGE_WEIGHT_TYPE" {
 					edgeWeightType = lineSplit[1]
-					if edgeWeightType != "EUC_2D" && edgeWeightType != "CEIL_2D" {
-						fmt.Printf("Format other then EUC_2D|CEIL_2D. Skipping for now...\n")
+					continue
+				}
+				if lineSplit[0] == "EDGE_WEIGHT_FORMAT" {
+					edgeWeightFormat = lineSplit[1]
+					continue
+				}
+			}
+			if edgeWeightSection {
+				//the rows of the file don't have to match the rows of the matrix, so we just keep them as they are
+				var row []float64
+				for _, w := range strings.Fields(t) {
+					weight, err := strconv.ParseFloat(w, 64)
+					if err != nil {
+						fmt.Printf("Couldn't parse the edge weights! Skipping file: %s\n", err.Error())
 						continue FILES
 					}
-					continue
+					row = append(row, weight)
 				}
+				edgeWeightRows = append(edgeWeightRows, row)
 			}
 			if nodeCoordSection {
-				if t == "NODE_SCORE_SECTION" {
-					nodeCoordSection = false
-					nodeScoreSection = true
-					continue
-				}
-				xyString := strings.Split(t, " ")
+				xyString := strings.Fields(t)
 
-				x, err := strconv.ParseFloat(xyString[1], 64)
-				if err != nil {
-					fmt.Printf("Error parsing coordinate index!: %s", err.Error())
-				}
-				y, err := strconv.ParseFloat(xyString[2], 64)
-				if err != nil {
-					fmt.Printf("Error parsing coordinate y!: %s", err.Error())
+				//2 or 3 coordinates after the node index, depending on the edge weight type
+				var xy []float64
+				for _, c := range xyString[1:] {
+					v, err := strconv.ParseFloat(c, 64)
+					if err != nil {
+						fmt.Printf("Error parsing coordinate!: %s", err.Error())
+					}
+					xy = append(xy, v)
 				}
-				xy := []float64{x, y}
 				coordinates = append(coordinates, xy)
-				nodeCount++
 			}
 			if nodeScoreSection {
-				if t == "DEPOT_SECTION" {
-					nodeScoreSection = false
-					depotSection = true
-					continue
-				}
-				tString := strings.Split(t, " ")
-				score, err := strconv.Atoi(tString[1])
+				tString := strings.Fields(t)
+				score, err := strconv.ParseFloat(tString[1], 64)
 				if err != nil {
 					fmt.Printf("Error parsing score!: %s", err.Error())
 				}
@@ -146,113 +161,47 @@ FILES:
 				depots = append(depots, depot-1) //its not 0-indexed in the file, so we subtract 1
 			}
 		}
-		/*var scaledCoords [][]int
-		var trimmed bool
-		scaledCoords, scaling, trimmed = scaleCoordinatesToInts(coordinates)
-		tmax = tmax * int(scaling)*/
-		edgeWeights = make([][]int, nodeCount)
-		for i := 0; i < nodeCount; i++ {
-			edgeWeights[i] = make([]int, nodeCount)
+		inst := op.Instance{Name: name, Type: problemType, Dimension: len(nodeScores), DisplayDataType: "COORD_DISPLAY", EdgeWeightType: edgeWeightType, NodeCoordinates: coordinates, Prices: nodeScores, TMax: tmax, Depots: depots}
+		if edgeWeightType == op.EXPLICIT {
+			inst.EdgeWeightFormat = edgeWeightFormat
+			inst.EdgeWeights = edgeWeightRows
+			if len(coordinates) == 0 {
+				inst.DisplayDataType = "NO_DISPLAY"
+			}
 		}
-		for i := 0; i < nodeCount; i++ {
-			for j := i + 1; j < nodeCount; j++ {
-				x := coordinates[i][0] - coordinates[j][0]
-				y := coordinates[i][1] - coordinates[j][1]
-				var distance int
-				if edgeWeightType == "EUC_2D" {
-					distance = int(math.Sqrt(math.Pow(x, 2)+math.Pow(y, 2)) + 0.5)
-				} else if edgeWeightType == "CEIL_2D" {
-					distance = int(math.Ceil(math.Sqrt(math.Pow(x, 2)+math.Pow(y, 2))))
-				}
-				edgeWeights[i][j] = distance
-				edgeWeights[j][i] = distance
+		if problems := inst.Validate(); len(problems) > 0 {
+			for _, problem := range problems {
+				fmt.Printf("Invalid instance - %s\n", problem)
 			}
+			fmt.Printf("Skipping file %s\n", f.Name())
+			continue FILES
+		}
+
+		edgeWeights, err = inst.GetEdgeDist()
+		if err != nil {
+			fmt.Printf("Couldn't calculate the edge weights! Skipping file: %s\n", err.Error())
+			continue FILES
 		}
 
-		var tspLength int
-		if calcTSP == "tsp"{
-			_, tspLength = tsp.SolveATSP(edgeWeights)
+		var tspLength float64
+		if calcTSP == "tsp" {
+			_, tspLength = tsp.SolveATSP(env, f.Name(), edgeWeights)
 		} else {
 			tspLength = 0
 		}
 
-		fileComment := comment
-		if scaling > 1.1 {
-			fileComment = fmt.Sprintf("%s | coordinates scaled by %d from the original to be integers.", fileComment, int(scaling))
-			/*if trimmed {
-				fileComment = fmt.Sprintf("%s They were trimmed at the 5th decimal point.", fileComment)
-			}*/
-		}
-		inst := op.Instance{Name: name, Comment: fileComment, Type: problemType, Dimension: nodeCount, DisplayDataType: "COORD_DISPLAY", EdgeWeightType: edgeWeightType, NodeCoordinates: coordinates, TSPLength: tspLength, Prices: nodeScores, TMax: tmax, Depots: depots}
+		inst.Comment = comment
+		inst.TSPLength = tspLength
 
 		if err := scanner.Err(); err != nil {
 			log.Fatal(err)
 			continue
 		}
 
-		jsonInst, err := json.MarshalIndent(inst, "", "\t")
+		err = op.SaveInstanceFile(fileName, &inst)
 		if err != nil {
 			log.Fatal(err)
 			continue
 		}
-
-		jsonInst = []byte(sanitizeJsonArrayLineBreaks(string(jsonInst)))
-		err = ioutil.WriteFile(fileName, jsonInst, 0644)
-		if err != nil {
-			log.Fatal(err)
-			continue
-		}
-	}
-}
-
-func sanitizeJsonArrayLineBreaks(json string) string {
-	res := fmt.Sprintf("%s", json)
-	var numbers = regexp.MustCompile(`\s*([-]?[0-9]+(\.[0-9]+)?),\s+([-]?[0-9]+(\.[0-9]+)?)(,)?`)
-	var brackets = regexp.MustCompile(`\[(([-]?[0-9]+(\.[0-9]+)?,)+[-]?[0-9]+(\.[0-9]+)?)\s+\](,?)(\s+)`)
-	for numbers.MatchString(res) {
-		res = numbers.ReplaceAllString(res, "$1,$3$5")
-	}
-	for brackets.MatchString(res) {
-		res = brackets.ReplaceAllString(res, "[$1]$5$6")
-	}
-	return res
-}
-
-func scaleCoordinatesToInts(coords [][]float64) ([][]int, float64, bool) {
-	scaledCoords := make([][]int, len(coords))
-	places, trimmed := findMaxDecimalPointsInCoords(coords)
-	scale := math.Pow(10, float64(places))
-	for i := 0; i < len(coords); i++ {
-		scaledCoords[i] = []int{int(coords[i][0] * scale), int(coords[i][1] * scale)}
-	}
-	return scaledCoords, scale, trimmed
-}
-
-//if returns true, then the decimal points were more than 5 and had the be trimmed
-func findMaxDecimalPointsInCoords(coords [][]float64) (int, bool) {
-	max := 0
-	for i := 0; i < len(coords); i++ {
-		places := len(decimalPortion(coords[i][0]))
-		if places > max {
-			max = places
-		}
-		places = len(decimalPortion(coords[i][1]))
-		if places > max {
-			max = places
-		}
-	}
-	if max > 5 {
-		return 5, true
-	}
-	return max, false
-}
-
-func decimalPortion(n float64) string {
-	nString := strconv.FormatFloat(n, 'f', -1, 64) // produces 0.xxxx0000
-	decimalPlaces := ""
-	if strings.Contains(nString, ".") {
-		decimalPlaces = strings.Split(nString, ".")[1]
 	}
-	decimalPlaces = strings.TrimRight(decimalPlaces, "0") // remove trailing 0s
-	return decimalPlaces
 }
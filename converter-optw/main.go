@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	_ "git.solver4all.com/azaryc2s/op/mip/bnc"
+	_ "git.solver4all.com/azaryc2s/op/mip/grb"
+	"git.solver4all.com/azaryc2s/op/tsp"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* Converts the OPTW benchmarks of Montemanni and Vansteenwegen et al. (based on the instances of Solomon and Cordeau).
+   The first line "k v N t" and the second line "D Q" don't matter for the OPTW. Every following line describes a point
+   "i x y d S f a list O C" with the service duration d, the score S and the time window [O, C] for the beginning of the
+   service. The list has a entries. The first point is the depot and its closing time is the budget. */
+
+func main() {
+	var (
+		err error
+	)
+	targetDir := os.Args[1]
+	comment := os.Args[2]
+	files, err := ioutil.ReadDir(targetDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	env, err := mip.LoadEnv("tsp_gurobi.log")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer env.Free()
+	for _, f := range files {
+		if !strings.Contains(f.Name(), ".txt") {
+			continue
+		}
+		fileName := targetDir + "/" + f.Name()
+		fmt.Println(fileName)
+		file, err := os.Open(fileName)
+		defer file.Close()
+		fileName = strings.ReplaceAll(fileName, ".txt", ".json")
+
+		scanner := bufio.NewScanner(file)
+		line := -1
+		nodeCount := 0
+		var coordinates [][]float64
+		var nodeScores []float64
+		var serviceTimes []float64
+		var timeWindows [][]float64
+		for scanner.Scan() {
+			line = line + 1
+			xyz := strings.Fields(scanner.Text())
+			if line < 2 || len(xyz) == 0 {
+				continue
+			}
+			if len(xyz) < 9 {
+				fmt.Printf("Error parsing line %d: expected at least 9 values, got %d\n", line, len(xyz))
+				continue
+			}
+			values := make([]float64, len(xyz))
+			for k, v := range xyz {
+				values[k], err = strconv.ParseFloat(v, 64)
+				if err != nil {
+					fmt.Printf("Error parsing value %d of line %d!: %s", k, line, err.Error())
+				}
+			}
+			coordinates = append(coordinates, []float64{values[1], values[2]})
+			serviceTimes = append(serviceTimes, values[3])
+			nodeScores = append(nodeScores, values[4])
+			timeWindows = append(timeWindows, []float64{values[len(values)-2], values[len(values)-1]})
+			nodeCount++
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatal(err)
+			continue
+		}
+		if nodeCount == 0 {
+			fmt.Printf("Skipping file %s without points\n", f.Name())
+			continue
+		}
+
+		//the distances of the literature are real-valued euclidean ones
+		edgeWeights, err := op.CalcEdgeDist(coordinates, op.EXACT_2D)
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, tspLength, _ := tsp.SolveTSPPath(env, f.Name(), edgeWeights, 0, 0)
+		tmax := timeWindows[0][1]
+
+		inst := op.Instance{Name: strings.ReplaceAll(f.Name(), ".txt", ""), Comment: comment, Type: "OPTW", Dimension: nodeCount, DisplayDataType: "COORD_DISPLAY", EdgeWeightType: op.EXACT_2D, NodeCoordinates: coordinates, TSPLength: tspLength, Prices: nodeScores, TMax: tmax, Depots: []int{0}, TimeWindows: timeWindows, ServiceTimes: serviceTimes}
+
+		if problems := inst.Validate(); len(problems) > 0 {
+			for _, problem := range problems {
+				fmt.Printf("Invalid instance - %s\n", problem)
+			}
+			fmt.Printf("Skipping file %s\n", f.Name())
+			continue
+		}
+
+		err = op.SaveInstanceFile(fileName, &inst)
+		if err != nil {
+			log.Fatal(err)
+			continue
+		}
+	}
+}
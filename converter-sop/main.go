@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	_ "git.solver4all.com/azaryc2s/op/mip/bnc"
+	_ "git.solver4all.com/azaryc2s/op/mip/grb"
+	"git.solver4all.com/azaryc2s/op/tsp"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* Converts the Set-OP benchmarks of Archetti, Carrabs and Cerulli, which extend the GTSP instances of Fischetti et al.
+   by a profit for every cluster and a budget TMAX. The clusters follow the GTSP_SET_SECTION as lines
+   "set_id set_profit node ... -1" with 1-indexed nodes. The route starts at the node of the START_CLUSTER and ends at
+   the node of the END_CLUSTER, which for clusters with several nodes is the first one. */
+
+func main() {
+	var (
+		err error
+	)
+	targetDir := os.Args[1]
+	files, err := ioutil.ReadDir(targetDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var calcTSP string
+	if len(os.Args) > 2 {
+		calcTSP = os.Args[2]
+	}
+	var env mip.Env
+	if calcTSP == "tsp" {
+		env, err = mip.LoadEnv("tsp_gurobi.log")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer env.Free()
+	}
+
+FILES:
+	for _, f := range files {
+		if !strings.Contains(f.Name(), ".sop") {
+			continue
+		}
+		fileName := targetDir + "/" + f.Name()
+		fmt.Println(fileName)
+		file, err := os.Open(fileName)
+		defer file.Close()
+		fileName = strings.ReplaceAll(fileName, ".sop", ".json")
+
+		var name, comment, problemType, edgeWeightType, edgeWeightFormat string
+		var tmax float64
+		var dimension, startCluster, endCluster int
+
+		scanner := bufio.NewScanner(file)
+		var edgeWeightRows [][]float64
+		var coordinates [][]float64
+		var clusters [][]int
+		var clusterPrices []float64
+		var clusterIDs []int
+		var metaData bool
+		var nodeCoordSection, edgeWeightSection, setSection bool
+		metaData = true
+		for scanner.Scan() {
+			t := strings.Trim(scanner.Text(), " ")
+			if t == "EOF" {
+				break
+			}
+			//the set section may be followed by a description of its columns
+			if section := strings.Trim(strings.Split(t, ":")[0], " "); strings.HasSuffix(section, "_SECTION") {
+				metaData = false
+				edgeWeightSection = section == "EDGE_WEIGHT_SECTION"
+				nodeCoordSection = section == "NODE_COORD_SECTION" || section == "DISPLAY_DATA_SECTION"
+				setSection = section == "GTSP_SET_SECTION"
+				continue
+			}
+			if metaData {
+				lineSplit := strings.Split(t, ":")
+				if len(lineSplit) < 2 {
+					continue
+				}
+				lineSplit[0] = strings.Trim(lineSplit[0], " ")
+				lineSplit[1] = strings.Trim(lineSplit[1], " ")
+
+				switch lineSplit[0] {
+				case "NAME":
+					name = lineSplit[1]
+				case "COMMENT":
+					comment = lineSplit[1]
+				case "TYPE":
+					problemType = lineSplit[1]
+				case "DIMENSION":
+					dimension, err = strconv.Atoi(lineSplit[1])
+				case "TMAX", "COST_LIMIT":
+					tmax, err = strconv.ParseFloat(lineSplit[1], 64)
+				case "START_CLUSTER":
+					startCluster, err = strconv.Atoi(lineSplit[1])
+				case "END_CLUSTER":
+					endCluster, err = strconv.Atoi(lineSplit[1])
+				case "EDGE_WEIGHT_TYPE":
+					edgeWeightType = lineSplit[1]
+				case "EDGE_WEIGHT_FORMAT":
+					edgeWeightFormat = lineSplit[1]
+				}
+				if err != nil {
+					fmt.Printf("Couldn't parse %s! Skipping file: %s\n", lineSplit[0], err.Error())
+					continue FILES
+				}
+				continue
+			}
+			if edgeWeightSection {
+				//the rows of the file don't have to match the rows of the matrix, so we just keep them as they are
+				var row []float64
+				for _, w := range strings.Fields(t) {
+					weight, err := strconv.ParseFloat(w, 64)
+					if err != nil {
+						fmt.Printf("Couldn't parse the edge weights! Skipping file: %s\n", err.Error())
+						continue FILES
+					}
+					row = append(row, weight)
+				}
+				edgeWeightRows = append(edgeWeightRows, row)
+			}
+			if nodeCoordSection {
+				xyString := strings.Fields(t)
+
+				//2 or 3 coordinates after the node index, depending on the edge weight type
+				var xy []float64
+				for _, c := range xyString[1:] {
+					v, err := strconv.ParseFloat(c, 64)
+					if err != nil {
+						fmt.Printf("Error parsing coordinate!: %s", err.Error())
+					}
+					xy = append(xy, v)
+				}
+				coordinates = append(coordinates, xy)
+			}
+			if setSection {
+				setString := strings.Fields(t)
+				if len(setString) < 3 {
+					continue
+				}
+				id, err := strconv.Atoi(setString[0])
+				if err != nil {
+					fmt.Printf("Couldn't parse the set id! Skipping file: %s\n", err.Error())
+					continue FILES
+				}
+				profit, err := strconv.ParseFloat(setString[1], 64)
+				if err != nil {
+					fmt.Printf("Couldn't parse the set profit! Skipping file: %s\n", err.Error())
+					continue FILES
+				}
+				var cluster []int
+				for _, n := range setString[2:] {
+					node, err := strconv.Atoi(n)
+					if err != nil {
+						fmt.Printf("Couldn't parse the nodes of set %d! Skipping file: %s\n", id, err.Error())
+						continue FILES
+					}
+					if node < 0 {
+						break
+					}
+					cluster = append(cluster, node-1) //its not 0-indexed in the file, so we subtract 1
+				}
+				clusterIDs = append(clusterIDs, id)
+				clusters = append(clusters, cluster)
+				clusterPrices = append(clusterPrices, profit)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatal(err)
+			continue
+		}
+
+		depots := make([]int, 2)
+		for k, depotCluster := range []int{startCluster, endCluster} {
+			depots[k] = -1
+			for c, id := range clusterIDs {
+				if id == depotCluster && len(clusters[c]) > 0 {
+					depots[k] = clusters[c][0]
+				}
+			}
+			if depots[k] < 0 {
+				fmt.Printf("Cluster %d of the depot doesn't exist! Skipping file %s\n", depotCluster, f.Name())
+				continue FILES
+			}
+		}
+		if depots[0] == depots[1] {
+			depots = depots[:1]
+		}
+		if problemType == "" {
+			problemType = "SOP"
+		}
+
+		inst := op.Instance{Name: name, Comment: comment, Type: problemType, Dimension: dimension, DisplayDataType: "COORD_DISPLAY", EdgeWeightType: edgeWeightType, NodeCoordinates: coordinates, Prices: make([]float64, dimension), TMax: tmax, Depots: depots, Clusters: clusters, ClusterPrices: clusterPrices}
+		if edgeWeightType == op.EXPLICIT {
+			inst.EdgeWeightFormat = edgeWeightFormat
+			inst.EdgeWeights = edgeWeightRows
+			if len(coordinates) == 0 {
+				inst.DisplayDataType = "NO_DISPLAY"
+			}
+		}
+		if problems := inst.Validate(); len(problems) > 0 {
+			for _, problem := range problems {
+				fmt.Printf("Invalid instance - %s\n", problem)
+			}
+			fmt.Printf("Skipping file %s\n", f.Name())
+			continue FILES
+		}
+
+		if calcTSP == "tsp" {
+			edgeWeights, err := inst.GetEdgeDist()
+			if err != nil {
+				fmt.Printf("Couldn't calculate the edge weights! Skipping file: %s\n", err.Error())
+				continue FILES
+			}
+			_, inst.TSPLength, _ = tsp.SolveTSPPath(env, f.Name(), edgeWeights, depots[0], depots[len(depots)-1])
+		}
+
+		err = op.SaveInstanceFile(fileName, &inst)
+		if err != nil {
+			log.Fatal(err)
+			continue
+		}
+	}
+}
@@ -2,57 +2,34 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
+	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	_ "git.solver4all.com/azaryc2s/op/mip/bnc"
+	_ "git.solver4all.com/azaryc2s/op/mip/grb"
 	"git.solver4all.com/azaryc2s/op/tsp"
 	"io/ioutil"
 	"log"
-	"math"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-type OPInstance struct {
-	Name    string `json:"name"`
-	Comment string `json:"comment"`
-	Type    string `json:"type"`
-
-	Dimension       int         `json:"dimension"`
-	DisplayDataType string      `json:"display_data_type"`
-	EdgeWeightType  string      `json:"edge_weight_type"`
-	Depots          []int       `json:"depots"`
-	NodeCoordinates [][]int     `json:"node_coordinates"`
-	EdgeWeights     [][]float64 `json:"edge_weights"`
-	Prices          []int       `json:"prices"`
-	TMax            int         `json:"tmax"`
-	TSPLength       float64     `json:"tsp_length"`
-}
-
-var scaling float64
-
 func main() {
 	var (
 		err error
 	)
 	targetDir := os.Args[1]
 	comment := os.Args[2]
-	if len(os.Args) > 3 {
-		scalingStr := os.Args[3]
-		scaling, err = strconv.ParseFloat(scalingStr, 64)
-		if err != nil {
-			log.Fatal(err)
-			return
-		}
-		comment = fmt.Sprintf("%s | coordinates scaled by %d from the original to be integers", comment, int(scaling))
-	} else {
-		scaling = 1.0
-	}
 	files, err := ioutil.ReadDir(targetDir)
 	if err != nil {
 		log.Fatal(err)
 	}
+	env, err := mip.LoadEnv("tsp_gurobi.log")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer env.Free()
 	for _, f := range files {
 		if !strings.Contains(f.Name(), ".txt") {
 			continue
@@ -67,19 +44,40 @@ func main() {
 		line := -1
 		nodeCount := 0
 		var edgeWeights [][]float64
-		var coordinates [][]int
-		var nodeScores []int
-		var tmax int
+		var coordinates [][]float64
+		var nodeScores []float64
+		var tmax float64
+		var vehicles int
+		//the TOP files of Chao start with the lines "n <nodes>", "m <vehicles>" and "tmax <budget>" instead of "<tmax> <vehicles>"
+		chao := false
 		for scanner.Scan() {
 			line = line + 1
 			t := scanner.Text()
-			xyz := strings.Split(t, "\t")
+			xyz := strings.Fields(t)
+			if len(xyz) == 0 {
+				continue
+			}
+			if xyz[0] == "n" || xyz[0] == "m" || xyz[0] == "tmax" {
+				chao = true
+				if xyz[0] == "m" {
+					vehicles, err = strconv.Atoi(xyz[1])
+					if err != nil {
+						fmt.Printf("Error parsing the number of vehicles!: %s", err.Error())
+					}
+				}
+				if xyz[0] == "tmax" {
+					tmax, err = strconv.ParseFloat(xyz[1], 64)
+					if err != nil {
+						fmt.Printf("Error parsing tmax!: %s", err.Error())
+					}
+				}
+				continue
+			}
 			if line == 0 {
-				maxScore, err := strconv.Atoi(xyz[0])
+				tmax, err = strconv.ParseFloat(xyz[0], 64)
 				if err != nil {
 					fmt.Printf("Error parsing tmax!: %s", err.Error())
 				}
-				tmax = maxScore * int(scaling)
 				continue
 			}
 			x, err := strconv.ParseFloat(xyz[0], 64)
@@ -90,64 +88,51 @@ func main() {
 			if err != nil {
 				fmt.Printf("Error parsing coordinate y!: %s", err.Error())
 			}
-			z, err := strconv.Atoi(xyz[2])
+			z, err := strconv.ParseFloat(xyz[2], 64)
 			if err != nil {
 				fmt.Printf("Error parsing node score!: %s", err.Error())
 			}
-			xy := []int{int(x * scaling), int(y * scaling)}
+			xy := []float64{x, y}
 			coordinates = append(coordinates, xy)
 			nodeScores = append(nodeScores, z)
 			nodeCount++
 		}
-		edgeWeights = make([][]float64, nodeCount)
-		for i := 0; i < nodeCount; i++ {
-			edgeWeights[i] = make([]float64, nodeCount)
+		//the original distances are real-valued euclidean ones
+		edgeWeights, err = op.CalcEdgeDist(coordinates, op.EXACT_2D)
+		if err != nil {
+			log.Fatal(err)
 		}
-		for i := 0; i < nodeCount; i++ {
-			for j := i + 1; j < nodeCount; j++ {
-				x := coordinates[i][0] - coordinates[j][0]
-				y := coordinates[i][1] - coordinates[j][1]
 
-				distance := math.Sqrt(float64(x*x+y*y)) //coordinates already scaled
-				edgeWeights[i][j] = distance
-				edgeWeights[j][i] = distance
-			}
+		//the first point is the start and the second the end of the path. In the files of Chao the last point is the end
+		depots := []int{0, 1}
+		problemType := "OP"
+		if chao {
+			depots = []int{0, nodeCount - 1}
 		}
+		if vehicles > 1 {
+			problemType = "TOP"
+		}
+		_, tspLength, _ := tsp.SolveTSPPath(env, f.Name(), edgeWeights, depots[0], depots[1])
 
-		_, tspLength := tsp.SolveATSP(edgeWeights)
-
-
-		inst := OPInstance{Name: strings.ReplaceAll(f.Name(), ".txt", ""), Comment: comment, Type: "OP", Dimension: nodeCount, DisplayDataType: "COORD_DISPLAY", EdgeWeightType: "EUC_2D", NodeCoordinates: coordinates, TSPLength: tspLength, Prices: nodeScores, TMax: tmax, Depots: []int{0, 1}}
+		inst := op.Instance{Name: strings.ReplaceAll(f.Name(), ".txt", ""), Comment: comment, Type: problemType, Dimension: nodeCount, DisplayDataType: "COORD_DISPLAY", EdgeWeightType: op.EXACT_2D, NodeCoordinates: coordinates, TSPLength: tspLength, Prices: nodeScores, TMax: tmax, Depots: depots, Vehicles: vehicles}
 
-		if err := scanner.Err(); err != nil {
-			log.Fatal(err)
+		if problems := inst.Validate(); len(problems) > 0 {
+			for _, problem := range problems {
+				fmt.Printf("Invalid instance - %s\n", problem)
+			}
+			fmt.Printf("Skipping file %s\n", f.Name())
 			continue
 		}
 
-		jsonInst, err := json.MarshalIndent(inst, "", "\t")
-		if err != nil {
+		if err := scanner.Err(); err != nil {
 			log.Fatal(err)
 			continue
 		}
 
-		jsonInst = []byte(sanitizeJsonArrayLineBreaks(string(jsonInst)))
-		err = ioutil.WriteFile(fileName, jsonInst, 0644)
+		err = op.SaveInstanceFile(fileName, &inst)
 		if err != nil {
 			log.Fatal(err)
 			continue
 		}
 	}
 }
-
-func sanitizeJsonArrayLineBreaks(json string) string {
-	res := fmt.Sprintf("%s", json)
-	var numbers = regexp.MustCompile(`\s*([-]?[0-9]+),\s+([-]?[0-9]+)(,)?`)
-	var brackets = regexp.MustCompile(`\[(([-]?[0-9]+,)+[-]?[0-9]+)\s+\](,?)(\s+)`)
-	for numbers.MatchString(res) {
-		res = numbers.ReplaceAllString(res, "$1,$2$3")
-	}
-	for brackets.MatchString(res) {
-		res = brackets.ReplaceAllString(res, "[$1]$3$4")
-	}
-	return res
-}
@@ -0,0 +1,202 @@
+package op
+
+import (
+	"fmt"
+	"math"
+)
+
+/* EDGE_WEIGHT_TYPEs as defined in TSPLIB 95 */
+const (
+	EXPLICIT = "EXPLICIT"
+	EUC_2D   = "EUC_2D"
+	EUC_3D   = "EUC_3D"
+	MAX_2D   = "MAX_2D"
+	MAX_3D   = "MAX_3D"
+	MAN_2D   = "MAN_2D"
+	MAN_3D   = "MAN_3D"
+	CEIL_2D  = "CEIL_2D"
+	GEO      = "GEO"
+	ATT      = "ATT"
+)
+
+// EDGE_WEIGHT_TYPEs for real-valued euclidean distances, which TSPLIB doesn't have. All TSPLIB types keep
+// their integer rounding, so e.g. EUC_2D instances are solved exactly like before.
+const (
+	EXACT_2D = "EXACT_2D"
+	EXACT_3D = "EXACT_3D"
+)
+
+// EPSILON is the relative tolerance used when comparing real-valued lengths and prizes
+const EPSILON = 1e-6
+
+// NearlyEqual returns true if a and b differ by at most EPSILON relative to their magnitude
+func NearlyEqual(a, b float64) bool {
+	return math.Abs(a-b) <= EPSILON*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+// Exceeds returns true if a is larger than b and not just by rounding errors
+func Exceeds(a, b float64) bool {
+	return a > b && !NearlyEqual(a, b)
+}
+
+// distance functions between two nodes and the number of coordinates they need
+var distFuncs = map[string]struct {
+	dim  int
+	dist func(a, b []float64) float64
+}{
+	EUC_2D:   {2, func(a, b []float64) float64 { return nint(euclid(a, b, 2)) }},
+	EUC_3D:   {3, func(a, b []float64) float64 { return nint(euclid(a, b, 3)) }},
+	MAX_2D:   {2, func(a, b []float64) float64 { return maximum(a, b, 2) }},
+	MAX_3D:   {3, func(a, b []float64) float64 { return maximum(a, b, 3) }},
+	MAN_2D:   {2, func(a, b []float64) float64 { return nint(manhattan(a, b, 2)) }},
+	MAN_3D:   {3, func(a, b []float64) float64 { return nint(manhattan(a, b, 3)) }},
+	CEIL_2D:  {2, func(a, b []float64) float64 { return math.Ceil(euclid(a, b, 2)) }},
+	GEO:      {2, geo},
+	ATT:      {2, att},
+	EXACT_2D: {2, func(a, b []float64) float64 { return euclid(a, b, 2) }},
+	EXACT_3D: {3, func(a, b []float64) float64 { return euclid(a, b, 3) }},
+}
+
+// CalcEdgeDist calculates the distance matrix for the given coordinates according to the TSPLIB
+// EDGE_WEIGHT_TYPE. EXPLICIT and unknown types can't be calculated and return an error.
+func CalcEdgeDist(coordinates [][]float64, distType string) ([][]float64, error) {
+	d, err := NewCoordDistances(coordinates, distType)
+	if err != nil {
+		return nil, err
+	}
+	n := len(coordinates)
+	result := make([][]float64, n)
+	for node := 0; node < n; node++ {
+		result[node] = make([]float64, n)
+		for node2 := 0; node2 < node; node2++ {
+			distance := d.Dist(node, node2)
+			result[node][node2] = distance
+			result[node2][node] = distance
+		}
+	}
+	return result, nil
+}
+
+/* EDGE_WEIGHT_FORMATs of explicit edge weights as defined in TSPLIB 95 */
+const (
+	FULL_MATRIX    = "FULL_MATRIX"
+	UPPER_ROW      = "UPPER_ROW"
+	LOWER_ROW      = "LOWER_ROW"
+	UPPER_DIAG_ROW = "UPPER_DIAG_ROW"
+	LOWER_DIAG_ROW = "LOWER_DIAG_ROW"
+	UPPER_COL      = "UPPER_COL"
+	LOWER_COL      = "LOWER_COL"
+	UPPER_DIAG_COL = "UPPER_DIAG_COL"
+	LOWER_DIAG_COL = "LOWER_DIAG_COL"
+)
+
+// GetEdgeDist returns the distance matrix of the instance. Edge weights given in the instance
+// are expanded according to its EDGE_WEIGHT_FORMAT, otherwise they are calculated from the coordinates.
+func (inst *Instance) GetEdgeDist() ([][]float64, error) {
+	if len(inst.EdgeWeights) == 0 {
+		if inst.EdgeWeightType == EXPLICIT {
+			return nil, fmt.Errorf("%s instance without edge weights", EXPLICIT)
+		}
+		return CalcEdgeDist(inst.NodeCoordinates, inst.EdgeWeightType)
+	}
+	n := inst.Dimension
+	if n == 0 {
+		n = len(inst.Prices)
+	}
+	return ExpandEdgeWeights(inst.EdgeWeights, inst.EdgeWeightFormat, n)
+}
+
+// ExpandEdgeWeights builds the full n x n distance matrix from edge weights given in one of the
+// TSPLIB EDGE_WEIGHT_FORMATs. Like in TSPLIB files, only the order of the numbers matters and not
+// how they are split into rows. An empty format is read as FULL_MATRIX, which is also the only
+// format that can hold asymmetric distances.
+func ExpandEdgeWeights(weights [][]float64, format string, n int) ([][]float64, error) {
+	d, err := NewExplicitDistances(weights, format, n)
+	if err != nil {
+		return nil, err
+	}
+	return ToMatrix(d), nil
+}
+
+// IsSymmetric returns true if d[i][j] == d[j][i] for all nodes
+func IsSymmetric(d DistanceMatrix) bool {
+	switch m := d.(type) {
+	case *CoordDistances:
+		//all distance functions of coordinates are symmetric
+		return true
+	case *ExplicitDistances:
+		if !m.full {
+			return true
+		}
+	}
+	for i := 0; i < d.Len(); i++ {
+		for j := 0; j < i; j++ {
+			if d.Dist(i, j) != d.Dist(j, i) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// nint rounds to the nearest integer like the TSPLIB reference implementation: (int) (x + 0.5)
+func nint(x float64) float64 {
+	return float64(int(x + 0.5))
+}
+
+func euclid(a, b []float64, dim int) float64 {
+	sum := 0.0
+	for k := 0; k < dim; k++ {
+		sum += (a[k] - b[k]) * (a[k] - b[k])
+	}
+	return math.Sqrt(sum)
+}
+
+func manhattan(a, b []float64, dim int) float64 {
+	sum := 0.0
+	for k := 0; k < dim; k++ {
+		sum += math.Abs(a[k] - b[k])
+	}
+	return sum
+}
+
+func maximum(a, b []float64, dim int) float64 {
+	max := 0.0
+	for k := 0; k < dim; k++ {
+		if d := nint(math.Abs(a[k] - b[k])); d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+/* the coordinates are given as DDD.MM (degrees and minutes) with x as latitude and y as longitude */
+func geo(a, b []float64) float64 {
+	const (
+		pi  = 3.141592
+		rrr = 6378.388
+	)
+	toRad := func(x float64) float64 {
+		deg := math.Trunc(x)
+		min := x - deg
+		return pi * (deg + 5.0*min/3.0) / 180.0
+	}
+	latA, lonA := toRad(a[0]), toRad(a[1])
+	latB, lonB := toRad(b[0]), toRad(b[1])
+	q1 := math.Cos(lonA - lonB)
+	q2 := math.Cos(latA - latB)
+	q3 := math.Cos(latA + latB)
+	return float64(int(rrr*math.Acos(0.5*((1.0+q1)*q2-(1.0-q1)*q3)) + 1.0))
+}
+
+/* pseudo-euclidean distance used by the att instances */
+func att(a, b []float64) float64 {
+	xd := a[0] - b[0]
+	yd := a[1] - b[1]
+	r := math.Sqrt((xd*xd + yd*yd) / 10.0)
+	t := nint(r)
+	if t < r {
+		return t + 1
+	}
+	return t
+}
@@ -0,0 +1,112 @@
+package op
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDist(t *testing.T) {
+	tests := []struct {
+		name     string
+		distType string
+		a, b     []float64
+		expected float64
+	}{
+		{"EUC_2D", EUC_2D, []float64{0, 0}, []float64{3, 4}, 5},
+		{"EUC_2D rounded down", EUC_2D, []float64{0, 0}, []float64{1, 1}, 1},
+		{"EUC_2D rounded up", EUC_2D, []float64{0, 0}, []float64{1.5, 2}, 3},
+		//nint rounds halves up like (int) (x + 0.5) and not to the even number
+		{"EUC_2D half", EUC_2D, []float64{0, 0}, []float64{0, 2.5}, 3},
+		{"EUC_3D", EUC_3D, []float64{0, 0, 0}, []float64{1, 2, 2.4}, 3},
+		{"CEIL_2D", CEIL_2D, []float64{0, 0}, []float64{1, 1}, 2},
+		{"CEIL_2D integral", CEIL_2D, []float64{0, 0}, []float64{3, 4}, 5},
+		{"MAN_2D", MAN_2D, []float64{0, 0}, []float64{1.4, 2.3}, 4},
+		{"MAN_3D", MAN_3D, []float64{0, 0, 0}, []float64{1.2, 1, 1}, 3},
+		//every coordinate is rounded before the maximum
+		{"MAX_2D", MAX_2D, []float64{0, 0}, []float64{1.4, 2.6}, 3},
+		{"MAX_3D", MAX_3D, []float64{0, 0, 0}, []float64{1.4, 2.4, 0.6}, 2},
+		//att rounds up unless the rounded distance isn't smaller
+		{"ATT", ATT, []float64{0, 0}, []float64{10, 0}, 4},
+		{"ATT rounded", ATT, []float64{0, 0}, []float64{30, 40}, 16},
+		{"ATT integral", ATT, []float64{0, 0}, []float64{10, 30}, 10},
+		//the first two cities of ulysses16
+		{"GEO", GEO, []float64{38.24, 20.42}, []float64{39.57, 26.15}, 509},
+		{"EXACT_2D", EXACT_2D, []float64{0, 0}, []float64{1, 1}, math.Sqrt2},
+		{"EXACT_3D", EXACT_3D, []float64{0, 0, 0}, []float64{1, 1, 1}, math.Sqrt(3)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := NewCoordDistances([][]float64{test.a, test.b}, test.distType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dist := d.Dist(0, 1); dist != test.expected {
+				t.Errorf("distance %g, expected %g", dist, test.expected)
+			}
+			if dist := d.Dist(1, 0); dist != test.expected {
+				t.Errorf("reverse distance %g, expected %g", dist, test.expected)
+			}
+		})
+	}
+}
+
+//the optimal tours of TSPLIB instances, whose lengths only match with the rounding of the reference implementation
+func TestTSPLIBTours(t *testing.T) {
+	tests := []struct {
+		name        string
+		distType    string
+		coordinates [][]float64
+		tour        []int //the nodes starting at 1 like in the .opt.tour files
+		expected    float64
+	}{
+		{"ulysses16", GEO,
+			[][]float64{{38.24, 20.42}, {39.57, 26.15}, {40.56, 25.32}, {36.26, 23.12}, {33.48, 10.54}, {37.56, 12.19},
+				{38.42, 13.11}, {37.52, 20.44}, {41.23, 9.10}, {41.17, 13.05}, {36.08, -5.21}, {38.47, 15.13},
+				{38.15, 15.35}, {37.51, 15.17}, {35.49, 14.32}, {39.36, 19.56}},
+			[]int{1, 14, 13, 12, 7, 6, 15, 5, 11, 9, 10, 16, 3, 2, 4, 8}, 6859},
+		{"att48", ATT,
+			[][]float64{{6734, 1453}, {2233, 10}, {5530, 1424}, {401, 841}, {3082, 1644}, {7608, 4458}, {7573, 3716},
+				{7265, 1268}, {6898, 1885}, {1112, 2049}, {5468, 2606}, {5989, 2873}, {4706, 2674}, {4612, 2035},
+				{6347, 2683}, {6107, 669}, {7611, 5184}, {7462, 3590}, {7732, 4723}, {5900, 3561}, {4483, 3369},
+				{6101, 1110}, {5199, 2182}, {1633, 2809}, {4307, 2322}, {675, 1006}, {7555, 4819}, {7541, 3981},
+				{3177, 756}, {7352, 4506}, {7545, 2801}, {3245, 3305}, {6426, 3173}, {4608, 1198}, {23, 2216},
+				{7248, 3779}, {7762, 4595}, {7392, 2244}, {3484, 2829}, {6271, 2135}, {4985, 140}, {1916, 1569},
+				{7280, 4899}, {7509, 3239}, {10, 2676}, {6807, 2993}, {5185, 3258}, {3023, 1942}},
+			[]int{1, 8, 38, 31, 44, 18, 7, 28, 6, 37, 19, 27, 17, 43, 30, 36, 46, 33, 20, 47, 21, 32, 39, 48, 5, 42,
+				24, 10, 45, 35, 4, 26, 2, 29, 34, 41, 16, 22, 3, 23, 14, 25, 13, 11, 12, 15, 40, 9}, 10628},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := CalcEdgeDist(test.coordinates, test.distType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tour := make([]int, len(test.tour))
+			for i, node := range test.tour {
+				tour[i] = node - 1
+			}
+			if length := GetTourLength(tour, Matrix(d)); length != test.expected {
+				t.Errorf("tour length %g, expected %g", length, test.expected)
+			}
+		})
+	}
+}
+
+func TestCalcEdgeDistErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		distType    string
+		coordinates [][]float64
+	}{
+		{"explicit", EXPLICIT, [][]float64{{0, 0}, {1, 1}}},
+		{"unknown", "EUC_4D", [][]float64{{0, 0}, {1, 1}}},
+		{"missing coordinate", EUC_3D, [][]float64{{0, 0, 0}, {1, 1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := CalcEdgeDist(test.coordinates, test.distType); err == nil {
+				t.Errorf("no error for %s", test.distType)
+			}
+		})
+	}
+}
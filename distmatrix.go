@@ -0,0 +1,207 @@
+package op
+
+import (
+	"fmt"
+)
+
+// DistanceMatrix gives the distances between the nodes of an instance. Depending on the implementation the distances
+// are calculated when needed, read from the edge weights of the file or cached.
+type DistanceMatrix interface {
+	// Dist returns the distance from node i to node j
+	Dist(i, j int) float64
+	// Len returns the number of nodes
+	Len() int
+}
+
+// GetDistanceMatrix returns the distances of the instance without building the full matrix. Distances of coordinates
+// are calculated on every lookup, edge weights given in the instance are read in their EDGE_WEIGHT_FORMAT.
+func (inst *Instance) GetDistanceMatrix() (DistanceMatrix, error) {
+	if len(inst.EdgeWeights) == 0 {
+		if inst.EdgeWeightType == EXPLICIT {
+			return nil, fmt.Errorf("%s instance without edge weights", EXPLICIT)
+		}
+		return NewCoordDistances(inst.NodeCoordinates, inst.EdgeWeightType)
+	}
+	n := inst.Dimension
+	if n == 0 {
+		n = len(inst.Prices)
+	}
+	return NewExplicitDistances(inst.EdgeWeights, inst.EdgeWeightFormat, n)
+}
+
+// CoordDistances calculates the distances from the coordinates of the nodes on every lookup. It needs no memory
+// besides the coordinates, but every lookup pays for the distance function.
+type CoordDistances struct {
+	coordinates [][]float64
+	dist        func(a, b []float64) float64
+}
+
+// NewCoordDistances returns the distances of the coordinates according to the TSPLIB EDGE_WEIGHT_TYPE. EXPLICIT and
+// unknown types can't be calculated and return an error.
+func NewCoordDistances(coordinates [][]float64, distType string) (*CoordDistances, error) {
+	f, ok := distFuncs[distType]
+	if !ok {
+		if distType == EXPLICIT {
+			return nil, fmt.Errorf("edge weights of type %s can't be calculated from coordinates", distType)
+		}
+		return nil, fmt.Errorf("unknown edge weight type %q", distType)
+	}
+	for node := 0; node < len(coordinates); node++ {
+		if len(coordinates[node]) < f.dim {
+			return nil, fmt.Errorf("node %d has %d coordinates but %s needs %d", node, len(coordinates[node]), distType, f.dim)
+		}
+	}
+	return &CoordDistances{coordinates: coordinates, dist: f.dist}, nil
+}
+
+func (c *CoordDistances) Dist(i, j int) float64 {
+	if i == j {
+		return 0
+	}
+	return c.dist(c.coordinates[i], c.coordinates[j])
+}
+
+func (c *CoordDistances) Len() int {
+	return len(c.coordinates)
+}
+
+// ExplicitDistances reads the distances from the edge weights of the file in their EDGE_WEIGHT_FORMAT, so the
+// triangular formats only need half of the memory of the full matrix.
+type ExplicitDistances struct {
+	n           int
+	values      []float64
+	upper, diag bool
+	full        bool
+}
+
+// NewExplicitDistances returns the distances of n nodes given by edge weights in one of the TSPLIB
+// EDGE_WEIGHT_FORMATs. Like in TSPLIB files, only the order of the numbers matters and not how they are split into
+// rows. An empty format is read as FULL_MATRIX, which is also the only format that can hold asymmetric distances.
+func NewExplicitDistances(weights [][]float64, format string, n int) (*ExplicitDistances, error) {
+	var values []float64
+	for _, row := range weights {
+		values = append(values, row...)
+	}
+	e := &ExplicitDistances{n: n, values: values}
+
+	/* every format is a sequence of rows, the column formats are the transposed row formats */
+	switch format {
+	case "", FULL_MATRIX:
+		if len(values) != n*n {
+			return nil, fmt.Errorf("%s needs %d edge weights for %d nodes, got %d", FULL_MATRIX, n*n, n, len(values))
+		}
+		e.full = true
+		return e, nil
+	case UPPER_ROW, LOWER_COL:
+		e.upper, e.diag = true, false
+	case LOWER_ROW, UPPER_COL:
+		e.upper, e.diag = false, false
+	case UPPER_DIAG_ROW, LOWER_DIAG_COL:
+		e.upper, e.diag = true, true
+	case LOWER_DIAG_ROW, UPPER_DIAG_COL:
+		e.upper, e.diag = false, true
+	default:
+		return nil, fmt.Errorf("unknown edge weight format %q", format)
+	}
+
+	count := n * (n - 1) / 2
+	if e.diag {
+		count += n
+	}
+	if len(values) != count {
+		return nil, fmt.Errorf("%s needs %d edge weights for %d nodes, got %d", format, count, n, len(values))
+	}
+	return e, nil
+}
+
+func (e *ExplicitDistances) Dist(i, j int) float64 {
+	if e.full {
+		return e.values[i*e.n+j]
+	}
+	if i == j && !e.diag {
+		return 0
+	}
+	//the triangular formats only hold the row i for the columns on one side of the diagonal
+	if (e.upper && i > j) || (!e.upper && i < j) {
+		i, j = j, i
+	}
+	switch {
+	case e.upper && e.diag:
+		return e.values[i*e.n-i*(i-1)/2+j-i]
+	case e.upper:
+		return e.values[i*e.n-i*(i+1)/2+j-i-1]
+	case e.diag:
+		return e.values[i*(i+1)/2+j]
+	default:
+		return e.values[i*(i-1)/2+j]
+	}
+}
+
+func (e *ExplicitDistances) Len() int {
+	return e.n
+}
+
+// FlatDistances caches all distances row by row in one contiguous slice. A lookup is a single index, without the
+// pointer of every row a [][]float64 needs.
+type FlatDistances struct {
+	n      int
+	values []float64
+}
+
+// NewFlatDistances caches the distances of d
+func NewFlatDistances(d DistanceMatrix) *FlatDistances {
+	n := d.Len()
+	f := &FlatDistances{n: n, values: make([]float64, n*n)}
+	for i := 0; i < n; i++ {
+		row := f.values[i*n : (i+1)*n]
+		for j := 0; j < n; j++ {
+			row[j] = d.Dist(i, j)
+		}
+	}
+	return f
+}
+
+func (f *FlatDistances) Dist(i, j int) float64 {
+	return f.values[i*f.n+j]
+}
+
+func (f *FlatDistances) Len() int {
+	return f.n
+}
+
+// Set changes the distance from node i to node j
+func (f *FlatDistances) Set(i, j int, dist float64) {
+	f.values[i*f.n+j] = dist
+}
+
+// ClosePath makes the edge between the start and the end depot free like ClosePath does for a [][]float64
+func (f *FlatDistances) ClosePath(start, end int) {
+	if start != end {
+		f.Set(start, end, 0)
+		f.Set(end, start, 0)
+	}
+}
+
+// Matrix uses a full [][]float64 as a DistanceMatrix
+type Matrix [][]float64
+
+func (m Matrix) Dist(i, j int) float64 {
+	return m[i][j]
+}
+
+func (m Matrix) Len() int {
+	return len(m)
+}
+
+// ToMatrix returns the full [][]float64 of the distances for code, that needs all of them at once
+func ToMatrix(d DistanceMatrix) [][]float64 {
+	n := d.Len()
+	result := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		result[i] = make([]float64, n)
+		for j := 0; j < n; j++ {
+			result[i][j] = d.Dist(i, j)
+		}
+	}
+	return result
+}
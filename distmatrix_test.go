@@ -0,0 +1,196 @@
+package op
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+//a path instance with random coordinates
+func randomInstance(rnd *rand.Rand, n int) *Instance {
+	inst := &Instance{Name: fmt.Sprintf("benchmark_%d", n), Dimension: n, EdgeWeightType: EUC_2D, Depots: []int{0, n - 1}}
+	inst.NodeCoordinates = make([][]float64, n)
+	inst.Prices = make([]float64, n)
+	for node := 0; node < n; node++ {
+		inst.NodeCoordinates[node] = []float64{float64(rnd.Intn(10000)), float64(rnd.Intn(10000))}
+		inst.Prices[node] = float64(rnd.Intn(100))
+	}
+	inst.Prices[0], inst.Prices[n-1] = 0, 0
+	inst.TMax = 10000
+	return inst
+}
+
+//the distances of an instance with 2000 nodes, which get slow for large instances
+func BenchmarkDistances(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	inst := randomInstance(rnd, 2000)
+	start, end := inst.GetDepots()
+	full, err := inst.GetEdgeDist()
+	if err != nil {
+		b.Fatal(err)
+	}
+	coord, err := inst.GetDistanceMatrix()
+	if err != nil {
+		b.Fatal(err)
+	}
+	flat := NewFlatDistances(coord)
+	var upperRow []float64
+	for i := 0; i < inst.Dimension; i++ {
+		upperRow = append(upperRow, full[i][i+1:]...)
+	}
+	explicit, err := NewExplicitDistances([][]float64{upperRow}, UPPER_ROW, inst.Dimension)
+	if err != nil {
+		b.Fatal(err)
+	}
+	tour := rnd.Perm(inst.Dimension)
+
+	//the distances the solver needed before the DistanceMatrix: the full matrix and its copy with the closed path
+	b.Run("Setup/Matrix", func(b *testing.B) {
+		b.ReportAllocs()
+		for k := 0; k < b.N; k++ {
+			d, _ := inst.GetEdgeDist()
+			ClosePath(d, start, end)
+		}
+	})
+	b.Run("Setup/Flat", func(b *testing.B) {
+		b.ReportAllocs()
+		for k := 0; k < b.N; k++ {
+			d, _ := inst.GetDistanceMatrix()
+			NewFlatDistances(d).ClosePath(start, end)
+		}
+	})
+	b.Run("Setup/Coord", func(b *testing.B) {
+		b.ReportAllocs()
+		for k := 0; k < b.N; k++ {
+			inst.GetDistanceMatrix()
+		}
+	})
+	b.Run("Validate", func(b *testing.B) {
+		b.ReportAllocs()
+		for k := 0; k < b.N; k++ {
+			inst.Validate()
+		}
+	})
+	for _, bm := range []struct {
+		name string
+		d    DistanceMatrix
+	}{{"Matrix", Matrix(full)}, {"Flat", flat}, {"Coord", coord}, {"Explicit", explicit}} {
+		d := bm.d
+		b.Run("TourLength/"+bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for k := 0; k < b.N; k++ {
+				GetTourLength(tour, d)
+			}
+		})
+	}
+}
+
+func TestExplicitDistances(t *testing.T) {
+	//the symmetric distances of 4 nodes, the diagonal formats give the diagonal from 7 to 10
+	sym := [][]float64{{0, 1, 2, 3}, {1, 0, 4, 5}, {2, 4, 0, 6}, {3, 5, 6, 0}}
+	diag := [][]float64{{7, 1, 2, 3}, {1, 8, 4, 5}, {2, 4, 9, 6}, {3, 5, 6, 10}}
+	asym := [][]float64{{0, 1, 2, 3}, {4, 0, 5, 6}, {7, 8, 0, 9}, {10, 11, 12, 0}}
+	tests := []struct {
+		name     string
+		format   string
+		weights  [][]float64
+		expected [][]float64
+	}{
+		{"FULL_MATRIX", FULL_MATRIX, asym, asym},
+		{"no format", "", asym, asym},
+		{"FULL_MATRIX in one row", FULL_MATRIX, [][]float64{{0, 1, 2, 3, 4, 0, 5, 6, 7, 8, 0, 9, 10, 11, 12, 0}}, asym},
+		{"UPPER_ROW", UPPER_ROW, [][]float64{{1, 2, 3}, {4, 5}, {6}}, sym},
+		{"LOWER_ROW", LOWER_ROW, [][]float64{{1}, {2, 4}, {3, 5, 6}}, sym},
+		{"UPPER_DIAG_ROW", UPPER_DIAG_ROW, [][]float64{{7, 1, 2, 3}, {8, 4, 5}, {9, 6}, {10}}, diag},
+		{"LOWER_DIAG_ROW", LOWER_DIAG_ROW, [][]float64{{7}, {1, 8}, {2, 4, 9}, {3, 5, 6, 10}}, diag},
+		{"UPPER_COL", UPPER_COL, [][]float64{{1}, {2, 4}, {3, 5, 6}}, sym},
+		{"LOWER_COL", LOWER_COL, [][]float64{{1, 2, 3}, {4, 5}, {6}}, sym},
+		{"UPPER_DIAG_COL", UPPER_DIAG_COL, [][]float64{{7}, {1, 8}, {2, 4, 9}, {3, 5, 6, 10}}, diag},
+		{"LOWER_DIAG_COL", LOWER_DIAG_COL, [][]float64{{7, 1, 2, 3}, {8, 4, 5}, {9, 6}, {10}}, diag},
+		//only the order of the numbers matters and not how they are split into rows
+		{"UPPER_ROW in other rows", UPPER_ROW, [][]float64{{1, 2}, {3, 4}, {5, 6}}, sym},
+		{"LOWER_DIAG_ROW in one row", LOWER_DIAG_ROW, [][]float64{{7, 1, 8, 2, 4, 9, 3, 5, 6, 10}}, diag},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := NewExplicitDistances(test.weights, test.format, len(test.expected))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range test.expected {
+				for j := range test.expected[i] {
+					if dist := d.Dist(i, j); dist != test.expected[i][j] {
+						t.Errorf("distance from %d to %d is %g, expected %g", i, j, dist, test.expected[i][j])
+					}
+				}
+			}
+			full, err := ExpandEdgeWeights(test.weights, test.format, len(test.expected))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(full, test.expected) {
+				t.Errorf("ExpandEdgeWeights returns %v, expected %v", full, test.expected)
+			}
+			if symmetric := IsSymmetric(d); symmetric != (test.format != "" && test.format != FULL_MATRIX) {
+				t.Errorf("IsSymmetric returns %v", symmetric)
+			}
+		})
+	}
+}
+
+//the triangular indexing of larger matrices, where the offsets of the rows differ more than for 4 nodes
+func TestExplicitDistancesIndex(t *testing.T) {
+	const n = 7
+	full := make([][]float64, n)
+	for i := range full {
+		full[i] = make([]float64, n)
+		for j := range full[i] {
+			if i != j {
+				full[i][j] = float64((i+1)*(j+1) + i + j)
+			}
+		}
+	}
+	var upper, lower, upperDiag, lowerDiag []float64
+	for i := 0; i < n; i++ {
+		upper = append(upper, full[i][i+1:]...)
+		lower = append(lower, full[i][:i]...)
+		upperDiag = append(upperDiag, full[i][i:]...)
+		lowerDiag = append(lowerDiag, full[i][:i+1]...)
+	}
+	for _, test := range []struct {
+		format  string
+		weights []float64
+	}{{UPPER_ROW, upper}, {LOWER_ROW, lower}, {UPPER_DIAG_ROW, upperDiag}, {LOWER_DIAG_ROW, lowerDiag},
+		{LOWER_COL, upper}, {UPPER_COL, lower}, {LOWER_DIAG_COL, upperDiag}, {UPPER_DIAG_COL, lowerDiag}} {
+		t.Run(test.format, func(t *testing.T) {
+			d, err := NewExplicitDistances([][]float64{test.weights}, test.format, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m := ToMatrix(d); !reflect.DeepEqual(m, full) {
+				t.Errorf("distances %v, expected %v", m, full)
+			}
+		})
+	}
+}
+
+func TestExplicitDistancesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		weights [][]float64
+	}{
+		{"FULL_MATRIX too short", FULL_MATRIX, [][]float64{{0, 1, 2}, {1, 0, 3}}},
+		{"UPPER_ROW with diagonal", UPPER_ROW, [][]float64{{0, 1, 2}, {0, 3}, {0}}},
+		{"UPPER_DIAG_ROW without diagonal", UPPER_DIAG_ROW, [][]float64{{1, 2}, {3}}},
+		{"unknown", "UPPER_MATRIX", [][]float64{{1, 2}, {3}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewExplicitDistances(test.weights, test.format, 3); err == nil {
+				t.Errorf("no error for %v in %s", test.weights, test.format)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package op
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EncodeJson writes v indented by tabs with its numeric arrays on one line. It streams the output of
+// json.MarshalIndent(v, "", "\t") after the regex based line break sanitizing of the older versions byte for byte,
+// without building the indented document in memory and searching it repeatedly:
+//   - arrays of two or more numbers are written without the line breaks between their elements
+//   - the line breaks around such an array are only left out, if none of its numbers has an exponent
+//   - arrays of a single number are written over three lines like json.MarshalIndent does
+// Strings are written as they are, while the regexes also removed the spaces in strings like "1, 2".
+// It supports the kinds of values an Instance consists of: structs, pointers, slices, strings, bools and numbers.
+func EncodeJson(w io.Writer, v interface{}) error {
+	e := &jsonEncoder{w: bufio.NewWriterSize(w, 1<<16)}
+	e.value(reflect.ValueOf(v), 0)
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+type jsonEncoder struct {
+	w      *bufio.Writer
+	buf    []byte
+	err    error
+	fields map[reflect.Type][]jsonField
+}
+
+type jsonField struct {
+	index     int
+	key       string
+	omitEmpty bool
+}
+
+const tabs = "\t\t\t\t\t\t\t\t\t\t\t\t\t\t\t\t"
+
+func (e *jsonEncoder) newline(depth int) {
+	e.w.WriteByte('\n')
+	for ; depth > len(tabs); depth -= len(tabs) {
+		e.w.WriteString(tabs)
+	}
+	e.w.WriteString(tabs[:depth])
+}
+
+func (e *jsonEncoder) value(v reflect.Value, depth int) {
+	if e.err != nil {
+		return
+	}
+	if !v.IsValid() {
+		e.w.WriteString("null")
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.w.WriteString("null")
+			return
+		}
+		e.value(v.Elem(), depth)
+	case reflect.Struct:
+		e.object(v, depth)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.w.WriteString("null")
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.err = fmt.Errorf("EncodeJson doesn't support the type %s", v.Type())
+			return
+		}
+		if isNumberKind(v.Type().Elem().Kind()) {
+			e.numbers(v, depth)
+			return
+		}
+		e.array(v, depth)
+	case reflect.String:
+		e.str(v.String())
+	case reflect.Bool:
+		e.w.WriteString(strconv.FormatBool(v.Bool()))
+	default:
+		if !isNumberKind(v.Kind()) {
+			e.err = fmt.Errorf("EncodeJson doesn't support the type %s", v.Type())
+			return
+		}
+		e.buf, _ = e.number(e.buf[:0], v)
+		e.w.Write(e.buf)
+	}
+}
+
+func (e *jsonEncoder) object(v reflect.Value, depth int) {
+	fields := e.structFields(v.Type())
+	if e.err != nil {
+		return
+	}
+	first := true
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if first {
+			e.w.WriteByte('{')
+		} else {
+			e.w.WriteByte(',')
+		}
+		first = false
+		e.newline(depth + 1)
+		e.str(f.key)
+		e.w.WriteString(": ")
+		e.value(fv, depth+1)
+	}
+	if first {
+		e.w.WriteString("{}")
+		return
+	}
+	e.newline(depth)
+	e.w.WriteByte('}')
+}
+
+func (e *jsonEncoder) array(v reflect.Value, depth int) {
+	if v.Len() == 0 {
+		e.w.WriteString("[]")
+		return
+	}
+	e.w.WriteByte('[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.newline(depth + 1)
+		e.value(v.Index(i), depth+1)
+	}
+	e.newline(depth)
+	e.w.WriteByte(']')
+}
+
+//the numbers on one line. The regexes only matched numbers without an exponent, which json writes for very small and
+//very large values, so the line breaks around arrays with such numbers remained
+func (e *jsonEncoder) numbers(v reflect.Value, depth int) {
+	n := v.Len()
+	if n == 0 {
+		e.w.WriteString("[]")
+		return
+	}
+	e.buf = e.buf[:0]
+	firstPlain, plain := false, n > 1
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		var p bool
+		e.buf, p = e.number(e.buf, v.Index(i))
+		if e.err != nil {
+			return
+		}
+		if i == 0 {
+			firstPlain = p
+		}
+		plain = plain && p
+	}
+	e.w.WriteByte('[')
+	if n == 1 || !firstPlain {
+		e.newline(depth + 1)
+	}
+	e.w.Write(e.buf)
+	if !plain {
+		e.newline(depth)
+	}
+	e.w.WriteByte(']')
+}
+
+//append the number like json does and report, if it's written without an exponent
+func (e *jsonEncoder) number(b []byte, v reflect.Value) ([]byte, bool) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		bits := 64
+		if v.Kind() == reflect.Float32 {
+			bits = 32
+		}
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			e.err = &json.UnsupportedValueError{Value: v, Str: strconv.FormatFloat(f, 'g', -1, bits)}
+			return b, false
+		}
+		format := byte('f')
+		if abs := math.Abs(f); abs != 0 && (bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21)) {
+			format = 'e'
+		}
+		b = strconv.AppendFloat(b, f, format, -1, bits)
+		if format == 'e' {
+			//json writes e-09 as e-9
+			if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+				b[n-2] = b[n-1]
+				b = b[:n-1]
+			}
+			return b, false
+		}
+		return b, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(b, v.Int(), 10), true
+	default:
+		return strconv.AppendUint(b, v.Uint(), 10), true
+	}
+}
+
+func (e *jsonEncoder) str(s string) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			//json escapes these, including the html characters
+			quoted, _ := json.Marshal(s)
+			e.w.Write(quoted)
+			return
+		}
+	}
+	e.w.WriteByte('"')
+	e.w.WriteString(s)
+	e.w.WriteByte('"')
+}
+
+//the exported fields of the struct with their keys from the json tags
+func (e *jsonEncoder) structFields(t reflect.Type) []jsonField {
+	if fields, ok := e.fields[t]; ok {
+		return fields
+	}
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous {
+			e.err = fmt.Errorf("EncodeJson doesn't support the embedded field %s of %s", sf.Name, t)
+			return nil
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		f := jsonField{index: i, key: sf.Name}
+		name, opts := tag, ""
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			name, opts = tag[:comma], tag[comma:]
+		}
+		if name != "" {
+			f.key = name
+		}
+		f.omitEmpty = strings.Contains(opts, ",omitempty")
+		fields = append(fields, f)
+	}
+	if e.fields == nil {
+		e.fields = make(map[reflect.Type][]jsonField)
+	}
+	e.fields[t] = fields
+	return fields
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return isNumberKind(v.Kind()) && v.IsZero()
+}
@@ -0,0 +1,123 @@
+package op
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"regexp"
+	"testing"
+)
+
+//the sanitizing of the json before EncodeJson
+func sanitizeJsonArrayLineBreaks(json string) string {
+	res := json
+	var numbers = regexp.MustCompile(`\s*([-]?[0-9]+(\.[0-9]+)?),\s+([-]?[0-9]+(\.[0-9]+)?)(,)?`)
+	var brackets = regexp.MustCompile(`\[(([-]?[0-9]+(\.[0-9]+)?,)+[-]?[0-9]+(\.[0-9]+)?)\s+\](,?)(\s+)`)
+	for numbers.MatchString(res) {
+		res = numbers.ReplaceAllString(res, "$1,$3$5")
+	}
+	for brackets.MatchString(res) {
+		res = brackets.ReplaceAllString(res, "[$1]$5$6")
+	}
+	return res
+}
+
+func sanitizedJson(t testing.TB, v interface{}) string {
+	jsonV, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sanitizeJsonArrayLineBreaks(string(jsonV))
+}
+
+func encodedJson(t testing.TB, v interface{}) string {
+	var buf bytes.Buffer
+	err := EncodeJson(&buf, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+//an explicit instance with real-valued distances like converter-tsiligirides used to write and a solution
+func explicitInstance(t testing.TB, rnd *rand.Rand, n int) *Instance {
+	inst := randomInstance(rnd, n)
+	d, err := NewCoordDistances(inst.NodeCoordinates, EXACT_2D)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst.EdgeWeightType, inst.EdgeWeightFormat, inst.EdgeWeights = EXPLICIT, FULL_MATRIX, ToMatrix(d)
+	inst.Solutions = []*Solution{{Route: rnd.Perm(inst.Dimension), Comment: "benchmark"}}
+	return inst
+}
+
+func TestEncodeJsonInstance(t *testing.T) {
+	inst := explicitInstance(t, rand.New(rand.NewSource(1)), 50)
+	if encoded, sanitized := encodedJson(t, inst), sanitizedJson(t, inst); encoded != sanitized {
+		t.Errorf("EncodeJson writes other bytes than the regex sanitizing:\n%s\nexpected:\n%s", encoded, sanitized)
+	}
+}
+
+func TestEncodeJson(t *testing.T) {
+	type numbers struct {
+		Ints   []int     `json:"ints"`
+		Floats []float64 `json:"floats,omitempty"`
+	}
+	type named struct {
+		Name string `json:"name"`
+	}
+	tests := []struct {
+		name     string
+		v        interface{}
+		expected string
+		regex    bool //the regex sanitizing writes the same
+	}{
+		{"numbers", numbers{Ints: []int{1, -2, 3}, Floats: []float64{0.5, 2}},
+			"{\n\t\"ints\": [1,-2,3],\n\t\"floats\": [0.5,2]\n}", true},
+		{"single number", numbers{Ints: []int{1}},
+			"{\n\t\"ints\": [\n\t\t1\n\t]\n}", true},
+		{"empty", numbers{Ints: []int{}},
+			"{\n\t\"ints\": []\n}", true},
+		{"matrix", [][]float64{{1, 2}, {3, 4}},
+			"[\n\t[1,2],\n\t[3,4]\n]", true},
+		//the regexes only matched numbers without an exponent, so the line breaks around the array remain
+		{"exponent", numbers{Ints: []int{1}, Floats: []float64{1e-7, 2, 3e21}},
+			"{\n\t\"ints\": [\n\t\t1\n\t],\n\t\"floats\": [\n\t\t1e-7,2,3e+21\n\t]\n}", true},
+		{"exponent last", numbers{Ints: []int{1, 2}, Floats: []float64{2, 1e-7}},
+			"{\n\t\"ints\": [1,2],\n\t\"floats\": [2,1e-7\n\t]\n}", true},
+		{"escaped string", named{Name: "a<b & \"c\""},
+			"{\n\t\"name\": \"a\\u003cb \\u0026 \\\"c\\\"\"\n}", true},
+		//the regexes also removed the spaces in strings, EncodeJson keeps them
+		{"string with numbers", named{Name: "1, 2"},
+			"{\n\t\"name\": \"1, 2\"\n}", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded := encodedJson(t, test.v)
+			if encoded != test.expected {
+				t.Errorf("EncodeJson writes\n%s\nexpected:\n%s", encoded, test.expected)
+			}
+			if sanitized := sanitizedJson(t, test.v); (sanitized == encoded) != test.regex {
+				t.Errorf("the regex sanitizing writes\n%s\nand EncodeJson\n%s", sanitized, encoded)
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeJson(b *testing.B) {
+	//the regex sanitizing takes seconds already for a few hundred nodes
+	inst := explicitInstance(b, rand.New(rand.NewSource(1)), 300)
+	b.Run("Regex", func(b *testing.B) {
+		b.ReportAllocs()
+		for k := 0; k < b.N; k++ {
+			sanitizedJson(b, inst)
+		}
+	})
+	b.Run("Stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for k := 0; k < b.N; k++ {
+			EncodeJson(ioutil.Discard, inst)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package op
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// FormatVersion is the version of the json format written by SaveInstance. Files of older versions are migrated to
+// it when they are loaded.
+const FormatVersion = 2
+
+// migrations[v] migrates an instance of the format version v to the version v+1
+var migrations = []func(inst *Instance){
+	migrateV0,
+	migrateV1,
+}
+
+// LoadInstance reads an instance in the json format. Gzip compressed input is detected and decompressed, instances
+// of older format versions are migrated to FormatVersion.
+func LoadInstance(r io.Reader) (*Instance, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+	inst := &Instance{}
+	err := json.NewDecoder(r).Decode(inst)
+	if err != nil {
+		return nil, err
+	}
+	if inst.Version > FormatVersion || inst.Version < 0 {
+		return nil, fmt.Errorf("unsupported format version %d, the newest known version is %d", inst.Version, FormatVersion)
+	}
+	for v := inst.Version; v < FormatVersion; v++ {
+		migrations[v](inst)
+	}
+	inst.Version = FormatVersion
+	return inst, nil
+}
+
+// SaveInstance writes the instance with its solutions in the json format of FormatVersion with EncodeJson
+func SaveInstance(w io.Writer, inst *Instance) error {
+	current := *inst
+	current.Version = FormatVersion
+	return EncodeJson(w, &current)
+}
+
+// LoadInstanceFile reads the instance from the file with LoadInstance
+func LoadInstanceFile(fileName string) (*Instance, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadInstance(file)
+}
+
+// SaveInstanceFile writes the instance to the file with SaveInstance. Files ending with .gz are gzip compressed.
+func SaveInstanceFile(fileName string, inst *Instance) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = file
+	var gz *gzip.Writer
+	if strings.HasSuffix(fileName, ".gz") {
+		gz = gzip.NewWriter(file)
+		w = gz
+	}
+	err = SaveInstance(w, inst)
+	if gz != nil {
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+//files without a version were written before it was introduced. converter-tsiligirides wrote the real-valued distances
+//of its integer coordinates as a full matrix without a format and declared them as EUC_2D, which rounds them
+func migrateV0(inst *Instance) {
+	n := len(inst.NodeCoordinates)
+	if len(inst.EdgeWeights) != n || n == 0 || inst.EdgeWeightFormat != "" || inst.EdgeWeightType != EUC_2D {
+		return
+	}
+	//the distances are the exact ones of the coordinates unless the file was edited
+	exact := true
+	for i, row := range inst.EdgeWeights {
+		if len(row) != n || len(inst.NodeCoordinates[i]) < 2 {
+			return
+		}
+		for j := 0; j < n && exact; j++ {
+			if len(inst.NodeCoordinates[j]) < 2 || !NearlyEqual(row[j], euclid(inst.NodeCoordinates[i], inst.NodeCoordinates[j], 2)) {
+				exact = false
+			}
+		}
+	}
+	if exact {
+		inst.EdgeWeightType = EXACT_2D
+		inst.EdgeWeights = nil
+		return
+	}
+	inst.EdgeWeightType = EXPLICIT
+	inst.EdgeWeightFormat = FULL_MATRIX
+}
+
+//the version 1 kept a single solution, which was overwritten by every run
+func migrateV1(inst *Instance) {
+	if inst.Solution != nil {
+		inst.Solutions = append(inst.Solutions, inst.Solution)
+		inst.Solution = nil
+	}
+}
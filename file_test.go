@@ -0,0 +1,111 @@
+package op
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadInstanceVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected Instance
+	}{
+		//converter-tsiligirides wrote the exact distances of the coordinates as EUC_2D without a version
+		{"version 0 exact distances",
+			`{"name": "v0", "edge_weight_type": "EUC_2D", "node_coordinates": [[0,0],[1,1],[3,4]],
+			"edge_weights": [[0,1.4142135623730951,5],[1.4142135623730951,0,3.605551275463989],[5,3.605551275463989,0]]}`,
+			Instance{Name: "v0", EdgeWeightType: EXACT_2D, NodeCoordinates: [][]float64{{0, 0}, {1, 1}, {3, 4}}}},
+		{"version 0 edited distances",
+			`{"name": "v0", "edge_weight_type": "EUC_2D", "node_coordinates": [[0,0],[1,1],[3,4]],
+			"edge_weights": [[0,2,5],[2,0,4],[5,4,0]]}`,
+			Instance{Name: "v0", EdgeWeightType: EXPLICIT, EdgeWeightFormat: FULL_MATRIX,
+				NodeCoordinates: [][]float64{{0, 0}, {1, 1}, {3, 4}}, EdgeWeights: [][]float64{{0, 2, 5}, {2, 0, 4}, {5, 4, 0}}}},
+		{"version 0 coordinates",
+			`{"name": "v0", "edge_weight_type": "EUC_2D", "node_coordinates": [[0,0],[1,1]]}`,
+			Instance{Name: "v0", EdgeWeightType: EUC_2D, NodeCoordinates: [][]float64{{0, 0}, {1, 1}}}},
+		{"version 1 solution",
+			`{"version": 1, "name": "v1", "solutions": null, "Solution": {"obj": 3, "route": [0,1]}}`,
+			Instance{Name: "v1", Solutions: []*Solution{{Obj: 3, Route: []int{0, 1}}}}},
+		{"version 1 without solution",
+			`{"version": 1, "name": "v1"}`,
+			Instance{Name: "v1"}},
+		{"version 2",
+			`{"version": 2, "name": "v2", "solutions": [{"obj": 3, "route": [0,1]}]}`,
+			Instance{Name: "v2", Solutions: []*Solution{{Obj: 3, Route: []int{0, 1}}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inst, err := LoadInstance(strings.NewReader(test.json))
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.expected.Version = FormatVersion
+			if !reflect.DeepEqual(*inst, test.expected) {
+				t.Errorf("loaded %+v, expected %+v", *inst, test.expected)
+			}
+
+			//the migrated instance is saved in the current version and loaded again unchanged, also compressed
+			var buf bytes.Buffer
+			if err = SaveInstance(&buf, inst); err != nil {
+				t.Fatal(err)
+			}
+			saved, err := LoadInstance(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(saved, inst) {
+				t.Errorf("the saved instance is loaded as %+v, expected %+v", *saved, *inst)
+			}
+			fileName := filepath.Join(t.TempDir(), "instance.json.gz")
+			if err = SaveInstanceFile(fileName, inst); err != nil {
+				t.Fatal(err)
+			}
+			saved, err = LoadInstanceFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(saved, inst) {
+				t.Errorf("the compressed instance is loaded as %+v, expected %+v", *saved, *inst)
+			}
+		})
+	}
+}
+
+func TestLoadInstanceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"newer version", `{"version": 3}`},
+		{"negative version", `{"version": -1}`},
+		{"no json", `EUC_2D`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := LoadInstance(strings.NewReader(test.json)); err == nil {
+				t.Errorf("no error for %s", test.json)
+			}
+		})
+	}
+}
+
+//the instance migrated to EXACT_2D calculates the real-valued distances, which the file held
+func TestMigrateV0Distances(t *testing.T) {
+	inst, err := LoadInstance(strings.NewReader(`{"edge_weight_type": "EUC_2D", "node_coordinates": [[0,0],[1,1]], "edge_weights": [[0,1.4142135623730951],[1.4142135623730951,0]]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst.Dimension = 2
+	d, err := inst.GetEdgeDist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d[0][1] != math.Sqrt2 {
+		t.Errorf("distance %g, expected %g", d[0][1], math.Sqrt2)
+	}
+}
@@ -1,8 +1,12 @@
 package op
 
 import (
+	"flag"
 	"fmt"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"io/ioutil"
 	"strconv"
+	"strings"
 )
 
 type ArrayStringFlags []string
@@ -44,4 +48,92 @@ func (i *ArrayFloatFlags) Set(value string) error {
 	}
 	*i = append(*i, val)
 	return nil
-}
\ No newline at end of file
+}
+
+// SolverFlags are the command line flags for the limits of the MIP backend and its parameter file
+type SolverFlags struct {
+	paramsFile *string
+	defaults   []mip.Param
+}
+
+//the flags of the limits with the parameters they set
+var limitFlags = []struct{ name, param, usage string }{
+	{"timeLimit", mip.DBL_PAR_TIMELIMIT, "Time limit in seconds"},
+	{"gap", mip.DBL_PAR_MIPGAP, "Relative MIP gap, at which the solve stops"},
+	{"threads", mip.INT_PAR_THREADS, "Number of threads of the backend"},
+	{"seed", mip.INT_PAR_SEED, "Random seed of the backend"},
+	{"nodeLimit", mip.DBL_PAR_NODELIMIT, "Limit of the explored branch-and-bound nodes"},
+}
+
+// AddSolverFlags defines the flags of the limits and the parameter file on the command line. The defaults are the
+// parameters of the command, that apply unless the file or the flags set them
+func AddSolverFlags(defaults ...mip.Param) *SolverFlags {
+	f := &SolverFlags{defaults: defaults}
+	for _, limit := range limitFlags {
+		if value, ok := f.defaultValue(limit.param); ok {
+			flag.String(limit.name, value, limit.usage)
+		} else {
+			flag.String(limit.name, "", limit.usage+". By default the one of the backend")
+		}
+	}
+	f.paramsFile = flag.String("params", "", "Path to a file of key=value lines with parameters of the selected backend, e.g. MIPGap=0.01")
+	return f
+}
+
+func (f *SolverFlags) defaultValue(name string) (string, bool) {
+	for _, param := range f.defaults {
+		if param.Name == name {
+			return param.Value, true
+		}
+	}
+	return "", false
+}
+
+// Params reads the parameter file and appends the limits set on the command line, so they replace the values of the
+// file, and the defaults of the command neither of them sets. They are checked against the parameters of the backend
+// mip selects, so mip.DefaultBackend has to be set before. The text returned to be recorded with the solution is the
+// content of the file followed by a key=value line for every appended parameter
+func (f *SolverFlags) Params() (mip.Params, string, error) {
+	var (
+		params  mip.Params
+		text    string
+		backend = mip.SelectedBackend()
+	)
+	if *f.paramsFile != "" {
+		content, err := ioutil.ReadFile(*f.paramsFile)
+		if err != nil {
+			return nil, "", err
+		}
+		text = string(content)
+		params, err = mip.ParseParams(backend, text)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %s", *f.paramsFile, err.Error())
+		}
+	}
+	set := make(map[string]bool)
+	flag.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+	})
+	var appended mip.Params
+	for _, limit := range limitFlags {
+		if set[limit.name] {
+			appended = append(appended, mip.Param{Name: limit.param, Value: flag.Lookup(limit.name).Value.String()})
+		}
+	}
+	for _, param := range f.defaults {
+		if !params.Has(param.Name) && !appended.Has(param.Name) {
+			appended = append(appended, param)
+		}
+	}
+	if len(appended) > 0 && text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	for _, param := range appended {
+		err := params.Add(backend, param.Name, param.Value)
+		if err != nil {
+			return nil, "", err
+		}
+		text += fmt.Sprintf("%s=%s\n", param.Name, param.Value)
+	}
+	return params, text, nil
+}
@@ -1,46 +1,33 @@
 package main
 
 import (
-	"fmt"
-	"io/ioutil"
+	"git.solver4all.com/azaryc2s/op"
 	"log"
 	"os"
-	"regexp"
 )
 
+/* Rewrites an instance in the current format version with the numeric arrays on single lines. The file is
+   overwritten unless a second file is given, which is gzip compressed if its name ends with .gz */
+
 func main() {
 	if len(os.Args) < 2 {
 		log.Printf("No arguments passed!")
 		return
 	}
-	
-	fileContent, err := ioutil.ReadFile(os.Args[1])
 
+	inst, err := op.LoadInstanceFile(os.Args[1])
 	if err != nil {
 		log.Printf("At %s: %s\n", os.Args[1], err.Error())
 		return
 	}
 
-	writeBackFile(string(fileContent), os.Args[1])
-}
-func writeBackFile(fileContent, fileName string) {
-	fileContent = sanitizeJsonArrayLineBreaks(fileContent)
-	err := ioutil.WriteFile(fileName, []byte(fileContent), 0644)
+	fileName := os.Args[1]
+	if len(os.Args) > 2 {
+		fileName = os.Args[2]
+	}
+	err = op.SaveInstanceFile(fileName, inst)
 	if err != nil {
 		log.Printf("At %s: %s\n", fileName, err.Error())
 		return
 	}
 }
-
-func sanitizeJsonArrayLineBreaks(json string) string {
-	res := fmt.Sprintf("%s",json)
-	var numbers = regexp.MustCompile(`\s*([0-9]+),\s+([0-9]+)(,)?`)
-	var brackets = regexp.MustCompile(`\[(([0-9]+,)+[0-9]+)\s+\](,?)(\s+)`)
-	for ; numbers.MatchString(res); {
-		res = numbers.ReplaceAllString(res,"$1,$2$3")
-	}
-	for ; brackets.MatchString(res); {
-		res = brackets.ReplaceAllString(res,"[$1]$3$4")
-	}
-	return res
-}
@@ -1,16 +1,15 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	_ "git.solver4all.com/azaryc2s/op/mip/bnc"
+	_ "git.solver4all.com/azaryc2s/op/mip/grb"
 	"git.solver4all.com/azaryc2s/op/tsp"
-	"io/ioutil"
 	"log"
-	"math"
 	"math/rand"
-	"regexp"
 	"time"
 )
 
@@ -21,66 +20,80 @@ var tmaxA op.ArrayFloatFlags
 func main() {
 	flag.Var(&prices, "prices", "List of price-generation strategies")
 	flag.Var(&nodes, "n", "List of number of nodes")
-	flag.Var(&tmaxA, "tmax", "List of maximum lengths of the route as value a: 0 < a < 1 which is a portion of the tsp-length")
+	flag.Var(&tmaxA, "tmax", "List of maximum lengths of the route as value a: 0 < a < 1 which is a portion of the tsp-length plus all service times")
 	name := flag.String("name", "zarychta", "Name for the instance")
 	//comment := flag.String("comment", "Zarychta generated OP-Instance", "Comment for the instances")
 	//dimension := flag.Int("n", 0, "List of number of nodes")
 	count := flag.Int("count", 10, "Number of instances per combination")
 	xTo := flag.Int("x", 10000, "Max value on the x-axis")
 	yTo := flag.Int("y", 10000, "Max value on the y-axis")
-	w := flag.String("w", "EUC_2D", "EDGE_WEIGHT_TYPE - how the distance between nodes is calculated.")
+	serviceTo := flag.Int("service", 0, "Max service time at a node. By default the nodes have no service times")
+	w := flag.String("w", op.EUC_2D, "EDGE_WEIGHT_TYPE - how the distance between nodes is calculated. EXACT_2D for real-valued distances")
 	//priceTo := flag.Int("price", 0, "Max price for a node")
 	calcTSP := flag.Bool("tsp", true, "Whether to calculate the tsp-route or not (needs gurobi configured and could take a while for bigger instances)")
 	//tmaxA := flag.Float64("tmax", 0.5, "Maximum length of the route as value a: 0 < a < 1 which is a portion of the tsp-length")
 
 	flag.Parse()
+	var env mip.Env
+	if *calcTSP {
+		var err error
+		env, err = mip.LoadEnv("tsp_gurobi.log")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer env.Free()
+	}
 
 	for l := 0; l < *count; l++ {
-		var tmax, tspLength int
+		var tmax, tspLength float64
 		rand.Seed(time.Now().UnixNano())
 		for i := 0; i < len(nodes); i++ {
 			n := nodes[i]
 			coordinatesArray := make([][]float64, n)
-			edgeWeights := make([][]int, n)
 			for node := 0; node < n; node++ {
 				x := rand.Intn(*xTo)
 				y := rand.Intn(*yTo)
 				coordinatesArray[node] = []float64{float64(x), float64(y)}
-				edgeWeights[node] = make([]int, n)
-				for node2 := 0; node2 < node; node2++ {
-					xDist := coordinatesArray[node][0] - coordinatesArray[node2][0]
-					yDist := coordinatesArray[node][1] - coordinatesArray[node2][1]
-					var distance int
-					if *w == "EUC_2D" {
-						distance = int(math.Sqrt(math.Pow(xDist, 2)+math.Pow(yDist, 2)) + 0.5)
-					} else if *w == "CEIL_2D" {
-						distance = int(math.Ceil(math.Sqrt(math.Pow(xDist, 2) + math.Pow(yDist, 2))))
-					}
-					edgeWeights[node][node2] = distance
-					edgeWeights[node2][node] = distance
-				}
+			}
+			edgeWeights, err := op.CalcEdgeDist(coordinatesArray, *w)
+			if err != nil {
+				log.Fatal(err)
 			}
 			if *calcTSP {
-				_, tspLength, _ = tsp.SolveTSP(edgeWeights)
+				_, tspLength, _ = tsp.SolveTSP(env, *name, edgeWeights)
 			}
 			depots := []int{0}
+			var serviceArray []float64
+			serviceSum := 0.0
+			if *serviceTo > 0 {
+				serviceArray = make([]float64, n)
+				for node := 0; node < n; node++ {
+					serviceArray[node] = float64(1 + rand.Intn(*serviceTo))
+				}
+				for d := 0; d < len(depots); d++ {
+					serviceArray[depots[d]] = 0
+				}
+				for node := 0; node < n; node++ {
+					serviceSum += serviceArray[node]
+				}
+			}
 			for j := 0; j < len(tmaxA); j++ {
 				a := tmaxA[j]
-				tmax = int((float64(tspLength) * a) + 0.5)
+				tmax = float64(int(((tspLength + serviceSum) * a) + 0.5))
 				for k := 0; k < len(prices); k++ {
 					p := prices[k]
-					pricesArray := make([]int, n)
+					pricesArray := make([]float64, n)
 					if p == "ONE" {
 						for pr := 0; pr < n; pr++ {
 							pricesArray[pr] = 1
 						}
 					} else if p == "RNG" {
 						for pr := 0; pr < n; pr++ {
-							pricesArray[pr] = 1 + rand.Intn(100)
+							pricesArray[pr] = float64(1 + rand.Intn(100))
 						}
 					} else if p == "RNG-DIST" {
 						for pr := 0; pr < n; pr++ {
-							pricesArray[pr] = 1 + rand.Intn(100) + int(((float64(edgeWeights[0][pr])/float64(tspLength))*100.0)+0.5)
+							pricesArray[pr] = float64(1 + rand.Intn(100) + int(((edgeWeights[0][pr]/tspLength)*100.0)+0.5))
 						}
 					}
 					for d := 0; d < len(depots); d++ {
@@ -89,15 +102,9 @@ func main() {
 
 					comment := fmt.Sprintf("%s instance Nr. %d with %d nodes, %.2f a-value and prices generated as %s", *name, l, n, a, p)
 					instName := fmt.Sprintf("%s_%d_%.2f_%s_%d", *name, n, a, p, l)
-					opInstance := op.Instance{Name: instName, Comment: comment, Type: "OP", Dimension: n, TMax: tmax, Prices: pricesArray, NodeCoordinates: coordinatesArray, Depots: depots, DisplayDataType: "COORD_DISPLAY", EdgeWeightType: *w, TSPLength: tspLength}
+					opInstance := op.Instance{Name: instName, Comment: comment, Type: "OP", Dimension: n, TMax: tmax, Prices: pricesArray, NodeCoordinates: coordinatesArray, Depots: depots, DisplayDataType: "COORD_DISPLAY", EdgeWeightType: *w, TSPLength: tspLength, ServiceTimes: serviceArray}
 
-					jsonInst, err := json.MarshalIndent(opInstance, "", "\t")
-					if err != nil {
-						log.Fatal(err)
-					}
-
-					jsonInst = []byte(sanitizeJsonArrayLineBreaks(string(jsonInst)))
-					err = ioutil.WriteFile(fmt.Sprintf("%s.json", instName), jsonInst, 0644)
+					err = op.SaveInstanceFile(fmt.Sprintf("%s.json", instName), &opInstance)
 					if err != nil {
 						log.Fatal(err)
 					}
@@ -106,16 +113,3 @@ func main() {
 		}
 	}
 }
-
-func sanitizeJsonArrayLineBreaks(json string) string {
-	res := fmt.Sprintf("%s", json)
-	var numbers = regexp.MustCompile(`\s*([-]?[0-9]+(\.[0-9]+)?),\s+([-]?[0-9]+(\.[0-9]+)?)(,)?`)
-	var brackets = regexp.MustCompile(`\[(([-]?[0-9]+(\.[0-9]+)?,)+[-]?[0-9]+(\.[0-9]+)?)\s+\](,?)(\s+)`)
-	for numbers.MatchString(res) {
-		res = numbers.ReplaceAllString(res, "$1,$3$5")
-	}
-	for brackets.MatchString(res) {
-		res = brackets.ReplaceAllString(res, "[$1]$5$6")
-	}
-	return res
-}
@@ -4,14 +4,15 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
-	"git.solver4all.com/azaryc2s/gorobi/gurobi"
 	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	_ "git.solver4all.com/azaryc2s/op/mip/bnc"
+	_ "git.solver4all.com/azaryc2s/op/mip/grb"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/host"
 	"github.com/shirou/gopsutil/mem"
-	"io/ioutil"
 	"log"
 	"os"
 	"strings"
@@ -21,8 +22,10 @@ import (
 var (
 	N        int
 	xijNum   int
-	edgeDist [][]int
+	edgeDist [][]float64
 	sol      op.Solution
+	pInst    op.Instance
+	inputF   string
 )
 
 /* Define structure to pass data to the callback function */
@@ -32,48 +35,78 @@ type SubData struct {
 }
 
 func main() {
-	var (
-		err   error
-		pInst op.Instance
-	)
+	//the limits of the gurobi.env file the command used before
+	solverFlags := op.AddSolverFlags(mip.Param{Name: mip.DBL_PAR_TIMELIMIT, Value: "7200"}, mip.Param{Name: mip.INT_PAR_THREADS, Value: "16"},
+		mip.Param{Name: mip.INT_PAR_LOGTOCONSOLE, Value: "0"})
+	flag.Parse()
+	inputF = flag.Arg(0)
+	params, paramsText, err := solverFlags.Params()
+	if err != nil {
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
 	hostStat, _ := host.Info()
 	cpuStat, _ := cpu.Info()
 	vmStat, _ := mem.VirtualMemory()
-	sol = op.Solution{Comment: "", System: op.SysInfo{hostStat.Platform, cpuStat[0].ModelName, fmt.Sprintf("%d GB", (vmStat.Total / 1024 / 1024 / 1024))}}
-
-	instStr, err := ioutil.ReadFile(os.Args[1])
+	sol = op.Solution{Comment: "", System: op.SysInfo{Platform: hostStat.Platform, CPU: cpuStat[0].ModelName, RAM: fmt.Sprintf("%d GB", (vmStat.Total / 1024 / 1024 / 1024))}}
+	sol.Params = paramsText
 
+	inst, err := op.LoadInstanceFile(inputF)
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
 		return
 	}
+	pInst = *inst
 
-	err = json.Unmarshal(instStr, &pInst)
-
+	if problems := pInst.Validate(); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("At %s: invalid instance - %s\n", inputF, problem)
+		}
+		return
+	}
+	edgeDist, err = pInst.GetEdgeDist()
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
+	if pInst.IsPath() {
+		log.Printf("At %s: the start and end depot differ, which lp-asym doesn't support\n", inputF)
+		return
+	}
+	if pInst.GetVehicles() > 1 {
+		log.Printf("At %s: the instance has %d vehicles, which lp-asym doesn't support\n", inputF, pInst.GetVehicles())
+		return
+	}
+	if pInst.HasClusters() {
+		log.Printf("At %s: the instance has clusters, which lp-asym doesn't support\n", inputF)
+		return
+	}
+	if pInst.HasTimeWindows() {
+		log.Printf("At %s: the instance has time windows, which lp-asym doesn't support\n", inputF)
 		return
 	}
-
-	pInst.Solution = &sol
-	edgeDist = op.CalcEdgeDist(pInst.NodeCoordinates, pInst.EdgeWeightType)
 
 	// Create environment
-	env, err := gurobi.LoadEnv("op-lp-asym.log")
+	env, err := mip.LoadEnv("op-lp-asym.log")
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
 		return
 	}
 	defer env.Free()
-	threads, _ := env.GetIntParam(gurobi.INT_PAR_THREADS)
+	if err = params.Apply(env); err != nil {
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
+	threads, _ := env.GetIntParam(mip.INT_PAR_THREADS)
 	sol.Comment = fmt.Sprintf("Using %d threads", threads)
+	sol.Config = op.SolverConfig{Command: "lp-asym", Backend: mip.SelectedBackend() + " " + env.Version()}
 
 	N = pInst.Dimension
 	xijNum = N * N //X_ij
 
 	/* Create an empty model */
 
-	model, err := env.NewModel("op", 0, nil, nil, nil, nil, nil)
+	model, err := env.NewModel("op")
 	if err != nil {
 		log.Println(err)
 		return
@@ -87,7 +120,7 @@ func main() {
 	for i := 0; i < N; i++ {
 		for j := 0; j < N; j++ {
 			name := fmt.Sprintf("x_%d_%d", i, j)
-			err = model.AddVar(nil, nil, float64(pInst.Prices[i]), 0.0, 1.0, gurobi.BINARY, name)
+			err = model.AddVar(pInst.Prices[i], 0.0, 1.0, mip.BINARY, name)
 			if err != nil {
 				log.Println(err)
 				return
@@ -96,9 +129,9 @@ func main() {
 	}
 
 	// Change objective sense to maximization
-	err = model.SetIntAttr(gurobi.INT_ATTR_MODELSENSE, gurobi.MAXIMIZE)
+	err = model.SetIntAttr(mip.INT_ATTR_MODELSENSE, mip.MAXIMIZE)
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
 		return
 	}
 
@@ -113,11 +146,11 @@ func main() {
 			val = append(val, 1.0)
 		}
 		nameo := fmt.Sprintf("deg2o_depot")
-		err = model.AddConstr(gurobi.Int32Slice(ind), val, gurobi.EQUAL, 1, nameo)
+		err = model.AddConstr(mip.Int32Slice(ind), val, mip.EQUAL, 1, nameo)
 
 		if err != nil {
 			log.Println("Error adding depot_out_deg")
-			log.Printf("At %s: %s\n", os.Args[1], err.Error())
+			log.Printf("At %s: %s\n", inputF, err.Error())
 			return
 		}
 
@@ -128,11 +161,11 @@ func main() {
 			val = append(val, 1.0)
 		}
 		namei := fmt.Sprintf("deg2i_depot")
-		err = model.AddConstr(gurobi.Int32Slice(ind), val, gurobi.EQUAL, 1, namei)
+		err = model.AddConstr(mip.Int32Slice(ind), val, mip.EQUAL, 1, namei)
 
 		if err != nil {
 			log.Println("Error adding depot_in_deg")
-			log.Printf("At %s: %s\n", os.Args[1], err.Error())
+			log.Printf("At %s: %s\n", inputF, err.Error())
 			return
 		}
 	}
@@ -140,7 +173,7 @@ func main() {
 	log.Println("Creating and setting constraints to forbid edge from node back to itself")
 	{
 		for i := 0; i < N; i++ {
-			err = model.SetDblAttrElem(gurobi.DBL_ATTR_UB, int32(i*N+i), 0)
+			err = model.SetDblAttrElem(mip.DBL_ATTR_UB, int32(i*N+i), 0)
 			if err != nil {
 				log.Println(err)
 				return
@@ -159,10 +192,10 @@ func main() {
 				ind = append(ind, int32(i*N+j))
 				val = append(val, 1.0)
 			}
-			err = model.AddConstr(ind, val, gurobi.LESS_EQUAL, 1.0, fmt.Sprintf("node_only1_%d", j))
+			err = model.AddConstr(ind, val, mip.LESS_EQUAL, 1.0, fmt.Sprintf("node_only1_%d", j))
 			if err != nil {
 				log.Printf("Error adding node_only1_%d\n", j)
-				log.Printf("At %s: %s\n", os.Args[1], err.Error())
+				log.Printf("At %s: %s\n", inputF, err.Error())
 				return
 			}
 		}
@@ -184,10 +217,10 @@ func main() {
 				ind = append(ind, int32(j*N+i))
 				val = append(val, -1.0)
 			}
-			err = model.AddConstr(ind, val, gurobi.EQUAL, 0.0, fmt.Sprintf("node_flow_%d", j))
+			err = model.AddConstr(ind, val, mip.EQUAL, 0.0, fmt.Sprintf("node_flow_%d", j))
 			if err != nil {
 				log.Println("Error adding node_flow_constraints")
-				log.Printf("At %s: %s\n", os.Args[1], err.Error())
+				log.Printf("At %s: %s\n", inputF, err.Error())
 				return
 			}
 		}
@@ -199,31 +232,32 @@ func main() {
 			ind []int32
 			val []float64
 		)
+		//a node is left on exactly one edge after its service
 		for i := 0; i < N; i++ {
 			for j := 0; j < N; j++ {
 				ind = append(ind, int32(i*N+j))
-				val = append(val, float64(edgeDist[i][j]))
+				val = append(val, edgeDist[i][j]+pInst.GetServiceTime(i))
 			}
 		}
-		err = model.AddConstr(ind, val, gurobi.LESS_EQUAL, float64(pInst.TMax), "travel_budget")
+		err = model.AddConstr(ind, val, mip.LESS_EQUAL, pInst.TMax, "travel_budget")
 		if err != nil {
 			log.Printf("Error adding constraint for travel budget")
-			log.Printf("At %s: %s\n", os.Args[1], err.Error())
+			log.Printf("At %s: %s\n", inputF, err.Error())
 			return
 		}
 	}
 
 	// Write model to a file with the same name as the input'
-	lpName := strings.ReplaceAll(os.Args[1], ".json", ".lp-asym")
+	lpName := strings.ReplaceAll(strings.TrimSuffix(inputF, ".gz"), ".json", ".lp-asym")
 	err = model.Write(lpName)
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
 		return
 	}
 
 	/* Set callback function */
 
-	err = model.SetCallbackFuncGo(subtourelimOP, SubData{N: int32(N)})
+	err = model.SetCallbackFunc(subtourelimOP, SubData{N: int32(N)})
 	if err != nil {
 		log.Println(err)
 		return
@@ -231,7 +265,7 @@ func main() {
 
 	/* Must set LazyConstraints parameter when using lazy constraints */
 
-	err = model.SetIntParam(gurobi.INT_PAR_LAZYCONSTRAINTS, 1)
+	err = model.SetIntParam(mip.INT_PAR_LAZYCONSTRAINTS, 1)
 	if err != nil {
 		log.Println(err)
 		return
@@ -242,54 +276,55 @@ func main() {
 	// Optimize model
 	err = model.Optimize()
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
 		return
 	}
 	sol.Time = time.Since(startTime).String()
-	log.Println("\n---OPTIMIZATION DONE---\n\t Generating and writing result now\n")
+	log.Print("\n---OPTIMIZATION DONE---\n\t Generating and writing result now\n")
 	defer writeSolution()
 
 	// Capture solution information
-	optimstatus, err := model.GetIntAttr(gurobi.INT_ATTR_STATUS)
+	optimstatus, err := model.GetIntAttr(mip.INT_ATTR_STATUS)
 	if err != nil {
 		sol.Comment += fmt.Sprintf("Couldn't retrieve optimization status: %s. ", err.Error())
-		log.Printf("At %s: %s\n", os.Args[1], sol.Comment)
+		log.Printf("At %s: %s\n", inputF, sol.Comment)
 		return
 	}
 
-	if optimstatus == gurobi.OPTIMAL {
+	if optimstatus == mip.OPTIMAL {
 		sol.Optimal = true
-	} else if optimstatus == gurobi.INF_OR_UNBD {
-		fmt.Printf("Model for %s is infeasible or unbounded\n", os.Args[1])
-	} else if optimstatus == gurobi.TIME_LIMIT {
+	} else if optimstatus == mip.INF_OR_UNBD {
+		fmt.Printf("Model for %s is infeasible or unbounded\n", inputF)
+	} else if optimstatus == mip.TIME_LIMIT {
 		sol.Comment += "Time limit reached"
 	} else {
 		sol.Comment += "For some reason the optimization stopped before the time limit without an optimal solution"
 	}
 
-	objval, err := model.GetDblAttr(gurobi.DBL_ATTR_OBJVAL)
+	objval, err := model.GetDblAttr(mip.DBL_ATTR_OBJVAL)
 	if err != nil {
 		sol.Comment += fmt.Sprintf("Couldn't retrieve the obj-value: %s. ", err.Error())
-		log.Printf("At %s: %s\n", os.Args[1], sol.Comment)
+		log.Printf("At %s: %s\n", inputF, sol.Comment)
 		return
 	}
-	sol.Obj = int(objval)
+	sol.Obj = objval
 
 	lb := 0.0
-	lb, err = model.GetDblAttr(gurobi.DBL_ATTR_OBJBOUND)
+	lb, err = model.GetDblAttr(mip.DBL_ATTR_OBJBOUND)
 	if err != nil {
 		sol.Comment += fmt.Sprintf("Couldn't retrieve the lower-bound-value: %s. ", err.Error())
 		log.Println(err)
 	}
-	sol.LBound = int(lb)
+	sol.LBound = lb
 
-	solM, err := model.GetDblAttrMatrix(gurobi.DBL_ATTR_X, 0, int32(N))
+	solA, err := model.GetDblAttrArray(mip.DBL_ATTR_X, 0, int32(xijNum))
 	if err != nil {
 		log.Println(err)
 	}
+	solM := mip.Matrix(solA, N)
 	//fmt.Printf("Found Solution: %v \n", solM)
 	solNodes := 0
-	opLength := 0
+	opLength := 0.0
 	for i := 0; i < N; i++ {
 		for j := 0; j < N; j++ {
 			if solM[i][j] > 0.5 { //this count edges set to 1. Having n such edges, means we have n nodes to visit
@@ -301,7 +336,7 @@ func main() {
 	tour := findsubtour(solM)
 	sol.Route = tour
 	sol.RouteCost = opLength
-	fmt.Printf("Found a subtour with %d nodes and length %d: %v \n", len(tour), opLength, tour)
+	fmt.Printf("Found a subtour with %d nodes and length %g: %v \n", len(tour), opLength, tour)
 }
 
 /* Given an integer-feasible solution 'sol', find the smallest
@@ -358,14 +393,15 @@ func findsubtour(sol [][]float64) (result []int) {
    find the shortest subtour, and add a subtour elimination constraint
    if that tour doesn't visit every node. */
 
-func subtourelimOP(model *gurobi.Model, cbdata gurobi.CPVoid, where int32, usrdata interface{}) int32 {
+func subtourelimOP(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
 	n := usrdata.(SubData).N
 
-	if where == gurobi.CB_MIPSOL {
-		subSol, err := gurobi.CbGetDblMatrix(cbdata, where, gurobi.CB_MIPSOL_SOL, int(n))
+	if where == mip.CB_MIPSOL {
+		solA, err := cbdata.GetDblArray(mip.CB_MIPSOL_SOL, int(n*n))
 		if err != nil {
 			log.Println(err)
 		}
+		subSol := mip.Matrix(solA, int(n))
 		//fmt.Printf("Found subsolution: %v \n", subSol)
 		subSolNodes := 0
 		for i := 0; i < int(n); i++ {
@@ -399,7 +435,7 @@ func subtourelimOP(model *gurobi.Model, cbdata gurobi.CPVoid, where int32, usrda
 				val = append(val, 1.0)
 			}
 
-			err = gurobi.CbLazy(cbdata, len(ind), ind, val, gurobi.LESS_EQUAL, float64(len(tour)-1))
+			err = cbdata.Lazy(ind, val, mip.LESS_EQUAL, float64(len(tour)-1))
 			if err != nil {
 				log.Println(err)
 			}
@@ -413,16 +449,22 @@ func subtourelimOP(model *gurobi.Model, cbdata gurobi.CPVoid, where int32, usrda
 }
 
 func writeSolution() {
-	jsonInst, err := json.MarshalIndent(sol, "", "\t")
+	verification := op.VerifySolution(&pInst, &sol)
+	sol.Verification = &verification
+	for _, problem := range verification.Problems {
+		log.Printf("At %s: the computed solution is invalid: %s\n", inputF, problem)
+	}
+	fileName := strings.ReplaceAll(strings.TrimSuffix(inputF, ".gz"), ".json", "_sol.json")
+	file, err := os.Create(fileName)
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
 		return
 	}
-	jsonInst = []byte(op.SanitizeJsonArrayLineBreaks(string(jsonInst)))
-	fileName := strings.ReplaceAll(os.Args[1], ".json", "_sol.json")
-	err = ioutil.WriteFile(fileName, jsonInst, 0644)
+	err = op.EncodeJson(file, sol)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
-		return
+		log.Printf("At %s: %s\n", inputF, err.Error())
 	}
 }
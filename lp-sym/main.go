@@ -4,78 +4,121 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
-	"git.solver4all.com/azaryc2s/gorobi/gurobi"
 	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	_ "git.solver4all.com/azaryc2s/op/mip/bnc"
+	_ "git.solver4all.com/azaryc2s/op/mip/grb"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/host"
 	"github.com/shirou/gopsutil/mem"
-	"io/ioutil"
 	"log"
-	"os"
 	"time"
 )
 
 var (
-	edgeDist [][]int
+	edgeDist [][]float64
 	sol      op.Solution
 	pInst    op.Instance
+	inputF   string
 )
 
 func main() {
-	var (
-		err error
-	)
+	//the limits of the gurobi.env file the command used before
+	solverFlags := op.AddSolverFlags(mip.Param{Name: mip.DBL_PAR_TIMELIMIT, Value: "7200"}, mip.Param{Name: mip.INT_PAR_THREADS, Value: "16"},
+		mip.Param{Name: mip.INT_PAR_LOGTOCONSOLE, Value: "0"})
+	flag.Parse()
+	inputF = flag.Arg(0)
+	params, paramsText, err := solverFlags.Params()
+	if err != nil {
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
 	hostStat, _ := host.Info()
 	cpuStat, _ := cpu.Info()
 	vmStat, _ := mem.VirtualMemory()
-	sol = op.Solution{Comment: "", System: op.SysInfo{hostStat.Platform, cpuStat[0].ModelName, fmt.Sprintf("%d GB", (vmStat.Total / 1024 / 1024 / 1024))}}
-
-	instStr, err := ioutil.ReadFile(os.Args[1])
+	sol = op.Solution{Comment: "", System: op.SysInfo{Platform: hostStat.Platform, CPU: cpuStat[0].ModelName, RAM: fmt.Sprintf("%d GB", (vmStat.Total / 1024 / 1024 / 1024))}}
+	sol.Params = paramsText
 
+	inst, err := op.LoadInstanceFile(inputF)
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
 		return
 	}
+	pInst = *inst
 
-	err = json.Unmarshal(instStr, &pInst)
-
+	if problems := pInst.Validate(); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("At %s: invalid instance - %s\n", inputF, problem)
+		}
+		return
+	}
+	edgeDist, err = pInst.GetEdgeDist()
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
+	if !op.IsSymmetric(op.Matrix(edgeDist)) {
+		log.Printf("At %s: the edge weights are asymmetric, which lp-sym doesn't support. Use lp-asym instead\n", inputF)
+		return
+	}
+	if pInst.HasClusters() {
+		log.Printf("At %s: the instance has clusters, which lp-sym doesn't support. Use the solver instead\n", inputF)
+		return
+	}
+	if pInst.HasTimeWindows() {
+		log.Printf("At %s: the instance has time windows, which lp-sym doesn't support. Use the solver instead\n", inputF)
 		return
 	}
-
-	pInst.Solution = &sol
-	edgeDist = op.CalcEdgeDist(pInst.NodeCoordinates, pInst.EdgeWeightType)
 
 	// Create environment
-	env, err := gurobi.LoadEnv("op-lp-sym.log")
+	env, err := mip.LoadEnv("op-lp-sym.log")
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
 		return
 	}
 	defer env.Free()
-	threads, _ := env.GetIntParam(gurobi.INT_PAR_THREADS)
+	if err = params.Apply(env); err != nil {
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
+	threads, _ := env.GetIntParam(mip.INT_PAR_THREADS)
 	sol.Comment = fmt.Sprintf("Using %d threads", threads)
+	sol.Config = op.SolverConfig{Command: "lp-sym", Backend: mip.SelectedBackend() + " " + env.Version()}
 
 	startTime := time.Now()
 
-	tour, score, length, optimstatus, lb, err := op.SolveOP(edgeDist, pInst.Prices, pInst.TMax)
+	start, end := pInst.GetDepots()
+	var (
+		tour              []int
+		routes            [][]int
+		score, length, lb float64
+		optimstatus       int32
+	)
+	if vehicles := pInst.GetVehicles(); vehicles > 1 {
+		var lengths []float64
+		routes, score, lengths, optimstatus, lb, err = op.SolveTOPPath(env, edgeDist, pInst.Prices, pInst.ServiceTimes, pInst.TMax, vehicles, start, end)
+		for _, l := range lengths {
+			length += l
+		}
+	} else {
+		tour, score, length, optimstatus, lb, err = op.SolveOPPath(env, edgeDist, pInst.Prices, pInst.ServiceTimes, pInst.TMax, start, end)
+	}
 	if err != nil {
 		log.Printf("Something went wrong while computing OP: %s\n", err.Error())
 		return
 	}
 
 	sol.Time = time.Since(startTime).String()
-	log.Println("\n---OPTIMIZATION DONE---\n\t Generating and writing result now\n")
+	log.Print("\n---OPTIMIZATION DONE---\n\t Generating and writing result now\n")
 	defer writeSolution()
 
-	if optimstatus == gurobi.OPTIMAL {
+	if optimstatus == mip.OPTIMAL {
 		sol.Optimal = true
-	} else if optimstatus == gurobi.INF_OR_UNBD {
-		fmt.Printf("Model for %s is infeasible or unbounded\n", os.Args[1])
-	} else if optimstatus == gurobi.TIME_LIMIT {
+	} else if optimstatus == mip.INF_OR_UNBD {
+		fmt.Printf("Model for %s is infeasible or unbounded\n", inputF)
+	} else if optimstatus == mip.TIME_LIMIT {
 		sol.Comment += "Time limit reached"
 	} else {
 		sol.Comment += "For some reason the optimization stopped before the time limit without an optimal solution"
@@ -84,22 +127,27 @@ func main() {
 	sol.Obj = score
 	sol.LBound = lb
 	sol.Route = tour
+	sol.Routes = routes
 	sol.RouteCost = length
-	fmt.Printf("Found a subtour with %d nodes and length %d: %v \n", len(tour), length, tour)
+	if routes != nil {
+		fmt.Printf("Found %d routes with a total length %g: %v \n", len(routes), length, routes)
+		return
+	}
+	fmt.Printf("Found a subtour with %d nodes and length %g: %v \n", len(tour), length, tour)
 }
 
 func writeSolution() {
-	jsonInst, err := json.MarshalIndent(pInst, "", "\t")
-	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
-		return
+	verification := op.VerifySolution(&pInst, &sol)
+	sol.Verification = &verification
+	for _, problem := range verification.Problems {
+		log.Printf("At %s: the computed solution is invalid: %s\n", inputF, problem)
 	}
-	jsonInst = []byte(op.SanitizeJsonArrayLineBreaks(string(jsonInst)))
-	//fileName := strings.ReplaceAll(os.Args[1], ".json", "_sol.json")
-	fileName := os.Args[1]
-	err = ioutil.WriteFile(fileName, jsonInst, 0644)
+	pInst.AddSolution(&sol)
+	//fileName := strings.ReplaceAll(inputF, ".json", "_sol.json")
+	fileName := inputF
+	err := op.SaveInstanceFile(fileName, &pInst)
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
 		return
 	}
 }
@@ -0,0 +1,386 @@
+/* Copyright 2021, Arkadiusz Zarychta, arkadiusz.zarychta@h-brs.de */
+
+// Package bnc is a pure-Go branch-and-cut backend for the mip interfaces. It solves the LP
+// relaxations with a dense bounded dual simplex, supports lazy constraints at integer
+// solutions and heuristic solutions from callbacks. Importing it registers the backend under
+// the name "bnc".
+//
+// It is meant for tests and for machines without Gurobi, not for benchmarks. Every pivot
+// updates the whole dense tableau, the search is best-first without presolve, cutting planes
+// or heuristics of its own, and it runs on a single thread. Instances with about ten to fifteen
+// nodes are solved in seconds. Already at 20 nodes lp-sym takes about a minute and the BCH
+// with the BEND_V1 or BEND_V2 cuts can run for more than five minutes, as these cuts are weak
+// in the LP relaxation. Infinite bounds are replaced by 1e9, an incumbent at this value is
+// reported as UNBOUNDED.
+package bnc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+const Name = "bnc"
+
+// Version of the branch-and-cut. It changes with every change, that can lead to other results.
+const Version = "1.0"
+
+func init() {
+	mip.Register(Name, 0, LoadEnv, params())
+}
+
+type Env struct {
+	params map[string]float64
+	logW   io.WriteCloser
+}
+
+type variable struct {
+	obj, lb, ub float64
+	vtype       int8
+	name        string
+}
+
+type constraint struct {
+	ind   []int32
+	val   []float64
+	sense int8
+	rhs   float64
+	name  string
+}
+
+type Model struct {
+	env     *Env
+	name    string
+	params  map[string]float64
+	vars    []variable
+	constrs []constraint
+	sense   int32
+	start   []float64
+
+	cb      mip.CallbackFunc
+	usrdata interface{}
+
+	// results of the last Optimize
+	status    int32
+	objVal    float64
+	objBound  float64
+	x         []float64
+	solCount  int32
+	nodeCount float64
+	runtime   float64
+	terminate int32 //set by Terminate from another goroutine, so it's only accessed atomically
+
+	logger *log.Logger
+}
+
+func defaultParams() map[string]float64 {
+	return map[string]float64{
+		mip.INT_PAR_THREADS:         1,
+		mip.INT_PAR_LAZYCONSTRAINTS: 0,
+		mip.INT_PAR_LOGTOCONSOLE:    1,
+		mip.INT_PAR_SEED:            0, //the search is deterministic, so the seed is only accepted
+		mip.DBL_PAR_TIMELIMIT:       math.Inf(1),
+		mip.DBL_PAR_MIPGAP:          1e-4,
+		mip.DBL_PAR_MIPGAPABS:       1e-10,
+		mip.DBL_PAR_NODELIMIT:       math.Inf(1),
+	}
+}
+
+//the parameters of the environments with whether their value is an integer
+func params() map[string]bool {
+	res := make(map[string]bool)
+	for name := range defaultParams() {
+		res[name] = false
+	}
+	for _, name := range []string{mip.INT_PAR_THREADS, mip.INT_PAR_LAZYCONSTRAINTS, mip.INT_PAR_LOGTOCONSOLE, mip.INT_PAR_SEED} {
+		res[name] = true
+	}
+	return res
+}
+
+func LoadEnv(logFile string) (mip.Env, error) {
+	env := &Env{params: defaultParams()}
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		env.logW = f
+	}
+	return env, nil
+}
+
+func (e *Env) NewModel(name string) (mip.Model, error) {
+	m := &Model{env: e, name: name, sense: mip.MINIMIZE, status: mip.LOADED, params: make(map[string]float64)}
+	for k, v := range e.params {
+		m.params[k] = v
+	}
+	return m, nil
+}
+
+func (e *Env) SetIntParam(name string, value int32) error {
+	return setParam(e.params, name, float64(value))
+}
+
+func (e *Env) GetIntParam(name string) (int32, error) {
+	v, ok := e.params[name]
+	if !ok {
+		return 0, fmt.Errorf("bnc: unknown parameter %s", name)
+	}
+	return int32(v), nil
+}
+
+func (e *Env) SetDblParam(name string, value float64) error {
+	return setParam(e.params, name, value)
+}
+
+func (e *Env) Version() string {
+	return Version
+}
+
+func (e *Env) Free() {
+	if e.logW != nil {
+		e.logW.Close()
+		e.logW = nil
+	}
+}
+
+func setParam(params map[string]float64, name string, value float64) error {
+	if _, ok := params[name]; !ok {
+		return fmt.Errorf("bnc: unknown parameter %s", name)
+	}
+	params[name] = value
+	return nil
+}
+
+func (m *Model) AddVar(obj, lb, ub float64, vtype int8, name string) error {
+	if vtype != mip.CONTINUOUS && vtype != mip.BINARY && vtype != mip.INTEGER {
+		return fmt.Errorf("bnc: unsupported variable type %c for %s", vtype, name)
+	}
+	if vtype == mip.BINARY {
+		lb = math.Max(lb, 0)
+		ub = math.Min(ub, 1)
+	}
+	m.vars = append(m.vars, variable{obj: obj, lb: lb, ub: ub, vtype: vtype, name: name})
+	return nil
+}
+
+func (m *Model) AddConstr(ind []int32, val []float64, sense int8, rhs float64, name string) error {
+	if len(ind) != len(val) {
+		return fmt.Errorf("bnc: constraint %s has %d indices but %d values", name, len(ind), len(val))
+	}
+	for _, i := range ind {
+		if i < 0 || int(i) >= len(m.vars) {
+			return fmt.Errorf("bnc: constraint %s references unknown variable %d", name, i)
+		}
+	}
+	if sense != mip.LESS_EQUAL && sense != mip.GREATER_EQUAL && sense != mip.EQUAL {
+		return fmt.Errorf("bnc: unsupported sense %c for %s", sense, name)
+	}
+	m.constrs = append(m.constrs, constraint{ind: append([]int32{}, ind...), val: append([]float64{}, val...), sense: sense, rhs: rhs, name: name})
+	return nil
+}
+
+func (m *Model) SetIntAttr(name string, value int32) error {
+	if name == mip.INT_ATTR_MODELSENSE {
+		if value != mip.MINIMIZE && value != mip.MAXIMIZE {
+			return fmt.Errorf("bnc: invalid model sense %d", value)
+		}
+		m.sense = value
+		return nil
+	}
+	return fmt.Errorf("bnc: unsupported int attribute %s", name)
+}
+
+func (m *Model) GetIntAttr(name string) (int32, error) {
+	switch name {
+	case mip.INT_ATTR_STATUS:
+		return m.status, nil
+	case mip.INT_ATTR_SOLCOUNT:
+		return m.solCount, nil
+	case mip.INT_ATTR_MODELSENSE:
+		return m.sense, nil
+	}
+	return 0, fmt.Errorf("bnc: unsupported int attribute %s", name)
+}
+
+func (m *Model) GetDblAttr(name string) (float64, error) {
+	switch name {
+	case mip.DBL_ATTR_OBJVAL:
+		if m.solCount == 0 {
+			return 0, errors.New("bnc: no solution available")
+		}
+		return m.objVal, nil
+	case mip.DBL_ATTR_OBJBOUND:
+		if m.status == mip.LOADED {
+			return 0, errors.New("bnc: model has not been optimized")
+		}
+		return m.objBound, nil
+	case mip.DBL_ATTR_NODECOUNT:
+		return m.nodeCount, nil
+	case mip.DBL_ATTR_RUNTIME:
+		return m.runtime, nil
+	case mip.DBL_ATTR_MIPGAP:
+		if m.solCount == 0 {
+			return math.Inf(1), nil
+		}
+		return gap(m.objVal, m.objBound), nil
+	}
+	return 0, fmt.Errorf("bnc: unsupported double attribute %s", name)
+}
+
+func (m *Model) GetDblAttrArray(name string, start, length int32) ([]float64, error) {
+	if start < 0 || int(start+length) > len(m.vars) {
+		return nil, fmt.Errorf("bnc: range %d+%d out of bounds for %d variables", start, length, len(m.vars))
+	}
+	res := make([]float64, length)
+	switch name {
+	case mip.DBL_ATTR_X:
+		if m.solCount == 0 {
+			return nil, errors.New("bnc: no solution available")
+		}
+		copy(res, m.x[start:start+length])
+	case mip.DBL_ATTR_LB, mip.DBL_ATTR_UB:
+		for i := range res {
+			if name == mip.DBL_ATTR_LB {
+				res[i] = m.vars[int(start)+i].lb
+			} else {
+				res[i] = m.vars[int(start)+i].ub
+			}
+		}
+	default:
+		return nil, fmt.Errorf("bnc: unsupported double array attribute %s", name)
+	}
+	return res, nil
+}
+
+func (m *Model) SetDblAttrArray(name string, start int32, values []float64) error {
+	if start < 0 || int(start)+len(values) > len(m.vars) {
+		return fmt.Errorf("bnc: range %d+%d out of bounds for %d variables", start, len(values), len(m.vars))
+	}
+	for i, v := range values {
+		if err := m.SetDblAttrElem(name, start+int32(i), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Model) SetDblAttrElem(name string, element int32, value float64) error {
+	if element < 0 || int(element) >= len(m.vars) {
+		return fmt.Errorf("bnc: variable %d out of bounds", element)
+	}
+	switch name {
+	case mip.DBL_ATTR_LB:
+		m.vars[element].lb = value
+	case mip.DBL_ATTR_UB:
+		m.vars[element].ub = value
+	case mip.DBL_ATTR_START:
+		if len(m.start) != len(m.vars) {
+			m.start = make([]float64, len(m.vars))
+		}
+		m.start[element] = value
+	default:
+		return fmt.Errorf("bnc: unsupported double attribute %s", name)
+	}
+	return nil
+}
+
+func (m *Model) SetIntParam(name string, value int32) error {
+	return setParam(m.params, name, float64(value))
+}
+
+func (m *Model) SetCallbackFunc(fn mip.CallbackFunc, usrdata interface{}) error {
+	m.cb = fn
+	m.usrdata = usrdata
+	return nil
+}
+
+func (m *Model) Terminate() {
+	atomic.StoreInt32(&m.terminate, 1)
+}
+
+func (m *Model) Free() {}
+
+// Write stores the model in the CPLEX LP format
+func (m *Model) Write(fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if m.sense == mip.MAXIMIZE {
+		fmt.Fprintln(w, "Maximize")
+	} else {
+		fmt.Fprintln(w, "Minimize")
+	}
+	var terms []string
+	for _, v := range m.vars {
+		if v.obj != 0 {
+			terms = append(terms, fmt.Sprintf("%+g %s", v.obj, v.name))
+		}
+	}
+	fmt.Fprintf(w, " obj: %s\nSubject To\n", strings.Join(terms, " "))
+	for k, c := range m.constrs {
+		terms = terms[:0]
+		for i, ind := range c.ind {
+			terms = append(terms, fmt.Sprintf("%+g %s", c.val[i], m.vars[ind].name))
+		}
+		sense := "<="
+		if c.sense == mip.GREATER_EQUAL {
+			sense = ">="
+		} else if c.sense == mip.EQUAL {
+			sense = "="
+		}
+		name := c.name
+		if name == "" {
+			name = fmt.Sprintf("R%d", k)
+		}
+		fmt.Fprintf(w, " %s: %s %s %g\n", name, strings.Join(terms, " "), sense, c.rhs)
+	}
+	fmt.Fprintln(w, "Bounds")
+	for _, v := range m.vars {
+		if !v.plainBinary() {
+			fmt.Fprintf(w, " %g <= %s <= %g\n", v.lb, v.name, v.ub)
+		}
+	}
+	//the binaries with other bounds than 0 and 1, e.g. fixed ones, are written as integers with their bounds
+	for _, section := range []struct {
+		title  string
+		binary bool
+	}{{"Binaries", true}, {"Generals", false}} {
+		var names []string
+		for _, v := range m.vars {
+			if v.vtype != mip.CONTINUOUS && v.plainBinary() == section.binary {
+				names = append(names, v.name)
+			}
+		}
+		if len(names) > 0 {
+			fmt.Fprintf(w, "%s\n %s\n", section.title, strings.Join(names, " "))
+		}
+	}
+	fmt.Fprintln(w, "End")
+	return w.Flush()
+}
+
+func (v variable) plainBinary() bool {
+	return v.vtype == mip.BINARY && v.lb == 0 && v.ub == 1
+}
+
+func gap(obj, bound float64) float64 {
+	if obj == bound {
+		return 0
+	}
+	if obj == 0 {
+		return math.Inf(1)
+	}
+	return math.Abs(obj-bound) / math.Abs(obj)
+}
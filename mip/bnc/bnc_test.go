@@ -0,0 +1,427 @@
+package bnc
+
+import (
+	"git.solver4all.com/azaryc2s/op/mip"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testTol = 1e-6
+
+type testVar struct {
+	obj, lb, ub float64
+	vtype       int8
+}
+
+type testConstr struct {
+	ind   []int32
+	val   []float64
+	sense int8
+	rhs   float64
+}
+
+//build a model without log output
+func newTestModel(t *testing.T, sense int32, vars []testVar, constrs []testConstr) mip.Model {
+	t.Helper()
+	env, err := LoadEnv("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(env.Free)
+	if err = env.SetIntParam(mip.INT_PAR_LOGTOCONSOLE, 0); err != nil {
+		t.Fatal(err)
+	}
+	model, err := env.NewModel("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j, v := range vars {
+		if err = model.AddVar(v.obj, v.lb, v.ub, v.vtype, "x"+string(rune('a'+j))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range constrs {
+		if err = model.AddConstr(c.ind, c.val, c.sense, c.rhs, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = model.SetIntAttr(mip.INT_ATTR_MODELSENSE, sense); err != nil {
+		t.Fatal(err)
+	}
+	return model
+}
+
+//optimize the model and check its status and, for an optimal one, the objective
+func optimize(t *testing.T, model mip.Model, status int32, obj float64) []float64 {
+	t.Helper()
+	if err := model.Optimize(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := model.GetIntAttr(mip.INT_ATTR_STATUS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != status {
+		t.Fatalf("status %d, want %d", got, status)
+	}
+	if status != mip.OPTIMAL {
+		return nil
+	}
+	objVal, err := model.GetDblAttr(mip.DBL_ATTR_OBJVAL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(objVal-obj) > testTol {
+		t.Fatalf("objective %g, want %g", objVal, obj)
+	}
+	bound, err := model.GetDblAttr(mip.DBL_ATTR_OBJBOUND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(bound-obj) > testTol {
+		t.Fatalf("bound %g, want %g", bound, obj)
+	}
+	n, _ := model.GetIntAttr(mip.INT_ATTR_SOLCOUNT)
+	x, err := model.GetDblAttrArray(mip.DBL_ATTR_X, 0, int32(len(model.(*Model).vars)))
+	if err != nil || n != 1 {
+		t.Fatalf("no solution: %v", err)
+	}
+	return x
+}
+
+func checkValues(t *testing.T, x []float64, want []float64) {
+	t.Helper()
+	for j := range want {
+		if math.Abs(x[j]-want[j]) > testTol {
+			t.Fatalf("x = %v, want %v", x, want)
+		}
+	}
+}
+
+func TestLP(t *testing.T) {
+	inf := math.Inf(1)
+	//max x + y with x + 2y <= 4 and 3x + y <= 6 has its optimum at (1.6, 1.2)
+	model := newTestModel(t, mip.MAXIMIZE, []testVar{{1, 0, inf, mip.CONTINUOUS}, {1, 0, inf, mip.CONTINUOUS}}, []testConstr{
+		{[]int32{0, 1}, []float64{1, 2}, mip.LESS_EQUAL, 4},
+		{[]int32{0, 1}, []float64{3, 1}, mip.LESS_EQUAL, 6},
+	})
+	x := optimize(t, model, mip.OPTIMAL, 2.8)
+	checkValues(t, x, []float64{1.6, 1.2})
+}
+
+func TestLPWithEqualityAndGreater(t *testing.T) {
+	inf := math.Inf(1)
+	//min 2x + 3y with x + y = 4 and x >= 1, y >= 1.5 has its optimum at (2.5, 1.5)
+	model := newTestModel(t, mip.MINIMIZE, []testVar{{2, 0, inf, mip.CONTINUOUS}, {3, 0, inf, mip.CONTINUOUS}}, []testConstr{
+		{[]int32{0, 1}, []float64{1, 1}, mip.EQUAL, 4},
+		{[]int32{0}, []float64{1}, mip.GREATER_EQUAL, 1},
+		{[]int32{1}, []float64{1}, mip.GREATER_EQUAL, 1.5},
+	})
+	x := optimize(t, model, mip.OPTIMAL, 9.5)
+	checkValues(t, x, []float64{2.5, 1.5})
+}
+
+func TestInfeasible(t *testing.T) {
+	inf := math.Inf(1)
+	model := newTestModel(t, mip.MAXIMIZE, []testVar{{1, 0, inf, mip.CONTINUOUS}, {1, 0, inf, mip.CONTINUOUS}}, []testConstr{
+		{[]int32{0, 1}, []float64{1, 1}, mip.GREATER_EQUAL, 3},
+		{[]int32{0, 1}, []float64{1, 1}, mip.LESS_EQUAL, 2},
+	})
+	optimize(t, model, mip.INFEASIBLE, 0)
+	if _, err := model.GetDblAttr(mip.DBL_ATTR_OBJVAL); err == nil {
+		t.Fatal("an infeasible model has an objective")
+	}
+}
+
+func TestInfeasibleIntegers(t *testing.T) {
+	//2x = 1 has no integer solution
+	model := newTestModel(t, mip.MINIMIZE, []testVar{{1, 0, 5, mip.INTEGER}}, []testConstr{
+		{[]int32{0}, []float64{2}, mip.EQUAL, 1},
+	})
+	optimize(t, model, mip.INFEASIBLE, 0)
+}
+
+func TestUnbounded(t *testing.T) {
+	inf := math.Inf(1)
+	//max x + y with x - y <= 1 grows along x = y
+	model := newTestModel(t, mip.MAXIMIZE, []testVar{{1, 0, inf, mip.CONTINUOUS}, {1, 0, inf, mip.INTEGER}}, []testConstr{
+		{[]int32{0, 1}, []float64{1, -1}, mip.LESS_EQUAL, 1},
+	})
+	optimize(t, model, mip.UNBOUNDED, 0)
+	if n, _ := model.GetIntAttr(mip.INT_ATTR_SOLCOUNT); n != 0 {
+		t.Fatal("an unbounded model has a solution")
+	}
+}
+
+func TestDegenerate(t *testing.T) {
+	//all five constraints go through the optimum (1, 1)
+	model := newTestModel(t, mip.MAXIMIZE, []testVar{{1, 0, 10, mip.CONTINUOUS}, {1, 0, 10, mip.CONTINUOUS}}, []testConstr{
+		{[]int32{0}, []float64{1}, mip.LESS_EQUAL, 1},
+		{[]int32{1}, []float64{1}, mip.LESS_EQUAL, 1},
+		{[]int32{0, 1}, []float64{1, 1}, mip.LESS_EQUAL, 2},
+		{[]int32{0, 1}, []float64{1, 2}, mip.LESS_EQUAL, 3},
+		{[]int32{0, 1}, []float64{2, 1}, mip.LESS_EQUAL, 3},
+	})
+	x := optimize(t, model, mip.OPTIMAL, 2)
+	checkValues(t, x, []float64{1, 1})
+}
+
+func TestKnapsack(t *testing.T) {
+	//max 5a + 4b + 3c with 2a + 3b + c <= 5 takes a and b
+	model := newTestModel(t, mip.MAXIMIZE, []testVar{{5, 0, 1, mip.BINARY}, {4, 0, 1, mip.BINARY}, {3, 0, 1, mip.BINARY}}, []testConstr{
+		{[]int32{0, 1, 2}, []float64{2, 3, 1}, mip.LESS_EQUAL, 5},
+	})
+	x := optimize(t, model, mip.OPTIMAL, 9)
+	checkValues(t, x, []float64{1, 1, 0})
+}
+
+func TestGapBound(t *testing.T) {
+	//the knapsack of TestKnapsack with a poor start, that is good enough for the gap, so the bound stays the one of
+	//the relaxation: c, a and 2/3 of b
+	model := newTestModel(t, mip.MAXIMIZE, []testVar{{5, 0, 1, mip.BINARY}, {4, 0, 1, mip.BINARY}, {3, 0, 1, mip.BINARY}}, []testConstr{
+		{[]int32{0, 1, 2}, []float64{2, 3, 1}, mip.LESS_EQUAL, 5},
+	})
+	model.(*Model).params[mip.DBL_PAR_MIPGAP] = 10
+	if err := model.SetDblAttrArray(mip.DBL_ATTR_START, 0, []float64{0, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Optimize(); err != nil {
+		t.Fatal(err)
+	}
+	status, _ := model.GetIntAttr(mip.INT_ATTR_STATUS)
+	obj, _ := model.GetDblAttr(mip.DBL_ATTR_OBJVAL)
+	bound, _ := model.GetDblAttr(mip.DBL_ATTR_OBJBOUND)
+	if status != mip.OPTIMAL || math.Abs(obj-3) > testTol || math.Abs(bound-32.0/3) > testTol {
+		t.Fatalf("status %d, objective %g and bound %g, want %d, 3 and %g", status, obj, bound, mip.OPTIMAL, 32.0/3)
+	}
+}
+
+func TestFixedBinary(t *testing.T) {
+	model := newTestModel(t, mip.MAXIMIZE, []testVar{{3, 0, 1, mip.BINARY}, {1, 0, 1, mip.BINARY}}, []testConstr{
+		{[]int32{0, 1}, []float64{1, 1}, mip.LESS_EQUAL, 2},
+	})
+	if err := model.SetDblAttrElem(mip.DBL_ATTR_UB, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	x := optimize(t, model, mip.OPTIMAL, 1)
+	checkValues(t, x, []float64{0, 1})
+
+	//the fixed binary keeps its bounds in the written model
+	fileName := filepath.Join(t.TempDir(), "fixed.lp")
+	if err := model.Write(fileName); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lp := string(content)
+	if !strings.Contains(lp, " 0 <= xa <= 0\n") {
+		t.Fatalf("the bounds of the fixed binary are missing:\n%s", lp)
+	}
+	if !strings.Contains(lp, "Binaries\n xb\n") || !strings.Contains(lp, "Generals\n xa\n") {
+		t.Fatalf("the fixed binary isn't written as an integer:\n%s", lp)
+	}
+	if strings.Contains(lp, "<= xb <=") {
+		t.Fatalf("the free binary has bounds:\n%s", lp)
+	}
+}
+
+//the symmetric TSP over two triangles far apart, whose degree constraints are met by two subtours
+var tspPoints = [][2]float64{{0, 0}, {1, 0}, {0, 1}, {10, 0}, {11, 0}, {10, 1}}
+
+func tspDist(i, j int) float64 {
+	return math.Hypot(tspPoints[i][0]-tspPoints[j][0], tspPoints[i][1]-tspPoints[j][1])
+}
+
+//the length of the shortest tour by trying all orders of the nodes after the first
+func bruteForceTSP(n int) float64 {
+	best := math.Inf(1)
+	perm := make([]int, n-1)
+	for i := range perm {
+		perm[i] = i + 1
+	}
+	var permute func(k int)
+	permute = func(k int) {
+		if k == len(perm) {
+			length, last := 0.0, 0
+			for _, node := range perm {
+				length += tspDist(last, node)
+				last = node
+			}
+			best = math.Min(best, length+tspDist(last, 0))
+			return
+		}
+		for i := k; i < len(perm); i++ {
+			perm[k], perm[i] = perm[i], perm[k]
+			permute(k + 1)
+			perm[k], perm[i] = perm[i], perm[k]
+		}
+	}
+	permute(0)
+	return best
+}
+
+func TestLazySubtourElimination(t *testing.T) {
+	n := len(tspPoints)
+	var (
+		vars  []testVar
+		edges [][2]int
+	)
+	index := make(map[[2]int]int32)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			index[[2]int{i, j}] = int32(len(vars))
+			edges = append(edges, [2]int{i, j})
+			vars = append(vars, testVar{tspDist(i, j), 0, 1, mip.BINARY})
+		}
+	}
+	edge := func(i, j int) int32 {
+		if i > j {
+			i, j = j, i
+		}
+		return index[[2]int{i, j}]
+	}
+	var constrs []testConstr
+	for i := 0; i < n; i++ {
+		c := testConstr{sense: mip.EQUAL, rhs: 2}
+		for j := 0; j < n; j++ {
+			if j != i {
+				c.ind = append(c.ind, edge(i, j))
+				c.val = append(c.val, 1)
+			}
+		}
+		constrs = append(constrs, c)
+	}
+	model := newTestModel(t, mip.MINIMIZE, vars, constrs)
+	if err := model.SetIntParam(mip.INT_PAR_LAZYCONSTRAINTS, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	cuts := 0
+	err := model.SetCallbackFunc(func(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+		if where != mip.CB_MIPSOL {
+			return 0
+		}
+		x, err := cbdata.GetDblArray(mip.CB_MIPSOL_SOL, len(edges))
+		if err != nil {
+			t.Error(err)
+			return 0
+		}
+		//the nodes connected to node 0
+		seen := map[int]bool{0: true}
+		for stack := []int{0}; len(stack) > 0; {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for j := 0; j < n; j++ {
+				if j != i && !seen[j] && x[edge(i, j)] > 0.5 {
+					seen[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		if len(seen) == n {
+			return 0
+		}
+		//at most |S| - 1 edges within the subtour S
+		var ind []int32
+		var val []float64
+		for i := range seen {
+			for j := range seen {
+				if i < j {
+					ind = append(ind, edge(i, j))
+					val = append(val, 1)
+				}
+			}
+		}
+		if err = cbdata.Lazy(ind, val, mip.LESS_EQUAL, float64(len(seen)-1)); err != nil {
+			t.Error(err)
+		}
+		cuts++
+		return 0
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := optimize(t, model, mip.OPTIMAL, bruteForceTSP(n))
+	if cuts == 0 {
+		t.Fatal("the two triangles were never cut off")
+	}
+	used := 0
+	for _, v := range x {
+		used += int(math.Round(v))
+	}
+	if used != n {
+		t.Fatalf("the tour has %d edges, want %d", used, n)
+	}
+}
+
+func TestTerminateBeforeOptimize(t *testing.T) {
+	model := newTestModel(t, mip.MAXIMIZE, []testVar{{5, 0, 1, mip.BINARY}, {4, 0, 1, mip.BINARY}}, []testConstr{
+		{[]int32{0, 1}, []float64{2, 3}, mip.LESS_EQUAL, 4},
+	})
+	model.Terminate()
+	optimize(t, model, mip.INTERRUPTED, 0)
+	//the request is only for the next solve
+	optimize(t, model, mip.OPTIMAL, 5)
+}
+
+func TestUnknownParam(t *testing.T) {
+	env, err := LoadEnv("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Free()
+	if err = env.SetDblParam("Heuristics", 0.5); err == nil {
+		t.Fatal("an unknown parameter was accepted")
+	}
+	//the parameter files are checked against the parameters of the backend before they are applied
+	if _, err = mip.ParseParams(Name, "Heuristics=0.5"); err == nil {
+		t.Fatal("a parameter of gurobi was accepted for bnc")
+	}
+	params, err := mip.ParseParams(Name, "Threads=1\nMIPGap=0.01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = params.Apply(env); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeuristicSolution(t *testing.T) {
+	//the knapsack of TestKnapsack gets its optimum from the MIPNODE callback of the fractional root
+	model := newTestModel(t, mip.MAXIMIZE, []testVar{{5, 0, 1, mip.BINARY}, {4, 0, 1, mip.BINARY}, {3, 0, 1, mip.BINARY}}, []testConstr{
+		{[]int32{0, 1, 2}, []float64{2, 3, 1}, mip.LESS_EQUAL, 5},
+	})
+	var inside, proposed bool
+	solutions := 0
+	err := model.SetCallbackFunc(func(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+		if inside {
+			t.Error("the callback was called again from within")
+		}
+		inside = true
+		defer func() { inside = false }()
+		if where == mip.CB_MIPSOL {
+			solutions++
+		}
+		if where == mip.CB_MIPNODE && !proposed {
+			proposed = true
+			obj, err := cbdata.Solution([]float64{1, 1, 0})
+			if err != nil || math.Abs(obj-9) > testTol {
+				t.Errorf("the solution got the objective %g and the error %v, want 9", obj, err)
+			}
+		}
+		return 0
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := optimize(t, model, mip.OPTIMAL, 9)
+	checkValues(t, x, []float64{1, 1, 0})
+	if !proposed || solutions == 0 {
+		t.Fatalf("the heuristic solution wasn't proposed or checked by the MIPSOL callback")
+	}
+}
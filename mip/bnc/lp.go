@@ -0,0 +1,444 @@
+/* Copyright 2021, Arkadiusz Zarychta, arkadiusz.zarychta@h-brs.de */
+
+package bnc
+
+import (
+	"math"
+)
+
+/*
+  Dense bounded dual simplex.
+
+  Every row i of the LP is turned into an equality a_i*x + s_i = rhs_i by adding a slack s_i
+  with the bounds [0,inf) for <=, (-inf,0] for >= and [0,0] for = rows. The slack columns are
+  stored after the n structural columns, so the LP has n+m columns in total.
+
+  Since all structural variables are boxed, the slack basis with every nonbasic variable at the
+  bound favoured by its cost is always dual feasible. Changing bounds or adding rows keeps dual
+  feasibility, which lets the branch-and-bound reuse the last basis for every node and every
+  round of lazy constraints.
+*/
+
+const (
+	lpOptimal = iota
+	lpInfeasible
+	lpIterLimit
+)
+
+const (
+	primalTol  = 1e-7
+	dualTol    = 1e-9
+	pivotTol   = 1e-9
+	refactorAt = 100 // pivots between two refactorizations of the tableau
+	bigBound   = 1e9 // replaces infinite bounds of structural variables
+)
+
+type lp struct {
+	m, n int
+
+	// original data. rows are kept dense over the structural columns
+	rows [][]float64
+	rhs  []float64
+	cost []float64 // for all n+m columns (slacks have 0)
+	lb   []float64 // for all n+m columns
+	ub   []float64
+
+	// simplex state
+	t       [][]float64 // m x (n+m) tableau B^-1 [A I]
+	basis   []int       // basic column per row
+	pos     []int       // row of a basic column, -1 for nonbasic columns
+	atUpper []bool      // nonbasic column sits at its upper bound
+	x       []float64   // values of all columns
+	d       []float64   // reduced costs of all columns
+	pivots  int         // pivots since the last refactorization
+	iterSum int         // pivots over the lifetime of the LP
+}
+
+func newLP(cost, lb, ub []float64) *lp {
+	n := len(cost)
+	l := &lp{n: n}
+	l.cost = append([]float64{}, cost...)
+	l.lb = make([]float64, n)
+	l.ub = make([]float64, n)
+	for j := 0; j < n; j++ {
+		l.lb[j] = math.Max(lb[j], -bigBound)
+		l.ub[j] = math.Min(ub[j], bigBound)
+	}
+	l.x = make([]float64, n)
+	l.d = make([]float64, n)
+	l.pos = make([]int, n)
+	l.atUpper = make([]bool, n)
+	l.reset()
+	return l
+}
+
+// addRow appends the row 'a x (sense) rhs' with a as a dense vector over the structural columns.
+// The new slack becomes basic, so the current basis stays dual feasible.
+func (l *lp) addRow(a []float64, sense int8, rhs float64) {
+	slackLB, slackUB := 0.0, math.Inf(1)
+	if sense == '>' {
+		slackLB, slackUB = math.Inf(-1), 0.0
+	} else if sense == '=' {
+		slackUB = 0.0
+	}
+	l.rows = append(l.rows, a)
+	l.rhs = append(l.rhs, rhs)
+	l.cost = append(l.cost, 0)
+	l.lb = append(l.lb, slackLB)
+	l.ub = append(l.ub, slackUB)
+	l.d = append(l.d, 0)
+	l.atUpper = append(l.atUpper, false)
+	col := l.n + l.m
+	l.m++
+
+	for i := range l.t {
+		l.t[i] = append(l.t[i], 0)
+	}
+	row := make([]float64, l.n+l.m)
+	copy(row, a)
+	row[col] = 1
+	for i := 0; i < len(l.t); i++ {
+		coef := row[l.basis[i]]
+		if coef == 0 {
+			continue
+		}
+		ti := l.t[i]
+		for j := range row {
+			row[j] -= coef * ti[j]
+		}
+		row[l.basis[i]] = 0
+	}
+	l.t = append(l.t, row)
+	l.basis = append(l.basis, col)
+	l.pos = append(l.pos, len(l.t)-1)
+
+	act := 0.0
+	for j := 0; j < l.n; j++ {
+		act += a[j] * l.x[j]
+	}
+	l.x = append(l.x, rhs-act)
+}
+
+// setBounds changes the bounds of the structural columns and moves the nonbasic ones along.
+func (l *lp) setBounds(lb, ub []float64) {
+	for j := 0; j < l.n; j++ {
+		nlb := math.Max(lb[j], -bigBound)
+		nub := math.Min(ub[j], bigBound)
+		l.lb[j] = nlb
+		l.ub[j] = nub
+		if l.pos[j] >= 0 {
+			continue
+		}
+		// columns that were fixed may have picked up a reduced cost of any sign,
+		// so the side has to be chosen again to stay dual feasible
+		if l.d[j] < -dualTol {
+			l.atUpper[j] = true
+		} else if l.d[j] > dualTol {
+			l.atUpper[j] = false
+		}
+		target := nlb
+		if l.atUpper[j] {
+			target = nub
+		}
+		l.moveNonbasic(j, target)
+	}
+}
+
+func (l *lp) moveNonbasic(j int, value float64) {
+	delta := value - l.x[j]
+	if delta == 0 {
+		return
+	}
+	for i := 0; i < l.m; i++ {
+		if a := l.t[i][j]; a != 0 {
+			l.x[l.basis[i]] -= a * delta
+		}
+	}
+	l.x[j] = value
+}
+
+// reset installs the slack basis. Nonbasic columns are placed at the bound their cost favours.
+func (l *lp) reset() {
+	cols := l.n + l.m
+	l.t = make([][]float64, l.m)
+	l.basis = make([]int, l.m)
+	l.pos = make([]int, cols)
+	for j := range l.pos {
+		l.pos[j] = -1
+	}
+	for i := 0; i < l.m; i++ {
+		row := make([]float64, cols)
+		copy(row, l.rows[i])
+		row[l.n+i] = 1
+		l.t[i] = row
+		l.basis[i] = l.n + i
+		l.pos[l.n+i] = i
+	}
+	for j := 0; j < l.n; j++ {
+		l.atUpper[j] = l.cost[j] < 0
+		if l.atUpper[j] {
+			l.x[j] = l.ub[j]
+		} else {
+			l.x[j] = l.lb[j]
+		}
+	}
+	copy(l.d, l.cost)
+	l.recomputeBasic()
+	l.pivots = 0
+}
+
+// recomputeBasic sets the basic values from the nonbasic ones using the current tableau.
+// It relies on the tableau holding B^-1 [A I] and rhs being transformed the same way,
+// which is why it is only called right after the tableau has been (re)built.
+func (l *lp) recomputeBasic() {
+	beta := l.transformedRHS()
+	for i := 0; i < l.m; i++ {
+		v := beta[i]
+		ti := l.t[i]
+		for j := 0; j < l.n+l.m; j++ {
+			if l.pos[j] < 0 && ti[j] != 0 {
+				v -= ti[j] * l.x[j]
+			}
+		}
+		l.x[l.basis[i]] = v
+	}
+}
+
+// transformedRHS returns B^-1 rhs. The slack columns of the tableau hold B^-1 itself.
+func (l *lp) transformedRHS() []float64 {
+	beta := make([]float64, l.m)
+	for i := 0; i < l.m; i++ {
+		v := 0.0
+		ti := l.t[i]
+		for k := 0; k < l.m; k++ {
+			v += ti[l.n+k] * l.rhs[k]
+		}
+		beta[i] = v
+	}
+	return beta
+}
+
+// refactor rebuilds the tableau for the current basis from the original rows to get rid
+// of the error accumulated over many pivots. Falls back to the slack basis if singular.
+func (l *lp) refactor() {
+	cols := l.n + l.m
+	mat := make([][]float64, l.m)
+	for i := 0; i < l.m; i++ {
+		row := make([]float64, cols)
+		copy(row, l.rows[i])
+		row[l.n+i] = 1
+		mat[i] = row
+	}
+	basic := append([]int{}, l.basis...)
+	for r, col := range basic {
+		best, bestAbs := -1, 1e-11
+		for i := r; i < l.m; i++ {
+			if a := math.Abs(mat[i][col]); a > bestAbs {
+				best, bestAbs = i, a
+			}
+		}
+		if best < 0 {
+			l.reset()
+			return
+		}
+		mat[r], mat[best] = mat[best], mat[r]
+		pivotRow(mat, r, col)
+		l.basis[r] = col
+		l.pos[col] = r
+	}
+	l.t = mat
+	l.recomputeBasic()
+	for j := 0; j < cols; j++ {
+		if l.pos[j] >= 0 {
+			l.d[j] = 0
+			continue
+		}
+		v := l.cost[j]
+		for i := 0; i < l.m; i++ {
+			v -= l.cost[l.basis[i]] * l.t[i][j]
+		}
+		l.d[j] = v
+		// restore dual feasibility lost to rounding by flipping boxed columns
+		if !l.atUpper[j] && v < -dualTol && !math.IsInf(l.ub[j], 1) {
+			l.atUpper[j] = true
+			l.moveNonbasic(j, l.ub[j])
+		} else if l.atUpper[j] && v > dualTol && !math.IsInf(l.lb[j], -1) {
+			l.atUpper[j] = false
+			l.moveNonbasic(j, l.lb[j])
+		}
+	}
+	l.pivots = 0
+}
+
+func pivotRow(t [][]float64, r, q int) {
+	tr := t[r]
+	inv := 1 / tr[q]
+	for j := range tr {
+		tr[j] *= inv
+	}
+	tr[q] = 1
+	for i := range t {
+		if i == r {
+			continue
+		}
+		ti := t[i]
+		f := ti[q]
+		if f == 0 {
+			continue
+		}
+		for j, v := range tr {
+			if v != 0 {
+				ti[j] -= f * v
+			}
+		}
+		ti[q] = 0
+	}
+}
+
+// solve runs the dual simplex from the current basis
+func (l *lp) solve(maxIter int) int {
+	for iter := 0; iter < maxIter; iter++ {
+		if l.pivots >= refactorAt {
+			l.refactor()
+		}
+		// leaving row: largest primal infeasibility
+		r := -1
+		worst := primalTol
+		for i := 0; i < l.m; i++ {
+			b := l.basis[i]
+			xb := l.x[b]
+			if inf := l.lb[b] - xb; inf > worst {
+				r, worst = i, inf
+			} else if inf := xb - l.ub[b]; inf > worst {
+				r, worst = i, inf
+			}
+		}
+		if r < 0 {
+			return lpOptimal
+		}
+		p := l.basis[r]
+		toLower := l.x[p] < l.lb[p]
+		tr := l.t[r]
+
+		// entering column: dual ratio test, ties broken by the largest pivot element
+		q := -1
+		bestRatio := math.Inf(1)
+		bestAlpha := 0.0
+		for j := 0; j < l.n+l.m; j++ {
+			if l.pos[j] >= 0 || l.lb[j] == l.ub[j] {
+				continue
+			}
+			alpha := tr[j]
+			if math.Abs(alpha) < pivotTol {
+				continue
+			}
+			var ratio float64
+			if toLower {
+				if !l.atUpper[j] && alpha < 0 {
+					ratio = l.d[j] / -alpha
+				} else if l.atUpper[j] && alpha > 0 {
+					ratio = -l.d[j] / alpha
+				} else {
+					continue
+				}
+			} else {
+				if !l.atUpper[j] && alpha > 0 {
+					ratio = l.d[j] / alpha
+				} else if l.atUpper[j] && alpha < 0 {
+					ratio = l.d[j] / alpha
+				} else {
+					continue
+				}
+			}
+			if ratio < 0 {
+				ratio = 0
+			}
+			if ratio < bestRatio-dualTol || (ratio <= bestRatio+dualTol && math.Abs(alpha) > bestAlpha) {
+				q = j
+				bestRatio = math.Min(ratio, bestRatio)
+				bestAlpha = math.Abs(alpha)
+			}
+		}
+		if q < 0 {
+			return lpInfeasible
+		}
+
+		alphaQ := tr[q]
+		target := l.ub[p]
+		if toLower {
+			target = l.lb[p]
+		}
+
+		// primal step
+		thetaP := (l.x[p] - target) / alphaQ
+		for i := 0; i < l.m; i++ {
+			if a := l.t[i][q]; a != 0 {
+				l.x[l.basis[i]] -= thetaP * a
+			}
+		}
+		l.x[q] += thetaP
+		l.x[p] = target
+
+		// dual step
+		thetaD := l.d[q] / alphaQ
+		for j, a := range tr {
+			if a != 0 {
+				l.d[j] -= thetaD * a
+			}
+		}
+		l.d[q] = 0
+
+		pivotRow(l.t, r, q)
+		l.basis[r] = q
+		l.pos[q] = r
+		l.pos[p] = -1
+		l.atUpper[p] = !toLower
+		l.pivots++
+		l.iterSum++
+	}
+	return lpIterLimit
+}
+
+// residual returns the largest violation of the original rows by the current values
+func (l *lp) residual() float64 {
+	res := 0.0
+	for i := 0; i < l.m; i++ {
+		v := l.x[l.n+i] - l.rhs[i]
+		for j, a := range l.rows[i] {
+			if a != 0 {
+				v += a * l.x[j]
+			}
+		}
+		res = math.Max(res, math.Abs(v)/(1+math.Abs(l.rhs[i])))
+	}
+	return res
+}
+
+// optimize solves the LP with the current bounds and rows, refactoring or restarting
+// from the slack basis if the warm started solve runs into numerical trouble.
+func (l *lp) optimize() int {
+	maxIter := 20 * (l.n + l.m + 10)
+	status := l.solve(maxIter)
+	if status == lpOptimal && l.residual() < 1e-6 {
+		return status
+	}
+	l.refactor()
+	status = l.solve(maxIter)
+	if status == lpOptimal && l.residual() < 1e-6 {
+		return status
+	}
+	l.reset()
+	status = l.solve(4 * maxIter)
+	if status == lpOptimal && l.residual() >= 1e-6 {
+		return lpIterLimit
+	}
+	return status
+}
+
+func (l *lp) objective() float64 {
+	obj := 0.0
+	for j := 0; j < l.n; j++ {
+		obj += l.cost[j] * l.x[j]
+	}
+	return obj
+}
@@ -0,0 +1,472 @@
+/* Copyright 2021, Arkadiusz Zarychta, arkadiusz.zarychta@h-brs.de */
+
+package bnc
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	intTol  = 1e-6
+	feasTol = 1e-6
+	logFreq = 100 // nodes between two log lines
+)
+
+type node struct {
+	lb, ub []float64
+	bound  float64 // lower bound of the (internally minimized) objective
+	depth  int
+	seq    int
+}
+
+type nodeQueue []*node
+
+func (q nodeQueue) Len() int { return len(q) }
+func (q nodeQueue) Less(i, j int) bool {
+	if q[i].bound != q[j].bound {
+		return q[i].bound < q[j].bound
+	}
+	if q[i].depth != q[j].depth {
+		return q[i].depth > q[j].depth
+	}
+	return q[i].seq > q[j].seq
+}
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(*node)) }
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// search holds the state of a single Optimize call. The objective is always minimized
+// internally, values are multiplied by the model sense when they leave the search.
+type search struct {
+	m         *Model
+	lp        *lp
+	cost      []float64
+	integer   []bool
+	lazy      []constraint
+	incumbent []float64
+	incObj    float64
+	queue     nodeQueue
+	nodes     int
+	seq       int
+	startTime time.Time
+	bound     float64 // bound of the node currently processed
+	pruned    float64 // the least bound of the nodes pruned by the gap tolerances, which the search never proved
+	lazyAdded bool
+}
+
+type callbackData struct {
+	s         *search
+	where     int32
+	sol       []float64 // candidate for CB_MIPSOL, relaxation for CB_MIPNODE
+	obj       float64
+	lazy      []constraint
+	solutions [][]float64 // the heuristic solutions of a CB_MIPNODE, that are tried after it returned
+}
+
+func (m *Model) Optimize() error {
+	startTime := time.Now()
+	n := len(m.vars)
+	m.status = mip.LOADED
+	m.solCount = 0
+	m.x = nil
+	//a Terminate from before the start stops this solve, so the flag is only cleared when it's done
+	defer atomic.StoreInt32(&m.terminate, 0)
+	m.setupLogger()
+
+	s := &search{m: m, startTime: startTime, incObj: math.Inf(1), bound: math.Inf(-1), pruned: math.Inf(1)}
+	s.cost = make([]float64, n)
+	s.integer = make([]bool, n)
+	lb := make([]float64, n)
+	ub := make([]float64, n)
+	for j, v := range m.vars {
+		s.cost[j] = float64(m.sense) * v.obj
+		s.integer[j] = v.vtype != mip.CONTINUOUS
+		lb[j], ub[j] = v.lb, v.ub
+		if s.integer[j] {
+			lb[j], ub[j] = math.Ceil(lb[j]-intTol), math.Floor(ub[j]+intTol)
+		}
+		if lb[j] > ub[j] {
+			m.finish(s, mip.INFEASIBLE)
+			return nil
+		}
+	}
+	s.lp = newLP(s.cost, lb, ub)
+	for _, c := range m.constrs {
+		s.lp.addRow(c.dense(n), c.sense, c.rhs)
+	}
+	m.logger.Printf("Optimize a model with %d rows, %d columns", len(m.constrs), n)
+
+	if len(m.start) == n {
+		if _, ok := s.trySolution(m.start); ok {
+			m.logger.Printf("Loaded MIP start with objective %g", float64(m.sense)*s.incObj)
+		}
+	}
+
+	heap.Push(&s.queue, &node{lb: lb, ub: ub, bound: math.Inf(-1)})
+	status := mip.OPTIMAL
+	for s.queue.Len() > 0 {
+		if reason := s.limitReached(); reason != 0 {
+			status = reason
+			break
+		}
+		if s.queue[0].bound >= s.cutoff() {
+			// every open node is worse than the incumbent within the gap tolerances
+			s.prune(s.queue[0].bound)
+			s.queue = s.queue[:0]
+			break
+		}
+		nd := heap.Pop(&s.queue).(*node)
+		s.processNode(nd)
+		s.nodes++
+		s.mipCallback()
+		if s.nodes%logFreq == 0 {
+			m.logger.Printf("%8d nodes, %8d open, incumbent %12g, bound %12g, %.1fs", s.nodes, s.queue.Len(), float64(m.sense)*s.incObj, float64(m.sense)*s.globalBound(), time.Since(startTime).Seconds())
+		}
+	}
+	if status == mip.OPTIMAL && s.incumbent == nil {
+		status = mip.INFEASIBLE
+	} else if status == mip.OPTIMAL && s.unbounded() {
+		status = mip.UNBOUNDED
+	}
+	m.finish(s, status)
+	return nil
+}
+
+// unbounded reports whether the incumbent only stopped at the finite replacement of an infinite bound
+func (s *search) unbounded() bool {
+	for j, v := range s.m.vars {
+		x := s.incumbent[j]
+		if (math.IsInf(v.ub, 1) && x >= bigBound/2) || (math.IsInf(v.lb, -1) && x <= -bigBound/2) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Model) setupLogger() {
+	var writers []io.Writer
+	if m.env.logW != nil {
+		writers = append(writers, m.env.logW)
+	}
+	if m.params[mip.INT_PAR_LOGTOCONSOLE] != 0 {
+		writers = append(writers, os.Stdout)
+	}
+	if len(writers) == 0 {
+		m.logger = log.New(ioutil.Discard, "", 0)
+		return
+	}
+	m.logger = log.New(io.MultiWriter(writers...), "bnc: ", log.LstdFlags)
+}
+
+func (m *Model) finish(s *search, status int32) {
+	m.status = status
+	m.runtime = time.Since(s.startTime).Seconds()
+	m.nodeCount = float64(s.nodes)
+	if s.incumbent != nil && status != mip.UNBOUNDED {
+		m.solCount = 1
+		m.x = s.incumbent
+		m.objVal = float64(m.sense) * s.incObj
+	}
+	switch {
+	case status == mip.INFEASIBLE:
+		m.objBound = float64(m.sense) * math.Inf(1)
+	case status == mip.UNBOUNDED:
+		m.objBound = float64(m.sense) * math.Inf(-1)
+	default:
+		m.objBound = float64(m.sense) * s.globalBound()
+	}
+	m.logger.Printf("Explored %d nodes in %.2f seconds, status %d, objective %g, bound %g", s.nodes, m.runtime, m.status, m.objVal, m.objBound)
+}
+
+func (s *search) limitReached() int32 {
+	if atomic.LoadInt32(&s.m.terminate) == 1 {
+		return mip.INTERRUPTED
+	}
+	if time.Since(s.startTime).Seconds() >= s.m.params[mip.DBL_PAR_TIMELIMIT] {
+		return mip.TIME_LIMIT
+	}
+	if float64(s.nodes) >= s.m.params[mip.DBL_PAR_NODELIMIT] {
+		return mip.NODE_LIMIT
+	}
+	return 0
+}
+
+// cutoff is the value a node bound has to stay below to be worth exploring
+func (s *search) cutoff() float64 {
+	if s.incumbent == nil {
+		return math.Inf(1)
+	}
+	tol := math.Max(s.m.params[mip.DBL_PAR_MIPGAPABS], s.m.params[mip.DBL_PAR_MIPGAP]*math.Abs(s.incObj))
+	return s.incObj - math.Max(tol, 1e-9)
+}
+
+func (s *search) globalBound() float64 {
+	bound := math.Min(s.incObj, s.pruned)
+	if s.queue.Len() > 0 && s.queue[0].bound < bound {
+		bound = s.queue[0].bound
+	}
+	if s.bound < bound {
+		bound = s.bound
+	}
+	return bound
+}
+
+// prune remembers the bound of a node left out only because of the gap tolerances
+func (s *search) prune(bound float64) {
+	if bound < s.incObj {
+		s.pruned = math.Min(s.pruned, bound)
+	}
+}
+
+func (s *search) processNode(nd *node) {
+	s.bound = nd.bound
+	defer func() { s.bound = math.Inf(1) }()
+	s.lp.setBounds(nd.lb, nd.ub)
+	for {
+		s.lazyAdded = false
+		if s.lp.optimize() != lpOptimal {
+			// infeasible, or numerically hopeless which we treat the same way
+			return
+		}
+		z := s.lp.objective()
+		if z >= s.cutoff() {
+			s.prune(z)
+			return
+		}
+		s.bound = math.Max(nd.bound, z)
+		x := append([]float64{}, s.lp.x[:s.lp.n]...)
+		branch, frac := -1, 0.0
+		for j, isInt := range s.integer {
+			if !isInt {
+				continue
+			}
+			f := math.Abs(x[j] - math.Round(x[j]))
+			if f > intTol && f > frac {
+				branch, frac = j, f
+			}
+		}
+		if branch < 0 {
+			if _, ok := s.trySolution(x); !ok && !s.lazyAdded {
+				s.m.logger.Printf("Dropped the integral relaxation with objective %g at node %d, which violates the rows by more than the tolerance", float64(s.m.sense)*z, s.nodes)
+			}
+			if s.lazyAdded {
+				continue
+			}
+			return
+		}
+
+		s.callback(mip.CB_MIPNODE, x, z)
+		if s.lazyAdded || z >= s.cutoff() {
+			// a heuristic solution brought new lazy constraints or a better incumbent
+			if z >= s.cutoff() {
+				s.prune(z)
+				return
+			}
+			continue
+		}
+
+		down := &node{lb: append([]float64{}, nd.lb...), ub: append([]float64{}, nd.ub...), bound: s.bound, depth: nd.depth + 1}
+		up := &node{lb: append([]float64{}, nd.lb...), ub: append([]float64{}, nd.ub...), bound: s.bound, depth: nd.depth + 1}
+		down.ub[branch] = math.Floor(x[branch])
+		up.lb[branch] = math.Ceil(x[branch])
+		s.seq++
+		down.seq = s.seq
+		s.seq++
+		up.seq = s.seq
+		heap.Push(&s.queue, down)
+		heap.Push(&s.queue, up)
+		return
+	}
+}
+
+// trySolution checks a full assignment against the bounds, the rows and the lazy
+// constraints, runs the MIPSOL callback on it and makes it the incumbent if it survives.
+func (s *search) trySolution(x []float64) (float64, bool) {
+	cand, obj, ok := s.candidate(x)
+	if !ok {
+		return 0, false
+	}
+	lazy := s.callback(mip.CB_MIPSOL, cand, obj)
+	for _, c := range lazy {
+		if c.violation(cand) > feasTol {
+			return 0, false
+		}
+	}
+	if obj < s.incObj {
+		s.incObj = obj
+		s.incumbent = cand
+		s.m.logger.Printf("New incumbent with objective %g found after %d nodes", float64(s.m.sense)*obj, s.nodes)
+	}
+	return float64(s.m.sense) * obj, true
+}
+
+// candidate is the assignment with its objective, if it satisfies the bounds, the rows and the lazy constraints
+// known so far. The integers are rounded, unless only the values within the tolerance satisfy the rows.
+func (s *search) candidate(x []float64) ([]float64, float64, bool) {
+	cand := append([]float64{}, x...)
+	for j := range s.m.vars {
+		if s.integer[j] {
+			cand[j] = math.Round(cand[j])
+			if math.Abs(cand[j]-x[j]) > intTol {
+				return nil, 0, false
+			}
+		}
+	}
+	if !s.feasible(cand) {
+		copy(cand, x)
+		if !s.feasible(cand) {
+			return nil, 0, false
+		}
+	}
+	obj := 0.0
+	for j := range cand {
+		obj += s.cost[j] * cand[j]
+	}
+	return cand, obj, true
+}
+
+// feasible checks the assignment against the bounds, the rows and the lazy constraints
+func (s *search) feasible(x []float64) bool {
+	for j, v := range s.m.vars {
+		if x[j] < v.lb-feasTol || x[j] > v.ub+feasTol {
+			return false
+		}
+	}
+	for _, c := range s.m.constrs {
+		if c.violation(x) > feasTol {
+			return false
+		}
+	}
+	for _, c := range s.lazy {
+		if c.violation(x) > feasTol {
+			return false
+		}
+	}
+	return true
+}
+
+// callback calls the user callback and adds the lazy constraints it produced to the LP
+func (s *search) callback(where int32, sol []float64, obj float64) []constraint {
+	if s.m.cb == nil {
+		return nil
+	}
+	cbd := &callbackData{s: s, where: where, sol: sol, obj: obj}
+	s.m.cb(s.m, cbd, where, s.m.usrdata)
+	n := len(s.m.vars)
+	for _, c := range cbd.lazy {
+		s.lazy = append(s.lazy, c)
+		s.lp.addRow(c.dense(n), c.sense, c.rhs)
+		s.lazyAdded = true
+	}
+	//the callback isn't called again while it runs, so the heuristic solutions get their MIPSOL callback only now
+	for _, solution := range cbd.solutions {
+		s.trySolution(solution)
+	}
+	return cbd.lazy
+}
+
+func (s *search) mipCallback() {
+	if s.m.cb != nil {
+		s.m.cb(s.m, &callbackData{s: s, where: mip.CB_MIP}, mip.CB_MIP, s.m.usrdata)
+	}
+}
+
+func (c *callbackData) GetDbl(what int32) (float64, error) {
+	s := c.s
+	sense := float64(s.m.sense)
+	switch what {
+	case mip.CB_MIP_OBJBST, mip.CB_MIPSOL_OBJBST, mip.CB_MIPNODE_OBJBST:
+		if s.incumbent == nil {
+			return sense * mip.INFINITY, nil
+		}
+		return sense * s.incObj, nil
+	case mip.CB_MIP_OBJBND, mip.CB_MIPSOL_OBJBND, mip.CB_MIPNODE_OBJBND:
+		return sense * s.globalBound(), nil
+	case mip.CB_MIP_NODCNT, mip.CB_MIPSOL_NODCNT, mip.CB_MIPNODE_NODCNT:
+		return float64(s.nodes), nil
+	case mip.CB_MIPSOL_OBJ:
+		if c.where == mip.CB_MIPSOL {
+			return sense * c.obj, nil
+		}
+	case mip.CB_MIPNODE_STATUS:
+		if c.where == mip.CB_MIPNODE {
+			return float64(mip.OPTIMAL), nil
+		}
+	}
+	return 0, fmt.Errorf("bnc: %d cannot be queried in callback %d", what, c.where)
+}
+
+func (c *callbackData) GetDblArray(what int32, length int) ([]float64, error) {
+	if (what == mip.CB_MIPSOL_SOL && c.where == mip.CB_MIPSOL) || (what == mip.CB_MIPNODE_REL && c.where == mip.CB_MIPNODE) {
+		if length > len(c.sol) {
+			return nil, fmt.Errorf("bnc: requested %d values but the model has %d variables", length, len(c.sol))
+		}
+		return append([]float64{}, c.sol[:length]...), nil
+	}
+	return nil, fmt.Errorf("bnc: %d cannot be queried in callback %d", what, c.where)
+}
+
+func (c *callbackData) Lazy(ind []int32, val []float64, sense int8, rhs float64) error {
+	if c.where != mip.CB_MIPSOL {
+		return errors.New("bnc: lazy constraints can only be added in the MIPSOL callback")
+	}
+	for _, i := range ind {
+		if i < 0 || int(i) >= len(c.s.m.vars) {
+			return fmt.Errorf("bnc: lazy constraint references unknown variable %d", i)
+		}
+	}
+	c.lazy = append(c.lazy, constraint{ind: append([]int32{}, ind...), val: append([]float64{}, val...), sense: sense, rhs: rhs})
+	return nil
+}
+
+func (c *callbackData) Solution(solution []float64) (float64, error) {
+	if c.where != mip.CB_MIPNODE {
+		return 0, errors.New("bnc: solutions can only be set in the MIPNODE callback")
+	}
+	if len(solution) != len(c.s.m.vars) {
+		return 0, fmt.Errorf("bnc: solution has %d values but the model has %d variables", len(solution), len(c.s.m.vars))
+	}
+	_, obj, ok := c.s.candidate(solution)
+	if !ok {
+		return mip.INFINITY, nil
+	}
+	c.solutions = append(c.solutions, append([]float64{}, solution...))
+	return float64(c.s.m.sense) * obj, nil
+}
+
+func (c constraint) dense(n int) []float64 {
+	row := make([]float64, n)
+	for i, ind := range c.ind {
+		row[ind] += c.val[i]
+	}
+	return row
+}
+
+func (c constraint) violation(x []float64) float64 {
+	act := 0.0
+	for i, ind := range c.ind {
+		act += c.val[i] * x[ind]
+	}
+	switch c.sense {
+	case mip.LESS_EQUAL:
+		return act - c.rhs
+	case mip.GREATER_EQUAL:
+		return c.rhs - act
+	}
+	return math.Abs(act - c.rhs)
+}
@@ -0,0 +1,6 @@
+/* Copyright 2021, Arkadiusz Zarychta, arkadiusz.zarychta@h-brs.de */
+
+// Package grb implements the mip interfaces on top of the Gurobi binding. Importing it
+// registers the backend under the name "gurobi". Building with the tag nogurobi leaves the
+// package empty, so the commands can be built on machines without a Gurobi installation.
+package grb
@@ -0,0 +1,174 @@
+//go:build !nogurobi
+// +build !nogurobi
+
+/* Copyright 2021, Arkadiusz Zarychta, arkadiusz.zarychta@h-brs.de */
+
+package grb
+
+import (
+	"git.solver4all.com/azaryc2s/gorobi/gurobi"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"runtime/debug"
+)
+
+const Name = "gurobi"
+
+//the module of the gurobi bindings, which are built against one version of the gurobi library
+const gorobiModule = "git.solver4all.com/azaryc2s/gorobi/gurobi"
+
+func init() {
+	mip.Register(Name, 10, LoadEnv, params)
+}
+
+//the parameters, that can be set in an environment, with whether their value is an integer
+var params = map[string]bool{
+	mip.INT_PAR_THREADS:         true,
+	mip.INT_PAR_LAZYCONSTRAINTS: true,
+	mip.INT_PAR_LOGTOCONSOLE:    true,
+	mip.INT_PAR_SEED:            true,
+	"OutputFlag":                true,
+	"MIPFocus":                  true,
+	"Presolve":                  true,
+	"Cuts":                      true,
+	"Method":                    true,
+	"SolutionLimit":             true,
+	mip.DBL_PAR_TIMELIMIT:       false,
+	mip.DBL_PAR_MIPGAP:          false,
+	mip.DBL_PAR_MIPGAPABS:       false,
+	mip.DBL_PAR_NODELIMIT:       false,
+	"Heuristics":                false,
+	"FeasibilityTol":            false,
+	"IntFeasTol":                false,
+	"OptimalityTol":             false,
+}
+
+type env struct {
+	env *gurobi.Env
+}
+
+type model struct {
+	model *gurobi.Model
+}
+
+type callbackData struct {
+	cbdata gurobi.CPVoid
+	where  int32
+}
+
+func LoadEnv(logFile string) (mip.Env, error) {
+	e, err := gurobi.LoadEnv(logFile)
+	if err != nil {
+		return nil, err
+	}
+	return &env{env: e}, nil
+}
+
+func (e *env) NewModel(name string) (mip.Model, error) {
+	m, err := e.env.NewModel(name, 0, nil, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &model{model: m}, nil
+}
+
+func (e *env) SetIntParam(name string, value int32) error {
+	return e.env.SetIntParam(name, value)
+}
+
+func (e *env) GetIntParam(name string) (int32, error) {
+	return e.env.GetIntParam(name)
+}
+
+func (e *env) SetDblParam(name string, value float64) error {
+	return e.env.SetDblParam(name, value)
+}
+
+//the version of the bindings from the build information of the binary
+func (e *env) Version() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == gorobiModule {
+				return "gorobi " + dep.Version
+			}
+		}
+	}
+	return "unknown"
+}
+
+func (e *env) Free() {
+	e.env.Free()
+}
+
+func (m *model) AddVar(obj, lb, ub float64, vtype int8, name string) error {
+	return m.model.AddVar(nil, nil, obj, lb, ub, vtype, name)
+}
+
+func (m *model) AddConstr(ind []int32, val []float64, sense int8, rhs float64, name string) error {
+	return m.model.AddConstr(ind, val, sense, rhs, name)
+}
+
+func (m *model) SetIntAttr(name string, value int32) error {
+	return m.model.SetIntAttr(name, value)
+}
+
+func (m *model) GetIntAttr(name string) (int32, error) {
+	return m.model.GetIntAttr(name)
+}
+
+func (m *model) GetDblAttr(name string) (float64, error) {
+	return m.model.GetDblAttr(name)
+}
+
+func (m *model) GetDblAttrArray(name string, start, length int32) ([]float64, error) {
+	return m.model.GetDblAttrArray(name, start, length)
+}
+
+func (m *model) SetDblAttrArray(name string, start int32, values []float64) error {
+	return m.model.SetDblAttrArray(name, start, values)
+}
+
+func (m *model) SetDblAttrElem(name string, element int32, value float64) error {
+	return m.model.SetDblAttrElem(name, element, value)
+}
+
+func (m *model) SetIntParam(name string, value int32) error {
+	return m.model.SetIntParam(name, value)
+}
+
+func (m *model) SetCallbackFunc(fn mip.CallbackFunc, usrdata interface{}) error {
+	return m.model.SetCallbackFuncGo(func(_ *gurobi.Model, cbdata gurobi.CPVoid, where int32, usrdata interface{}) int32 {
+		return fn(m, &callbackData{cbdata: cbdata, where: where}, where, usrdata)
+	}, usrdata)
+}
+
+func (m *model) Optimize() error {
+	return m.model.Optimize()
+}
+
+func (m *model) Terminate() {
+	m.model.Terminate()
+}
+
+func (m *model) Write(fileName string) error {
+	return m.model.Write(fileName)
+}
+
+func (m *model) Free() {
+	m.model.Free()
+}
+
+func (c *callbackData) GetDbl(what int32) (float64, error) {
+	return gurobi.CbGetDbl(c.cbdata, c.where, what)
+}
+
+func (c *callbackData) GetDblArray(what int32, length int) ([]float64, error) {
+	return gurobi.CbGetDblArray(c.cbdata, c.where, what, length)
+}
+
+func (c *callbackData) Lazy(ind []int32, val []float64, sense int8, rhs float64) error {
+	return gurobi.CbLazy(c.cbdata, len(ind), ind, val, sense, rhs)
+}
+
+func (c *callbackData) Solution(solution []float64) (float64, error) {
+	return gurobi.CbSolution(c.cbdata, solution)
+}
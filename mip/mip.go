@@ -0,0 +1,273 @@
+/* Copyright 2021, Arkadiusz Zarychta, arkadiusz.zarychta@h-brs.de */
+
+// Package mip defines the solver-agnostic interface every model in this repository
+// is built against. The constants follow the numbering of the Gurobi C API, so a
+// backend that wraps Gurobi can pass them through unchanged, while other backends
+// only need to understand the subset listed here.
+package mip
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// INFINITY is returned for values that do not exist, e.g. the best objective before a solution was found
+const INFINITY = 1e100
+
+/* Constraint senses */
+const (
+	LESS_EQUAL    int8 = '<'
+	GREATER_EQUAL int8 = '>'
+	EQUAL         int8 = '='
+)
+
+/* Variable types */
+const (
+	CONTINUOUS int8 = 'C'
+	BINARY     int8 = 'B'
+	INTEGER    int8 = 'I'
+)
+
+/* Objective senses */
+const (
+	MINIMIZE int32 = 1
+	MAXIMIZE int32 = -1
+)
+
+/* Optimization status codes */
+const (
+	LOADED          int32 = 1
+	OPTIMAL         int32 = 2
+	INFEASIBLE      int32 = 3
+	INF_OR_UNBD     int32 = 4
+	UNBOUNDED       int32 = 5
+	CUTOFF          int32 = 6
+	ITERATION_LIMIT int32 = 7
+	NODE_LIMIT      int32 = 8
+	TIME_LIMIT      int32 = 9
+	SOLUTION_LIMIT  int32 = 10
+	INTERRUPTED     int32 = 11
+	NUMERIC         int32 = 12
+)
+
+/* Callback codes - where the callback was called from */
+const (
+	CB_POLLING int32 = 0
+	CB_MIP     int32 = 3
+	CB_MIPSOL  int32 = 4
+	CB_MIPNODE int32 = 5
+)
+
+/* Callback codes - what can be queried inside the callback */
+const (
+	CB_MIP_OBJBST     int32 = 3000
+	CB_MIP_OBJBND     int32 = 3001
+	CB_MIP_NODCNT     int32 = 3002
+	CB_MIPSOL_SOL     int32 = 4001
+	CB_MIPSOL_OBJ     int32 = 4002
+	CB_MIPSOL_OBJBST  int32 = 4003
+	CB_MIPSOL_OBJBND  int32 = 4004
+	CB_MIPSOL_NODCNT  int32 = 4005
+	CB_MIPNODE_STATUS int32 = 5001
+	CB_MIPNODE_REL    int32 = 5002
+	CB_MIPNODE_OBJBST int32 = 5003
+	CB_MIPNODE_OBJBND int32 = 5004
+	CB_MIPNODE_NODCNT int32 = 5005
+)
+
+/* Attributes */
+const (
+	INT_ATTR_STATUS     = "Status"
+	INT_ATTR_SOLCOUNT   = "SolCount"
+	INT_ATTR_MODELSENSE = "ModelSense"
+	DBL_ATTR_OBJVAL     = "ObjVal"
+	DBL_ATTR_OBJBOUND   = "ObjBound"
+	DBL_ATTR_X          = "X"
+	DBL_ATTR_START      = "Start"
+	DBL_ATTR_LB         = "LB"
+	DBL_ATTR_UB         = "UB"
+	DBL_ATTR_NODECOUNT  = "NodeCount"
+	DBL_ATTR_MIPGAP     = "MIPGap"
+	DBL_ATTR_RUNTIME    = "Runtime"
+)
+
+/* Parameters */
+const (
+	INT_PAR_THREADS         = "Threads"
+	INT_PAR_LAZYCONSTRAINTS = "LazyConstraints"
+	INT_PAR_LOGTOCONSOLE    = "LogToConsole"
+	INT_PAR_SEED            = "Seed"
+	DBL_PAR_TIMELIMIT       = "TimeLimit"
+	DBL_PAR_MIPGAP          = "MIPGap"
+	DBL_PAR_MIPGAPABS       = "MIPGapAbs"
+	DBL_PAR_NODELIMIT       = "NodeLimit"
+)
+
+// Env is a solver environment. Models are created from it and inherit its parameters.
+type Env interface {
+	NewModel(name string) (Model, error)
+	SetIntParam(name string, value int32) error
+	GetIntParam(name string) (int32, error)
+	SetDblParam(name string, value float64) error
+	// Version returns the version of the solver behind the backend
+	Version() string
+	Free()
+}
+
+// Model is a mixed integer program. Variables and constraints are referenced by the
+// index in the order they were added.
+type Model interface {
+	AddVar(obj, lb, ub float64, vtype int8, name string) error
+	AddConstr(ind []int32, val []float64, sense int8, rhs float64, name string) error
+
+	SetIntAttr(name string, value int32) error
+	GetIntAttr(name string) (int32, error)
+	GetDblAttr(name string) (float64, error)
+	GetDblAttrArray(name string, start, length int32) ([]float64, error)
+	SetDblAttrArray(name string, start int32, values []float64) error
+	SetDblAttrElem(name string, element int32, value float64) error
+	SetIntParam(name string, value int32) error
+
+	SetCallbackFunc(fn CallbackFunc, usrdata interface{}) error
+	Optimize() error
+	// Terminate asks a running Optimize to stop as soon as possible with the status INTERRUPTED. It can be called
+	// from another goroutine. A call just before Optimize starts may stop it as well.
+	Terminate()
+	Write(fileName string) error
+	Free()
+}
+
+// CallbackFunc is called by the backend during Optimize. 'where' is one of the CB_* codes
+// and 'cbdata' gives access to the information available at that point.
+type CallbackFunc func(model Model, cbdata CallbackData, where int32, usrdata interface{}) int32
+
+// CallbackData is only valid during the call of the CallbackFunc it was passed to.
+type CallbackData interface {
+	GetDbl(what int32) (float64, error)
+	GetDblArray(what int32, length int) ([]float64, error)
+	// Lazy adds a lazy constraint. Only allowed for where == CB_MIPSOL.
+	Lazy(ind []int32, val []float64, sense int8, rhs float64) error
+	// Solution proposes a heuristic solution and returns its objective if it was accepted. The
+	// callback isn't called again before it returns, so a MIPSOL callback may still reject it later.
+	Solution(solution []float64) (float64, error)
+}
+
+// EnvLoader creates a new environment logging to the given file.
+type EnvLoader func(logFile string) (Env, error)
+
+// BackendEnvVar names the environment variable that selects the backend if DefaultBackend is not set.
+const BackendEnvVar = "OP_MIP_BACKEND"
+
+type backend struct {
+	loader   EnvLoader
+	priority int
+	params   map[string]bool
+}
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]backend)
+
+	// DefaultBackend is used by LoadEnv. If empty, the backend named in $OP_MIP_BACKEND or else
+	// the registered backend with the highest priority is used.
+	DefaultBackend string
+)
+
+// Register makes a backend available under the given name. It is meant to be called from
+// the init function of the backend package. The params are the parameters, that can be set in
+// its environments, with whether their value is an integer.
+func Register(name string, priority int, loader EnvLoader, params map[string]bool) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if _, ok := backends[name]; ok {
+		panic(fmt.Sprintf("mip: backend %s registered twice", name))
+	}
+	backends[name] = backend{loader: loader, priority: priority, params: params}
+}
+
+// Backends returns the names of all registered backends.
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	var names []string
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SelectedBackend returns the name of the backend LoadEnv uses
+func SelectedBackend() string {
+	if DefaultBackend != "" {
+		return DefaultBackend
+	}
+	if name := os.Getenv(BackendEnvVar); name != "" {
+		return name
+	}
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	selected, priority := "", 0
+	for name, b := range backends {
+		if selected == "" || b.priority > priority || (b.priority == priority && name < selected) {
+			selected, priority = name, b.priority
+		}
+	}
+	return selected
+}
+
+// LoadEnv creates a new environment of the selected backend.
+func LoadEnv(logFile string) (Env, error) {
+	return LoadBackendEnv(SelectedBackend(), logFile)
+}
+
+// LoadBackendEnv creates a new environment of the named backend.
+func LoadBackendEnv(name string, logFile string) (Env, error) {
+	b, err := lookupBackend(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.loader(logFile)
+}
+
+func lookupBackend(name string) (backend, error) {
+	backendsMu.RLock()
+	b, ok := backends[name]
+	backendsMu.RUnlock()
+	if !ok {
+		if name == "" {
+			return backend{}, errors.New("mip: no backend registered")
+		}
+		return backend{}, fmt.Errorf("mip: unknown backend %s (available: %v)", name, Backends())
+	}
+	return b, nil
+}
+
+//the parameters of the backend with whether their value is an integer
+func backendParams(name string) (map[string]bool, error) {
+	b, err := lookupBackend(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.params, nil
+}
+
+func Int32Slice(a []int) []int32 {
+	res := make([]int32, len(a))
+	for i := 0; i < len(a); i++ {
+		res[i] = int32(a[i])
+	}
+	return res
+}
+
+// Matrix reshapes the values of n*n variables (row-major) into a n x n matrix
+func Matrix(a []float64, n int) [][]float64 {
+	res := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		res[i] = a[i*n : (i+1)*n]
+	}
+	return res
+}
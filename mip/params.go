@@ -0,0 +1,127 @@
+/* Copyright 2021, Arkadiusz Zarychta, arkadiusz.zarychta@h-brs.de */
+
+package mip
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Param is the value of a parameter as it is written in a parameter file
+type Param struct {
+	Name    string
+	Value   string
+	integer bool
+}
+
+// Params are set in an environment in their order, so a later value of a parameter replaces an earlier one
+type Params []Param
+
+// ParseParams reads the parameters of the backend from lines of key=value. Empty lines and the lines starting with #
+// are skipped. Every parameter has to be one of the backend with a value of its type.
+func ParseParams(backend string, text string) (Params, error) {
+	var res Params
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("mip: line %d of the parameters is not key=value: %s", line, entry)
+		}
+		param := Param{Name: strings.TrimSpace(parts[0]), Value: strings.TrimSpace(parts[1])}
+		if err := param.validate(backend); err != nil {
+			return nil, fmt.Errorf("mip: line %d of the parameters: %s", line, err.Error())
+		}
+		res = append(res, param)
+	}
+	return res, scanner.Err()
+}
+
+// Add appends the parameter after checking its name and value for the backend
+func (p *Params) Add(backend string, name string, value string) error {
+	param := Param{Name: name, Value: value}
+	if err := param.validate(backend); err != nil {
+		return fmt.Errorf("mip: %s", err.Error())
+	}
+	*p = append(*p, param)
+	return nil
+}
+
+// Has reports whether the parameter is set
+func (p Params) Has(name string) bool {
+	for _, param := range p {
+		if param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply sets the parameters in the environment
+func (p Params) Apply(env Env) error {
+	for _, param := range p {
+		var err error
+		if param.integer {
+			var v int64
+			v, err = strconv.ParseInt(param.Value, 10, 32)
+			if err == nil {
+				err = env.SetIntParam(param.Name, int32(v))
+			}
+		} else {
+			var v float64
+			v, err = strconv.ParseFloat(param.Value, 64)
+			if err == nil {
+				err = env.SetDblParam(param.Name, v)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("mip: setting %s=%s: %s", param.Name, param.Value, err.Error())
+		}
+	}
+	return nil
+}
+
+func (p *Param) validate(backend string) error {
+	params, err := backendParams(backend)
+	if err != nil {
+		return err
+	}
+	isInt, ok := params[p.Name]
+	if !ok {
+		return fmt.Errorf("unknown parameter %s of %s (known: %s)", p.Name, backend, strings.Join(paramNames(params), ", "))
+	}
+	p.integer = isInt
+	if isInt {
+		_, err = strconv.ParseInt(p.Value, 10, 32)
+	} else {
+		_, err = strconv.ParseFloat(p.Value, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %s of %s", p.Value, p.Name)
+	}
+	return nil
+}
+
+// KnownParams returns the names of the parameters, that can be set in the environments of the backend
+func KnownParams(backend string) ([]string, error) {
+	params, err := backendParams(backend)
+	if err != nil {
+		return nil, err
+	}
+	return paramNames(params), nil
+}
+
+func paramNames(params map[string]bool) []string {
+	var names []string
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
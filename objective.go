@@ -0,0 +1,39 @@
+package op
+
+// The objectives the node/edge model of the OP can be solved for. The OP collects the largest prize within the travel
+// budget. The prize-collecting TSP (PCTSP) looks for the shortest tour, that collects at least the minimum prize, and
+// the profitable tour problem (PTP) maximizes the prize minus lambda times the length. Both have no travel budget.
+const (
+	OBJ_OP    = "OP"
+	OBJ_PCTSP = "PCTSP"
+	OBJ_PTP   = "PTP"
+)
+
+// GetObjective returns the objective the solution was computed for. Solutions without one are OP solutions.
+func (sol *Solution) GetObjective() string {
+	if sol.Objective == "" {
+		return OBJ_OP
+	}
+	return sol.Objective
+}
+
+// HasTravelBudget returns true if the routes of the solution have to keep the travel budget of the instance
+func (sol *Solution) HasTravelBudget() bool {
+	return sol.GetObjective() == OBJ_OP
+}
+
+// Minimizes returns true if a smaller objective value of the solution is better
+func (sol *Solution) Minimizes() bool {
+	return sol.GetObjective() == OBJ_PCTSP
+}
+
+// ObjectiveValue returns the value of the objective of the solution for routes with the given prize and length
+func (sol *Solution) ObjectiveValue(prize, length float64) float64 {
+	switch sol.GetObjective() {
+	case OBJ_PCTSP:
+		return length
+	case OBJ_PTP:
+		return prize - sol.Lambda*length
+	}
+	return prize
+}
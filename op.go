@@ -2,7 +2,7 @@ package op
 
 import (
 	"fmt"
-	"git.solver4all.com/azaryc2s/gorobi/gurobi"
+	"git.solver4all.com/azaryc2s/op/mip"
 	"log"
 )
 
@@ -13,29 +13,62 @@ type SubData struct {
 	varCount int
 	startX   int
 	startY   int
+	depot    int
 }
 
-func SolveOP(d [][]int, p []int, tmax int) (tour []int, score int, length int, optimstatus int32, lb int, err error) {
-	// Create environment
-	env, err := gurobi.LoadEnv("op-lp-sym.log")
-	if err != nil {
-		log.Printf("Error: %s\n", err.Error())
-		return nil, -1, -1, -1, -1, err
-	}
-	defer env.Free()
-	env.SetIntParam("LogToConsole", int32(0))
-	defer env.SetIntParam("LogToConsole", int32(1))
+// SolveOP solves the OP for a closed tour starting and ending at node 0. The service times of the visited nodes count
+// against tmax together with the length of the tour. They may be nil.
+func SolveOP(env mip.Env, d [][]float64, p []float64, service []float64, tmax float64) (tour []int, score float64, length float64, optimstatus int32, lb float64, err error) {
+	return SolveOPPath(env, d, p, service, tmax, 0, 0)
+}
 
+// SolveOPPath solves the OP for a path from the start to the end depot. If both are the same, it's a closed tour.
+// The returned tour begins at the start depot and ends at the end depot. The route ends with the arrival at the end
+// depot, so its service time doesn't count against tmax. Nodes and edges, that no route within tmax can use, are
+// removed from the model beforehand. The model is created in env with its parameters and doesn't log to the console.
+func SolveOPPath(env mip.Env, d [][]float64, p []float64, service []float64, tmax float64, start int, end int) (tour []int, score float64, length float64, optimstatus int32, lb float64, err error) {
 	N := len(d)
+	reach := NewReachability(Matrix(d), service, tmax, start, end)
+	if nodes := reach.UsableNodes(); len(nodes) < N {
+		//the nodes, that no route within tmax can visit, are left out of the model
+		subD, subP, subService := make([][]float64, len(nodes)), make([]float64, len(nodes)), make([]float64, len(nodes))
+		subStart, subEnd := 0, 0
+		for k, i := range nodes {
+			subD[k] = make([]float64, len(nodes))
+			for l, j := range nodes {
+				subD[k][l] = d[i][j]
+			}
+			subP[k] = p[i]
+			subService[k] = ServiceTime(service, i)
+			if i == start {
+				subStart = k
+			}
+			if i == end {
+				subEnd = k
+			}
+		}
+		tour, score, length, optimstatus, lb, err = SolveOPPath(env, subD, subP, subService, tmax, subStart, subEnd)
+		for k := range tour {
+			tour[k] = nodes[tour[k]]
+		}
+		return tour, score, length, optimstatus, lb, err
+	}
+	//a path is modelled as a tour, that closes it with the fixed edge between start and end which costs nothing
+	d = ClosePath(d, start, end)
 
 	/* Create an empty model */
 
-	model, err := env.NewModel("op", 0, nil, nil, nil, nil, nil)
+	model, err := env.NewModel("op")
 	if err != nil {
 		log.Println(err)
 		return nil, -1, -1, -1, -1, err
 	}
 	defer model.Free()
+	err = model.SetIntParam(mip.INT_PAR_LOGTOCONSOLE, 0)
+	if err != nil {
+		log.Println(err)
+		return nil, -1, -1, -1, -1, err
+	}
 
 	/* Add variables X_i - one for every node*/
 	//log.Println("Adding variables X_i...")
@@ -43,7 +76,7 @@ func SolveOP(d [][]int, p []int, tmax int) (tour []int, score int, length int, o
 	varCount := 0
 	for i := 0; i < N; i++ {
 		name := fmt.Sprintf("X_%d", i)
-		err = model.AddVar(nil, nil, float64(p[i]), 0.0, 1.0, gurobi.BINARY, name)
+		err = model.AddVar(p[i], 0.0, 1.0, mip.BINARY, name)
 		if err != nil {
 			log.Println(err)
 			return nil, -1, -1, -1, -1, err
@@ -56,7 +89,14 @@ func SolveOP(d [][]int, p []int, tmax int) (tour []int, score int, length int, o
 	for i := 0; i < N; i++ {
 		for j := i + 1; j < N; j++ {
 			name := fmt.Sprintf("Y_%d_%d", i, j)
-			err = model.AddVar(nil, nil, 0.0, 0.0, 1.0, gurobi.BINARY, name)
+			lb, ub := 0.0, 1.0
+			if start != end && ((i == start && j == end) || (i == end && j == start)) {
+				lb = 1.0
+			} else if !reach.EdgeUsable(i, j) {
+				//no route within tmax can use the edge
+				ub = 0.0
+			}
+			err = model.AddVar(0.0, lb, ub, mip.BINARY, name)
 			if err != nil {
 				log.Println(err)
 				return nil, -1, -1, -1, -1, err
@@ -66,22 +106,30 @@ func SolveOP(d [][]int, p []int, tmax int) (tour []int, score int, length int, o
 	}
 
 	// Change objective sense to maximization
-	err = model.SetIntAttr(gurobi.INT_ATTR_MODELSENSE, gurobi.MAXIMIZE)
+	err = model.SetIntAttr(mip.INT_ATTR_MODELSENSE, mip.MAXIMIZE)
 	if err != nil {
 		log.Printf("Error: %s\n", err.Error())
 		return nil, -1, -1, -1, -1, err
 	}
 
-	//log.Println("Creating and setting a constraint for the depot 0 to always be used")
+	//log.Println("Creating and setting a constraint for the depots to always be used")
 	{
-		ind := []int{startX}
+		ind := []int{startX + start}
 		val := []float64{1.0}
 		name := fmt.Sprintf("must_depot")
-		err = model.AddConstr(gurobi.Int32Slice(ind), val, gurobi.EQUAL, 1, name)
+		err = model.AddConstr(mip.Int32Slice(ind), val, mip.EQUAL, 1, name)
 		if err != nil {
 			log.Println("Error adding must_depot")
 			return nil, -1, -1, -1, -1, err
 		}
+		if start != end {
+			ind = []int{startX + end}
+			err = model.AddConstr(mip.Int32Slice(ind), val, mip.EQUAL, 1, "must_end_depot")
+			if err != nil {
+				log.Println("Error adding must_end_depot")
+				return nil, -1, -1, -1, -1, err
+			}
+		}
 	}
 
 	//log.Println("Creating and setting constraints for nodes to always be connected to 2 active edges")
@@ -101,7 +149,7 @@ func SolveOP(d [][]int, p []int, tmax int) (tour []int, score int, length int, o
 			}
 			ind = append(ind, int32(startX+i)) //X_i
 			val = append(val, -2.0)
-			err = model.AddConstr(ind, val, gurobi.EQUAL, 0.0, fmt.Sprintf("node_2_%d", i))
+			err = model.AddConstr(ind, val, mip.EQUAL, 0.0, fmt.Sprintf("node_2_%d", i))
 			if err != nil {
 				log.Printf("Error adding node_2_%d\n", i)
 				return nil, -1, -1, -1, -1, err
@@ -118,10 +166,16 @@ func SolveOP(d [][]int, p []int, tmax int) (tour []int, score int, length int, o
 		for i := 0; i < N; i++ {
 			for j := i + 1; j < N; j++ {
 				ind = append(ind, int32(GetEdgeIndex(i, j, N, startY)))
-				val = append(val, float64(d[i][j]))
+				val = append(val, d[i][j])
+			}
+		}
+		for i := 0; i < N; i++ {
+			if s := ServiceTime(service, i); s != 0 && (i != end || start == end) {
+				ind = append(ind, int32(startX+i))
+				val = append(val, s)
 			}
 		}
-		err = model.AddConstr(ind, val, gurobi.LESS_EQUAL, float64(tmax), "travel_budget")
+		err = model.AddConstr(ind, val, mip.LESS_EQUAL, tmax, "travel_budget")
 		if err != nil {
 			log.Printf("Error adding constraint for travel budget: %s\n", err.Error())
 			return nil, -1, -1, -1, -1, err
@@ -129,8 +183,8 @@ func SolveOP(d [][]int, p []int, tmax int) (tour []int, score int, length int, o
 	}
 
 	/* Set callback function */
-	cbData := SubData{N: N, varCount: varCount, startX: startX, startY: startY}
-	err = model.SetCallbackFuncGo(subtourelimOP, &cbData)
+	cbData := SubData{N: N, varCount: varCount, startX: startX, startY: startY, depot: start}
+	err = model.SetCallbackFunc(subtourelimOP, &cbData)
 	if err != nil {
 		log.Println(err)
 		return nil, -1, -1, -1, -1, err
@@ -138,7 +192,7 @@ func SolveOP(d [][]int, p []int, tmax int) (tour []int, score int, length int, o
 
 	/* Must set LazyConstraints parameter when using lazy constraints */
 
-	err = model.SetIntParam(gurobi.INT_PAR_LAZYCONSTRAINTS, 1)
+	err = model.SetIntParam(mip.INT_PAR_LAZYCONSTRAINTS, 1)
 	if err != nil {
 		log.Println(err)
 		return nil, -1, -1, -1, -1, err
@@ -150,38 +204,36 @@ func SolveOP(d [][]int, p []int, tmax int) (tour []int, score int, length int, o
 		log.Printf("Error: %s\n", err.Error())
 		return nil, -1, -1, -1, -1, err
 	}
-	log.Println("\n---OPTIMIZATION DONE---\n")
+	log.Print("\n---OPTIMIZATION DONE---\n")
 
 	// Capture solution information
-	optimstatus, err = model.GetIntAttr(gurobi.INT_ATTR_STATUS)
+	optimstatus, err = model.GetIntAttr(mip.INT_ATTR_STATUS)
 	if err != nil {
 		log.Printf("Error capturing solution: %s\n", err.Error())
 		return nil, -1, -1, -1, -1, err
 	}
 
-	objval, err := model.GetDblAttr(gurobi.DBL_ATTR_OBJVAL)
+	objval, err := model.GetDblAttr(mip.DBL_ATTR_OBJVAL)
 	if err != nil {
 		log.Printf("Couldn't retrieve the obj-value: %s.\n", err.Error())
 		return nil, -1, -1, -1, -1, err
 	}
-	score = int(objval + 0.5)
+	score = objval
 
-	lbF := 0.0
-	lbF, err = model.GetDblAttr(gurobi.DBL_ATTR_OBJBOUND)
+	lb, err = model.GetDblAttr(mip.DBL_ATTR_OBJBOUND)
 	if err != nil {
 		log.Printf("Couldn't retrieve the lower-bound-value: %s. ", err.Error())
 		return nil, -1, -1, -1, -1, err
 	}
-	lb = int(lbF + 0.5)
 
-	solM, err := model.GetDblAttrArray(gurobi.DBL_ATTR_X, 0, int32(varCount))
+	solM, err := model.GetDblAttrArray(mip.DBL_ATTR_X, 0, int32(varCount))
 	if err != nil {
 		log.Println(err)
 	}
 	yMat := extractIntYSolution(solM, N, startY)
 	//fmt.Printf("Found Solution: %v \n", solM)
 	solNodes := 0
-	opLength := 0
+	opLength := 0.0
 	for i := 0; i < N; i++ {
 		for j := i + 1; j < N; j++ {
 			if yMat[i][j] == 1 { //this count edges set to 1. Having n such edges, means we have n nodes to visit
@@ -190,104 +242,83 @@ func SolveOP(d [][]int, p []int, tmax int) (tour []int, score int, length int, o
 			}
 		}
 	}
-	tour = findsubtour(yMat)
+	tour = OrientRoute(findsubtours(yMat, start)[0], start, end)
 	length = opLength
 	//fmt.Printf("Found a op-tour with %d nodes and length %d: %v \n", len(tour), opLength, tour)
 	return tour, score, length, optimstatus, lb, nil
 }
 
-/* Given an integer-feasible solution 'sol', find the smallest
-   sub-tour.  Result is returned in 'tour', and length is
-   returned in 'tourlenP'. */
+/* Given an integer-feasible solution 'sol', find all sub-tours
+   through the visited nodes. The first one contains the depot. */
 
-func findsubtour(sol [][]int) (result []int) {
+func findsubtours(sol [][]int, depot int) (result [][]int) {
 	n := len(sol)
 	seen := make([]bool, n)
-	tour := make([]int, n)
-
-	start := 0
-	bestlen := n + 1
-	bestind := -1
-	i := 0
-	node := 0
-	for start < n {
-		for node = 0; node < n; node++ {
-			if !seen[node] {
-				break
-			}
+	for k := -1; k < n; k++ {
+		node := depot
+		if k >= 0 {
+			node = k
 		}
-		if node == n {
-			break
+		if seen[node] {
+			continue
 		}
-		isConnected := false
-		for leng := 0; leng < n; leng++ {
-			tour[start+leng] = node
+		var tour []int
+		for node >= 0 {
+			tour = append(tour, node)
 			seen[node] = true
-			for i = 0; i < n; i++ {
+			next := -1
+			for i := 0; i < n; i++ {
 				if sol[node][i] == 1 && !seen[i] {
-					node = i
-					isConnected = true
+					next = i
 					break
 				}
 			}
-			if i == n {
-				leng++
-				if isConnected && leng < bestlen {
-					bestlen = leng
-					bestind = start
-				}
-				start += leng
-				break
-			}
+			node = next
+		}
+		if len(tour) > 1 || k < 0 {
+			result = append(result, tour)
 		}
 	}
-
-	return tour[bestind : bestind+bestlen]
-
+	return result
 }
 
 /* Subtour elimination callback.  Whenever a feasible solution is found,
-   find the shortest subtour, and add a subtour elimination constraint
-   if that tour doesn't visit every node. */
+   find the shortest subtour without the depot, and add a subtour elimination
+   constraint for it. The constraint would be invalid for a subtour through the
+   depot, since the same nodes could make a feasible tour on their own. */
 
-func subtourelimOP(model *gurobi.Model, cbdata gurobi.CPVoid, where int32, usrdata interface{}) int32 {
+func subtourelimOP(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
 	cbData := usrdata.(*SubData)
 	varCount := cbData.varCount
 	N := cbData.N
-	startX := cbData.startX
 	startY := cbData.startY
-	if where == gurobi.CB_MIPSOL {
-		subSol, err := gurobi.CbGetDblArray(cbdata, where, gurobi.CB_MIPSOL_SOL, varCount)
+	if where == mip.CB_MIPSOL {
+		subSol, err := cbdata.GetDblArray(mip.CB_MIPSOL_SOL, varCount)
 		if err != nil {
 			log.Println(err)
 		}
 		yMat := extractIntYSolution(subSol, N, startY)
 		//fmt.Printf("Found subsolution: %v \n", subSol)
-		subSolNodes := 0
-		for i := startX; i < startY; i++ {
-			if subSol[i] > 0.5 { //Counting the nodes to be visited
-				subSolNodes++
-			}
-		}
-		//fmt.Printf("We should be looking for subtours with less than %d nodes\n", subSolNodes)
-		tour := findsubtour(yMat)
+		subtours := findsubtours(yMat, cbData.depot)
 
-		if len(tour) < subSolNodes {
+		if len(subtours) > 1 {
+			tour := subtours[1]
+			for _, t := range subtours[2:] {
+				if len(t) < len(tour) {
+					tour = t
+				}
+			}
 			//fmt.Printf("Found a subtour with %d nodes\n", len(tour))
 			//fmt.Printf("%v\n", tour)
-			secInd, secVal, oper, rhs := GetSECs([][]int32{gurobi.Int32Slice(tour)}, N, startY)
+			secInd, secVal, oper, rhs := GetSECs([][]int32{mip.Int32Slice(tour)}, N, startY)
 
 			for i := 0; i < len(secInd); i++ {
 				//log.Printf("Adding SEC: %v * %v <= %.2f\n", secVal[i], secInd[i], rhs[i])
-				err = gurobi.CbLazy(cbdata, len(secInd[i]), secInd[i], secVal[i], oper, rhs[i])
+				err = cbdata.Lazy(secInd[i], secVal[i], oper, rhs[i])
 				if err != nil {
 					log.Println(err)
 				}
 			}
-
-		} else {
-			//fmt.Printf("Found a valid tour with %d nodes\n", len(tour))
-			//fmt.Printf("%v\n", tour)
 		}
 	}
 	return 0
@@ -309,13 +340,13 @@ func extractIntYSolution(solA []float64, N int, startY int) [][]int {
 	return yMat
 }
 
-func GetTourLength(tour []int, d [][]int) int{
-	length := 0
-	for i := 0; i < len(tour); i++{
+func GetTourLength(tour []int, d DistanceMatrix) float64 {
+	length := 0.0
+	for i := 0; i < len(tour); i++ {
 		j := (i + 1) % len(tour)
 		u := tour[i]
 		v := tour[j]
-		length += d[u][v]
+		length += d.Dist(u, v)
 	}
 	return length
 }
@@ -0,0 +1,175 @@
+package op
+
+import (
+	"fmt"
+	"math"
+)
+
+// Reachability bounds the duration of the routes from the start to the end depot, that visit a node or use an edge.
+// A node i can only be visited if d(start,i)+d(i,end) fits into the budget, an edge (i,j) can only be used if
+// d(start,i)+d(i,j)+d(j,end) does. The bounds use the shortest paths from the start and to the end depot instead of
+// the direct edges, so they also hold for distances, that violate the triangle inequality like rounded coordinates.
+type Reachability struct {
+	d          DistanceMatrix
+	service    []float64
+	tmax       float64
+	start, end int
+	from, to   []float64 //the shortest paths from the start depot to every node and from every node to the end depot
+}
+
+// NewReachability computes the shortest paths of the bounds. The service times of the nodes count against tmax
+// together with the length of the route. They may be nil.
+func NewReachability(d DistanceMatrix, service []float64, tmax float64, start, end int) *Reachability {
+	r := &Reachability{d: d, service: service, tmax: tmax, start: start, end: end}
+	r.from = shortestPaths(d.Len(), start, func(i, j int) float64 { return d.Dist(i, j) })
+	r.to = shortestPaths(d.Len(), end, func(i, j int) float64 { return d.Dist(j, i) })
+	return r
+}
+
+// GetReachability returns the bounds for the routes of the instance with its service times and travel budget
+func (inst *Instance) GetReachability(d DistanceMatrix) *Reachability {
+	start, end := inst.GetDepots()
+	return NewReachability(d, inst.ServiceTimes, inst.TMax, start, end)
+}
+
+// NodeBound returns the duration of the shortest route through node i
+func (r *Reachability) NodeBound(i int) float64 {
+	return r.from[i] + r.to[i] + r.serviceAt(r.start, i)
+}
+
+// EdgeBound returns the duration of the shortest route, that uses the edge between i and j in either direction
+func (r *Reachability) EdgeBound(i, j int) float64 {
+	service := r.serviceAt(r.start, i, j)
+	return math.Min(r.from[i]+r.d.Dist(i, j)+r.to[j], r.from[j]+r.d.Dist(j, i)+r.to[i]) + service
+}
+
+// NodeUsable returns false if no route within the budget can visit node i. The depots are always usable.
+func (r *Reachability) NodeUsable(i int) bool {
+	return i == r.start || i == r.end || !Exceeds(r.NodeBound(i), r.tmax)
+}
+
+// EdgeUsable returns false if no route within the budget can use the edge between i and j. The edge between the
+// depots of a path is always usable, since it closes the path to a tour.
+func (r *Reachability) EdgeUsable(i, j int) bool {
+	if (i == r.start && j == r.end) || (i == r.end && j == r.start) {
+		return true
+	}
+	return !Exceeds(r.EdgeBound(i, j), r.tmax)
+}
+
+// UsableNodes returns the usable nodes in increasing order
+func (r *Reachability) UsableNodes() []int {
+	var nodes []int
+	for i := 0; i < r.d.Len(); i++ {
+		if r.NodeUsable(i) {
+			nodes = append(nodes, i)
+		}
+	}
+	return nodes
+}
+
+//the service time of the distinct nodes. A path ends with the arrival at the end depot, so its service doesn't count
+func (r *Reachability) serviceAt(nodes ...int) float64 {
+	service := 0.0
+	for k, node := range nodes {
+		counted := node == r.end && r.start != r.end
+		for _, other := range nodes[:k] {
+			counted = counted || other == node
+		}
+		if !counted {
+			service += ServiceTime(r.service, node)
+		}
+	}
+	return service
+}
+
+//dijkstra on the complete graph of n nodes from the source
+func shortestPaths(n int, source int, dist func(i, j int) float64) []float64 {
+	result := make([]float64, n)
+	done := make([]bool, n)
+	for i := 0; i < n; i++ {
+		result[i] = math.Inf(1)
+	}
+	result[source] = 0
+	for count := 0; count < n; count++ {
+		next := -1
+		for i := 0; i < n; i++ {
+			if !done[i] && (next < 0 || result[i] < result[next]) {
+				next = i
+			}
+		}
+		done[next] = true
+		for i := 0; i < n; i++ {
+			if !done[i] && result[next]+dist(next, i) < result[i] {
+				result[i] = result[next] + dist(next, i)
+			}
+		}
+	}
+	return result
+}
+
+// SubInstance returns the instance restricted to the given nodes in increasing order, which have to include the
+// depots. The node k of the result is the node nodes[k] of the instance. Clusters keep the nodes of the subset and
+// are left out, when none of them is left.
+func (inst *Instance) SubInstance(nodes []int) (*Instance, error) {
+	index := make(map[int]int)
+	for k, node := range nodes {
+		index[node] = k
+	}
+	for _, depot := range inst.Depots {
+		if _, ok := index[depot]; !ok {
+			return nil, fmt.Errorf("the nodes don't include the depot %d", depot)
+		}
+	}
+	if _, ok := index[0]; !ok && len(inst.Depots) == 0 {
+		return nil, fmt.Errorf("the nodes don't include the depot 0")
+	}
+
+	sub := &Instance{Name: inst.Name, Comment: inst.Comment, Type: inst.Type, Dimension: len(nodes),
+		DisplayDataType: inst.DisplayDataType, EdgeWeightType: inst.EdgeWeightType, Vehicles: inst.Vehicles,
+		TMax: inst.TMax, TSPLength: inst.TSPLength}
+	for _, depot := range inst.Depots {
+		sub.Depots = append(sub.Depots, index[depot])
+	}
+	if len(inst.EdgeWeights) == 0 {
+		for _, node := range nodes {
+			sub.NodeCoordinates = append(sub.NodeCoordinates, inst.NodeCoordinates[node])
+		}
+	} else {
+		//the subset of the edge weights is written as a full matrix, whatever format they had
+		d, err := inst.GetDistanceMatrix()
+		if err != nil {
+			return nil, err
+		}
+		sub.EdgeWeightFormat = FULL_MATRIX
+		for _, i := range nodes {
+			row := make([]float64, len(nodes))
+			for k, j := range nodes {
+				row[k] = d.Dist(i, j)
+			}
+			sub.EdgeWeights = append(sub.EdgeWeights, row)
+		}
+	}
+	for _, node := range nodes {
+		sub.Prices = append(sub.Prices, inst.Prices[node])
+		if len(inst.ServiceTimes) > 0 {
+			sub.ServiceTimes = append(sub.ServiceTimes, inst.ServiceTimes[node])
+		}
+		if inst.HasTimeWindows() {
+			sub.TimeWindows = append(sub.TimeWindows, inst.TimeWindows[node])
+		}
+	}
+	for c, cluster := range inst.Clusters {
+		var subCluster []int
+		for _, node := range cluster {
+			if k, ok := index[node]; ok {
+				subCluster = append(subCluster, k)
+			}
+		}
+		if len(subCluster) > 0 {
+			sub.Clusters = append(sub.Clusters, subCluster)
+			sub.ClusterPrices = append(sub.ClusterPrices, inst.ClusterPrices[c])
+		}
+	}
+	return sub, nil
+}
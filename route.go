@@ -0,0 +1,86 @@
+package op
+
+// GetDepots returns the start and end depot of the instance. A single depot (or none, in which case
+// node 0 is used) describes a closed tour, two different depots a path from the first to the second.
+func (inst *Instance) GetDepots() (start, end int) {
+	switch len(inst.Depots) {
+	case 0:
+		return 0, 0
+	case 1:
+		return inst.Depots[0], inst.Depots[0]
+	}
+	return inst.Depots[0], inst.Depots[1]
+}
+
+// IsPath returns true if the route has to start and end at different depots
+func (inst *Instance) IsPath() bool {
+	start, end := inst.GetDepots()
+	return start != end
+}
+
+// GetVehicles returns the number of routes the instance allows. Instances without vehicles have a single route.
+func (inst *Instance) GetVehicles() int {
+	if inst.Vehicles < 1 {
+		return 1
+	}
+	return inst.Vehicles
+}
+
+// GetRoutes returns the routes of the solution. A solution with a single route may only set Route.
+func (sol *Solution) GetRoutes() [][]int {
+	if len(sol.Routes) > 0 {
+		return sol.Routes
+	}
+	if len(sol.Route) > 0 {
+		return [][]int{sol.Route}
+	}
+	return nil
+}
+
+// ClosePath returns a copy of d in which the edge between the start and the end depot costs nothing.
+// A path from start to end then has the same length as the tour closing it with that edge, so the
+// tour models can be used for paths as long as they fix this edge.
+func ClosePath(d [][]float64, start, end int) [][]float64 {
+	res := make([][]float64, len(d))
+	for i := range d {
+		res[i] = append([]float64{}, d[i]...)
+	}
+	if start != end {
+		res[start][end] = 0
+		res[end][start] = 0
+	}
+	return res
+}
+
+// OrientRoute rotates (and if necessary reverses) the closed tour so that it begins at the start depot
+// and, if it's a path, ends at the end depot. The tour is changed in place.
+func OrientRoute(tour []int, start, end int) []int {
+	n := len(tour)
+	for k := 0; k < n; k++ {
+		if tour[k] != start {
+			continue
+		}
+		rotated := append(append([]int{}, tour[k:]...), tour[:k]...)
+		if start != end && n > 1 && rotated[1] == end {
+			//walk the tour in the other direction, so that the edge to the end depot is the last one
+			for i, j := 1, n-1; i < j; i, j = i+1, j-1 {
+				rotated[i], rotated[j] = rotated[j], rotated[i]
+			}
+		}
+		copy(tour, rotated)
+		break
+	}
+	return tour
+}
+
+// GetRouteLength returns the length of the route. For a closed tour this includes the edge back to the start.
+func (inst *Instance) GetRouteLength(route []int, d DistanceMatrix) float64 {
+	if !inst.IsPath() {
+		return GetTourLength(route, d)
+	}
+	length := 0.0
+	for i := 1; i < len(route); i++ {
+		length += d.Dist(route[i-1], route[i])
+	}
+	return length
+}
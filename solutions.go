@@ -0,0 +1,124 @@
+package op
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Key identifies the configuration and the objective the solution was computed for. An instance keeps one solution
+// per key. The order of the cuts doesn't matter.
+func (sol *Solution) Key() string {
+	c := sol.Config
+	cuts := append([]string(nil), c.Cuts...)
+	sort.Strings(cuts)
+	var parts []string
+	for _, part := range []string{c.Command, c.Strategy, c.SubStrat, strings.Join(cuts, "+"), c.YBounds, c.Backend} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if c.Sweep {
+		parts = append(parts, "sweep")
+	}
+	objective := sol.GetObjective()
+	switch objective {
+	case OBJ_PCTSP:
+		objective += fmt.Sprintf("(%g)", sol.MinPrize)
+	case OBJ_PTP:
+		objective += fmt.Sprintf("(%g)", sol.Lambda)
+	}
+	return strings.Join(append(parts, objective), "/")
+}
+
+// AddSolution adds the solution to the instance. It replaces the solution with the same key, the ones of other
+// configurations are kept.
+func (inst *Instance) AddSolution(sol *Solution) {
+	key := sol.Key()
+	for i, other := range inst.Solutions {
+		if other.Key() == key {
+			inst.Solutions[i] = sol
+			return
+		}
+	}
+	inst.Solutions = append(inst.Solutions, sol)
+}
+
+// GetSolution returns the solution with the key or nil, if the instance has none
+func (inst *Instance) GetSolution(key string) *Solution {
+	for _, sol := range inst.Solutions {
+		if sol.Key() == key {
+			return sol
+		}
+	}
+	return nil
+}
+
+// Hash returns the sha256 hash of the instance without its solutions in the json format of FormatVersion
+func (inst *Instance) Hash() (string, error) {
+	content := *inst
+	content.Version = FormatVersion
+	content.Solutions = nil
+	content.Solution = nil
+	h := sha256.New()
+	err := EncodeJson(h, &content)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SidecarFileName returns the name of the sidecar file of the instance file, e.g. a.solutions.json for a.json.gz
+func SidecarFileName(instanceFile string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(instanceFile, ".gz"), ".json") + ".solutions.json"
+}
+
+// LoadSidecar adds the solutions of the sidecar file to the instance. It fails if the sidecar belongs to another
+// instance or to another version of it.
+func LoadSidecar(fileName string, inst *Instance) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var sidecar Sidecar
+	err = json.NewDecoder(file).Decode(&sidecar)
+	if err != nil {
+		return err
+	}
+	if sidecar.Version > FormatVersion || sidecar.Version < 0 {
+		return fmt.Errorf("unsupported format version %d, the newest known version is %d", sidecar.Version, FormatVersion)
+	}
+	hash, err := inst.Hash()
+	if err != nil {
+		return err
+	}
+	if sidecar.InstanceHash != hash {
+		return fmt.Errorf("the solutions belong to another content of the instance %s", sidecar.InstanceName)
+	}
+	for _, sol := range sidecar.Solutions {
+		inst.AddSolution(sol)
+	}
+	return nil
+}
+
+// SaveSidecar writes the solutions of the instance to the sidecar file, together with the hash of the instance
+func SaveSidecar(fileName string, inst *Instance) error {
+	hash, err := inst.Hash()
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	err = EncodeJson(file, &Sidecar{Version: FormatVersion, InstanceName: inst.Name, InstanceHash: hash, Solutions: inst.Solutions})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
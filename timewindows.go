@@ -0,0 +1,67 @@
+package op
+
+// HasTimeWindows returns true if the nodes of the instance have opening hours (OPTW)
+func (inst *Instance) HasTimeWindows() bool {
+	return len(inst.TimeWindows) > 0
+}
+
+// GetServiceTime returns the time spent at the node. Instances without service times spend no time at the nodes.
+func (inst *Instance) GetServiceTime(node int) float64 {
+	return ServiceTime(inst.ServiceTimes, node)
+}
+
+// ServiceTime returns the service time of the node or 0, if there is none
+func ServiceTime(service []float64, node int) float64 {
+	if node < 0 || node >= len(service) {
+		return 0
+	}
+	return service[node]
+}
+
+// ScheduleRoute returns the time at which the service begins at every node of the route. The vehicle leaves the start
+// depot after its service at the opening of the depot and waits at every node until it opens. Instances without time
+// windows never wait. For a closed tour the return to the depot is appended to the times.
+// The duration is the time from the opening of the start depot until the arrival at the end depot.
+func (inst *Instance) ScheduleRoute(route []int, d DistanceMatrix) (times []float64, duration float64) {
+	if len(route) == 0 {
+		return nil, 0
+	}
+	stops := route
+	if !inst.IsPath() {
+		stops = append(append([]int{}, route...), route[0])
+	}
+	times = make([]float64, len(stops))
+	times[0] = inst.opening(stops[0])
+	for k := 1; k < len(stops); k++ {
+		arrival := times[k-1] + inst.GetServiceTime(stops[k-1]) + d.Dist(stops[k-1], stops[k])
+		if open := inst.opening(stops[k]); arrival < open {
+			arrival = open
+		}
+		times[k] = arrival
+	}
+	return times, times[len(times)-1] - times[0]
+}
+
+// TimeWindowViolations returns the nodes of the route at which the service begins after their time window closes.
+// The end depot of a closed tour is listed, if the vehicle returns too late.
+func (inst *Instance) TimeWindowViolations(route []int, d DistanceMatrix) []int {
+	if !inst.HasTimeWindows() {
+		return nil
+	}
+	times, _ := inst.ScheduleRoute(route, d)
+	var late []int
+	for k, t := range times {
+		node := route[k%len(route)]
+		if node < len(inst.TimeWindows) && Exceeds(t, inst.TimeWindows[node][1]) {
+			late = append(late, node)
+		}
+	}
+	return late
+}
+
+func (inst *Instance) opening(node int) float64 {
+	if node < 0 || node >= len(inst.TimeWindows) {
+		return 0
+	}
+	return inst.TimeWindows[node][0]
+}
@@ -0,0 +1,269 @@
+package op
+
+import (
+	"fmt"
+	"git.solver4all.com/azaryc2s/op/mip"
+	"log"
+)
+
+/* Define structure to pass data to the TOP callback function */
+
+type topSubData struct {
+	N         int
+	vehicles  int
+	blockSize int
+	varCount  int
+	depot     int
+}
+
+// SolveTOPPath solves the team orienteering problem with the given number of vehicles, each driving a path from the
+// start to the end depot (or a closed tour, if both are the same) within tmax. Vehicles may stay unused.
+// The returned routes begin at the start depot and end at the end depot, lengths holds the length of every route.
+// Like in SolveOPPath the service times of the nodes count against tmax, except for the end depot of a path, and the
+// model is created in env.
+func SolveTOPPath(env mip.Env, d [][]float64, p []float64, service []float64, tmax float64, vehicles int, start int, end int) (routes [][]int, score float64, lengths []float64, optimstatus int32, lb float64, err error) {
+	N := len(d)
+	//every path is closed with its own copy of the edge between start and end, which costs nothing
+	d = ClosePath(d, start, end)
+
+	/* Create an empty model */
+
+	model, err := env.NewModel("top")
+	if err != nil {
+		log.Println(err)
+		return nil, -1, nil, -1, -1, err
+	}
+	defer model.Free()
+	err = model.SetIntParam(mip.INT_PAR_LOGTOCONSOLE, 0)
+	if err != nil {
+		log.Println(err)
+		return nil, -1, nil, -1, -1, err
+	}
+
+	/* Every vehicle k gets a block with the variables X_i_k and Y_i_j_k of the single vehicle model */
+	blockSize := N + N*(N-1)/2
+	xIndex := func(i, k int) int {
+		return k*blockSize + i
+	}
+	yIndex := func(i, j, k int) int {
+		return GetEdgeIndex(i, j, N, k*blockSize+N)
+	}
+	varCount := 0
+	for k := 0; k < vehicles; k++ {
+		for i := 0; i < N; i++ {
+			err = model.AddVar(p[i], 0.0, 1.0, mip.BINARY, fmt.Sprintf("X_%d_%d", i, k))
+			if err != nil {
+				log.Println(err)
+				return nil, -1, nil, -1, -1, err
+			}
+			varCount++
+		}
+		for i := 0; i < N; i++ {
+			for j := i + 1; j < N; j++ {
+				err = model.AddVar(0.0, 0.0, 1.0, mip.BINARY, fmt.Sprintf("Y_%d_%d_%d", i, j, k))
+				if err != nil {
+					log.Println(err)
+					return nil, -1, nil, -1, -1, err
+				}
+				varCount++
+			}
+		}
+	}
+
+	// Change objective sense to maximization
+	err = model.SetIntAttr(mip.INT_ATTR_MODELSENSE, mip.MAXIMIZE)
+	if err != nil {
+		log.Printf("Error: %s\n", err.Error())
+		return nil, -1, nil, -1, -1, err
+	}
+
+	//every node except the depots is visited by at most one vehicle
+	for i := 0; i < N; i++ {
+		if i == start || i == end {
+			continue
+		}
+		var (
+			ind []int32
+			val []float64
+		)
+		for k := 0; k < vehicles; k++ {
+			ind = append(ind, int32(xIndex(i, k)))
+			val = append(val, 1.0)
+		}
+		err = model.AddConstr(ind, val, mip.LESS_EQUAL, 1.0, fmt.Sprintf("visit_once_%d", i))
+		if err != nil {
+			log.Printf("Error adding visit_once_%d\n", i)
+			return nil, -1, nil, -1, -1, err
+		}
+	}
+
+	for k := 0; k < vehicles; k++ {
+		//the nodes of every vehicle are connected to 2 of its active edges
+		for i := 0; i < N; i++ {
+			var (
+				ind []int32
+				val []float64
+			)
+			for j := 0; j < N; j++ {
+				if j != i {
+					ind = append(ind, int32(yIndex(i, j, k)))
+					val = append(val, 1.0)
+				}
+			}
+			ind = append(ind, int32(xIndex(i, k)))
+			val = append(val, -2.0)
+			err = model.AddConstr(ind, val, mip.EQUAL, 0.0, fmt.Sprintf("node_2_%d_%d", i, k))
+			if err != nil {
+				log.Printf("Error adding node_2_%d_%d\n", i, k)
+				return nil, -1, nil, -1, -1, err
+			}
+		}
+
+		//a used vehicle visits both depots and takes the fixed edge between them
+		if start != end {
+			ind := []int32{int32(xIndex(end, k)), int32(xIndex(start, k))}
+			val := []float64{1.0, -1.0}
+			err = model.AddConstr(ind, val, mip.EQUAL, 0.0, fmt.Sprintf("end_depot_%d", k))
+			if err != nil {
+				log.Printf("Error adding end_depot_%d\n", k)
+				return nil, -1, nil, -1, -1, err
+			}
+			ind = []int32{int32(yIndex(start, end, k)), int32(xIndex(start, k))}
+			err = model.AddConstr(ind, val, mip.EQUAL, 0.0, fmt.Sprintf("depot_edge_%d", k))
+			if err != nil {
+				log.Printf("Error adding depot_edge_%d\n", k)
+				return nil, -1, nil, -1, -1, err
+			}
+		}
+
+		//the vehicles are used in order, which removes symmetric solutions
+		if k > 0 {
+			ind := []int32{int32(xIndex(start, k-1)), int32(xIndex(start, k))}
+			val := []float64{1.0, -1.0}
+			err = model.AddConstr(ind, val, mip.GREATER_EQUAL, 0.0, fmt.Sprintf("vehicle_order_%d", k))
+			if err != nil {
+				log.Printf("Error adding vehicle_order_%d\n", k)
+				return nil, -1, nil, -1, -1, err
+			}
+		}
+
+		var (
+			ind []int32
+			val []float64
+		)
+		for i := 0; i < N; i++ {
+			for j := i + 1; j < N; j++ {
+				ind = append(ind, int32(yIndex(i, j, k)))
+				val = append(val, d[i][j])
+			}
+		}
+		for i := 0; i < N; i++ {
+			if s := ServiceTime(service, i); s != 0 && (i != end || start == end) {
+				ind = append(ind, int32(xIndex(i, k)))
+				val = append(val, s)
+			}
+		}
+		err = model.AddConstr(ind, val, mip.LESS_EQUAL, tmax, fmt.Sprintf("travel_budget_%d", k))
+		if err != nil {
+			log.Printf("Error adding constraint for travel budget of vehicle %d: %s\n", k, err.Error())
+			return nil, -1, nil, -1, -1, err
+		}
+	}
+
+	/* Set callback function */
+	cbData := topSubData{N: N, vehicles: vehicles, blockSize: blockSize, varCount: varCount, depot: start}
+	err = model.SetCallbackFunc(subtourelimTOP, &cbData)
+	if err != nil {
+		log.Println(err)
+		return nil, -1, nil, -1, -1, err
+	}
+
+	/* Must set LazyConstraints parameter when using lazy constraints */
+
+	err = model.SetIntParam(mip.INT_PAR_LAZYCONSTRAINTS, 1)
+	if err != nil {
+		log.Println(err)
+		return nil, -1, nil, -1, -1, err
+	}
+
+	// Optimize model
+	err = model.Optimize()
+	if err != nil {
+		log.Printf("Error: %s\n", err.Error())
+		return nil, -1, nil, -1, -1, err
+	}
+
+	// Capture solution information
+	optimstatus, err = model.GetIntAttr(mip.INT_ATTR_STATUS)
+	if err != nil {
+		log.Printf("Error capturing solution: %s\n", err.Error())
+		return nil, -1, nil, -1, -1, err
+	}
+
+	score, err = model.GetDblAttr(mip.DBL_ATTR_OBJVAL)
+	if err != nil {
+		log.Printf("Couldn't retrieve the obj-value: %s.\n", err.Error())
+		return nil, -1, nil, -1, -1, err
+	}
+
+	lb, err = model.GetDblAttr(mip.DBL_ATTR_OBJBOUND)
+	if err != nil {
+		log.Printf("Couldn't retrieve the lower-bound-value: %s. ", err.Error())
+		return nil, -1, nil, -1, -1, err
+	}
+
+	solM, err := model.GetDblAttrArray(mip.DBL_ATTR_X, 0, int32(varCount))
+	if err != nil {
+		log.Println(err)
+		return nil, -1, nil, -1, -1, err
+	}
+	for k := 0; k < vehicles; k++ {
+		if solM[xIndex(start, k)] < 0.5 {
+			continue
+		}
+		yMat := extractIntYSolution(solM, N, k*blockSize+N)
+		route := OrientRoute(findsubtours(yMat, start)[0], start, end)
+		routes = append(routes, route)
+		lengths = append(lengths, GetTourLength(route, Matrix(d)))
+	}
+	return routes, score, lengths, optimstatus, lb, nil
+}
+
+/* Subtour elimination callback of the TOP. Whenever a feasible solution is found,
+   every subtour without the depot of any vehicle is cut off for all vehicles together,
+   since no node can be visited twice. */
+
+func subtourelimTOP(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+	cbData := usrdata.(*topSubData)
+	N := cbData.N
+	if where == mip.CB_MIPSOL {
+		subSol, err := cbdata.GetDblArray(mip.CB_MIPSOL_SOL, cbData.varCount)
+		if err != nil {
+			log.Println(err)
+			return 0
+		}
+		for k := 0; k < cbData.vehicles; k++ {
+			yMat := extractIntYSolution(subSol, N, k*cbData.blockSize+N)
+			subtours := findsubtours(yMat, cbData.depot)
+			for _, tour := range subtours[1:] {
+				var (
+					ind []int32
+					val []float64
+				)
+				for v := 0; v < cbData.vehicles; v++ {
+					for i := 0; i < len(tour); i++ {
+						for j := i + 1; j < len(tour); j++ {
+							ind = append(ind, int32(GetEdgeIndex(tour[i], tour[j], N, v*cbData.blockSize+N)))
+							val = append(val, 1.0)
+						}
+					}
+				}
+				err = cbdata.Lazy(ind, val, mip.LESS_EQUAL, float64(len(tour)-1))
+				if err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}
+	return 0
+}
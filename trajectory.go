@@ -0,0 +1,80 @@
+package op
+
+import "math"
+
+/* The integrals of the trajectory of a solve follow Berthold, "Measuring the impact of primal heuristics" (2013). The
+   trajectory is a step function, every point holds until the next one and the gap is 1 before the first point. The
+   integrals end with the last point, which the solver records when the optimization stops. */
+
+// Gap returns the relative gap between the values in [0, 1]. It's 0 for (nearly) equal values, 1 for values of
+// different signs and the difference relative to the larger magnitude otherwise
+func Gap(a, b float64) float64 {
+	if NearlyEqual(a, b) {
+		return 0
+	}
+	if a*b < 0 {
+		return 1
+	}
+	return math.Abs(a-b) / math.Max(math.Abs(a), math.Abs(b))
+}
+
+// PrimalGap returns the gap between the incumbent of the point and the best known objective
+func (p TrajectoryPoint) PrimalGap(best float64) float64 {
+	if !p.HasIncumbent {
+		return 1
+	}
+	return Gap(p.Incumbent, best)
+}
+
+// DualGap returns the gap between the bound of the point and the best known objective
+func (p TrajectoryPoint) DualGap(best float64) float64 {
+	return Gap(p.Bound, best)
+}
+
+// PrimalDualGap returns the gap between the incumbent and the bound of the point
+func (p TrajectoryPoint) PrimalDualGap() float64 {
+	if !p.HasIncumbent {
+		return 1
+	}
+	return Gap(p.Incumbent, p.Bound)
+}
+
+// PrimalIntegral returns the integral of the gap between the incumbents and the best known objective over the time
+func PrimalIntegral(trajectory []TrajectoryPoint, best float64) float64 {
+	return integrate(trajectory, func(p TrajectoryPoint) float64 {
+		return p.PrimalGap(best)
+	})
+}
+
+// DualIntegral returns the integral of the gap between the bounds and the best known objective over the time
+func DualIntegral(trajectory []TrajectoryPoint, best float64) float64 {
+	return integrate(trajectory, func(p TrajectoryPoint) float64 {
+		return p.DualGap(best)
+	})
+}
+
+// PrimalDualIntegral returns the integral of the gap between the incumbents and the bounds over the time
+func PrimalDualIntegral(trajectory []TrajectoryPoint) float64 {
+	return integrate(trajectory, TrajectoryPoint.PrimalDualGap)
+}
+
+// TimeToTarget returns the time of the first point, whose gap is at most the target, and false if there is none
+func TimeToTarget(trajectory []TrajectoryPoint, gap func(p TrajectoryPoint) float64, target float64) (float64, bool) {
+	for _, p := range trajectory {
+		if gap(p) <= target {
+			return p.Time, true
+		}
+	}
+	return 0, false
+}
+
+func integrate(trajectory []TrajectoryPoint, gap func(p TrajectoryPoint) float64) float64 {
+	if len(trajectory) == 0 {
+		return 0
+	}
+	integral := trajectory[0].Time
+	for k := 0; k+1 < len(trajectory); k++ {
+		integral += gap(trajectory[k]) * (trajectory[k+1].Time - trajectory[k].Time)
+	}
+	return integral
+}
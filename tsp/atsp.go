@@ -12,9 +12,8 @@ package tsp
 
 import (
 	"fmt"
-	"git.solver4all.com/azaryc2s/gorobi/gurobi"
+	"git.solver4all.com/azaryc2s/op/mip"
 	"log"
-	"os"
 	//"math"
 	//"math/rand"
 	//"os"
@@ -72,15 +71,15 @@ func findsubtourATSP(sol [][]float64) (result []int32) {
    find the shortest subtour, and add a subtour elimination constraint
    if that tour doesn't visit every node. */
 
-func subtourelimATSP(model *gurobi.Model, cbdata gurobi.CPVoid, where int32, usrdata interface{}) int32 {
-	n := usrdata.(SubData).N
+func subtourelimATSP(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+	n := usrdata.(*SubData).N
 
-	if where == gurobi.CB_MIPSOL {
-		sol, err := gurobi.CbGetDblMatrix(cbdata, where, gurobi.CB_MIPSOL_SOL, int(n))
+	if where == mip.CB_MIPSOL {
+		solA, err := cbdata.GetDblArray(mip.CB_MIPSOL_SOL, int(n*n))
 		if err != nil {
 			log.Println(err)
 		}
-		tour := findsubtourATSP(sol)
+		tour := findsubtourATSP(mip.Matrix(solA, int(n)))
 		if int32(len(tour)) < n {
 			var (
 				ind []int32
@@ -97,7 +96,7 @@ func subtourelimATSP(model *gurobi.Model, cbdata gurobi.CPVoid, where int32, usr
 				val = append(val, 1.0)
 			}
 
-			err = gurobi.CbLazy(cbdata, len(ind), ind, val, gurobi.LESS_EQUAL, float64(len(tour)-1))
+			err = cbdata.Lazy(ind, val, mip.LESS_EQUAL, float64(len(tour)-1))
 			if err != nil {
 				log.Println(err)
 			}
@@ -107,33 +106,28 @@ func subtourelimATSP(model *gurobi.Model, cbdata gurobi.CPVoid, where int32, usr
 	return 0
 }
 
-func SolveATSP(d [][]int) ([]int32, int) {
-	// Create environment
-	var err error
-	gurobiEnv, err = gurobi.LoadEnv("atsp_gurobi.log")
-	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
-		return nil,-1
-	}
-	defer gurobiEnv.Free()
-
-	gurobiEnv.SetIntParam("LogToConsole", int32(0))
-	defer gurobiEnv.SetIntParam("LogToConsole", int32(1))
+// SolveATSP calculates the shortest tour of the asymmetric distances in a model of env like SolveTSPPath
+func SolveATSP(env mip.Env, name string, d [][]float64) ([]int32, float64) {
 	n := len(d)
 
 	/* Create an empty model */
 
-	model, err := gurobiEnv.NewModel("atsp", 0, nil, nil, nil, nil, nil)
+	model, err := env.NewModel("atsp")
 	if err != nil {
-		log.Println(err)
+		log.Printf("At %s: %s\n", name, err.Error())
 		return nil, -1
 	}
 	defer model.Free()
+	err = model.SetIntParam(mip.INT_PAR_LOGTOCONSOLE, 0)
+	if err != nil {
+		log.Printf("At %s: %s\n", name, err.Error())
+		return nil, -1
+	}
 
 	// Change objective sense to minimization
-	err = model.SetIntAttr(gurobi.INT_ATTR_MODELSENSE, gurobi.MINIMIZE)
+	err = model.SetIntAttr(mip.INT_ATTR_MODELSENSE, mip.MINIMIZE)
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", name, err.Error())
 		return nil, -1
 	}
 
@@ -141,8 +135,8 @@ func SolveATSP(d [][]int) ([]int32, int) {
 
 	for i := 0; i < n; i++ {
 		for j := 0; j < n; j++ {
-			name := fmt.Sprintf("x_%d_%d", i, j)
-			err = model.AddVar(nil, nil, float64(d[i][j]), 0.0, 1.0, gurobi.BINARY, name)
+			varName := fmt.Sprintf("x_%d_%d", i, j)
+			err = model.AddVar(d[i][j], 0.0, 1.0, mip.BINARY, varName)
 			if err != nil {
 				log.Println(err)
 				return nil, -1
@@ -164,7 +158,7 @@ func SolveATSP(d [][]int) ([]int32, int) {
 			val = append(val, 1.0)
 		}
 		nameo := fmt.Sprintf("deg2o_%d", i)
-		err = model.AddConstr(gurobi.Int32Slice(ind), val, gurobi.EQUAL, 1, nameo)
+		err = model.AddConstr(mip.Int32Slice(ind), val, mip.EQUAL, 1, nameo)
 
 		ind = nil
 		val = nil
@@ -173,12 +167,12 @@ func SolveATSP(d [][]int) ([]int32, int) {
 			val = append(val, 1.0)
 		}
 		namei := fmt.Sprintf("deg2i_%d", i)
-		err = model.AddConstr(gurobi.Int32Slice(ind), val, gurobi.EQUAL, 1, namei)
+		err = model.AddConstr(mip.Int32Slice(ind), val, mip.EQUAL, 1, namei)
 	}
 
 	/* Forbid edge from node back to itself */
 	for i := 0; i < n; i++ {
-		err = model.SetDblAttrElem(gurobi.DBL_ATTR_UB, int32(i*n+i), 0)
+		err = model.SetDblAttrElem(mip.DBL_ATTR_UB, int32(i*n+i), 0)
 		if err != nil {
 			log.Println(err)
 			return nil, -1
@@ -196,7 +190,7 @@ func SolveATSP(d [][]int) ([]int32, int) {
 			ind[1] = i + j*n
 			val[0] = 1
 			val[1] = 1
-			err = model.AddConstr(gurobi.Int32Slice(ind), val, gurobi.LESS_EQUAL, 1.0, fmt.Sprintf("asym_%d", count))
+			err = model.AddConstr(mip.Int32Slice(ind), val, mip.LESS_EQUAL, 1.0, fmt.Sprintf("asym_%d", count))
 			count++
 			if err != nil {
 				log.Println(err)
@@ -207,7 +201,7 @@ func SolveATSP(d [][]int) ([]int32, int) {
 
 	/* Set callback function */
 
-	err = model.SetCallbackFuncGo(subtourelimATSP, SubData{N: int32(n)})
+	err = model.SetCallbackFunc(subtourelimATSP, &SubData{N: int32(n)})
 	if err != nil {
 		log.Println(err)
 		return nil, -1
@@ -215,7 +209,7 @@ func SolveATSP(d [][]int) ([]int32, int) {
 
 	/* Must set LazyConstraints parameter when using lazy constraints */
 
-	err = model.SetIntParam(gurobi.INT_PAR_LAZYCONSTRAINTS, 1)
+	err = model.SetIntParam(mip.INT_PAR_LAZYCONSTRAINTS, 1)
 	if err != nil {
 		log.Println(err)
 		return nil, -1
@@ -230,20 +224,20 @@ func SolveATSP(d [][]int) ([]int32, int) {
 	}
 
 	/* Extract solution */
-	solcount, err := model.GetIntAttr(gurobi.INT_ATTR_SOLCOUNT)
+	solcount, err := model.GetIntAttr(mip.INT_ATTR_SOLCOUNT)
 	if err != nil {
 		log.Println(err)
 		return nil, -1
 	}
 
 	if solcount > 0 {
-		solAtsp, err := model.GetDblAttrMatrix(gurobi.DBL_ATTR_X, 0, int32(n))
+		solA, err := model.GetDblAttrArray(mip.DBL_ATTR_X, 0, int32(n*n))
 		if err != nil {
 			log.Println(err)
 			return nil, -1
 		}
-		tour := findsubtourATSP(solAtsp)
-		length := 0
+		tour := findsubtourATSP(mip.Matrix(solA, n))
+		length := 0.0
 		for i := 0; i < len(tour)-1; i++ {
 			length += d[int(tour[i])][int(tour[i+1])]
 		}
@@ -1,44 +1,96 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"git.solver4all.com/azaryc2s/op"
+	"git.solver4all.com/azaryc2s/op/mip"
+	_ "git.solver4all.com/azaryc2s/op/mip/bnc"
+	_ "git.solver4all.com/azaryc2s/op/mip/grb"
 	"git.solver4all.com/azaryc2s/op/tsp"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/host"
 	"github.com/shirou/gopsutil/mem"
-	"io/ioutil"
 	"log"
-	"os"
+	"time"
 )
 
 func main() {
 	var (
-		err   error
 		pInst op.Instance
-		sol op.Solution
+		sol   op.Solution
 	)
+	solverFlags := op.AddSolverFlags()
+	flag.Parse()
+	inputF := flag.Arg(0)
+	params, paramsText, err := solverFlags.Params()
+	if err != nil {
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
 	hostStat, _ := host.Info()
 	cpuStat, _ := cpu.Info()
 	vmStat, _ := mem.VirtualMemory()
-	sol = op.Solution{Comment: "", System: op.SysInfo{hostStat.Platform, cpuStat[0].ModelName, fmt.Sprintf("%d GB", (vmStat.Total / 1024 / 1024 / 1024))}}
-
-	instStr, err := ioutil.ReadFile(os.Args[1])
+	sol = op.Solution{Comment: "", System: op.SysInfo{Platform: hostStat.Platform, CPU: cpuStat[0].ModelName, RAM: fmt.Sprintf("%d GB", (vmStat.Total / 1024 / 1024 / 1024))}}
+	sol.Params = paramsText
 
+	inst, err := op.LoadInstanceFile(inputF)
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
+	pInst = *inst
+	if problems := pInst.Validate(); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("At %s: invalid instance - %s\n", inputF, problem)
+		}
 		return
 	}
+	edgeDist, err := pInst.GetEdgeDist()
+	if err != nil {
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
+	env, err := mip.LoadEnv("tsp_gurobi.log")
+	if err != nil {
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
+	defer env.Free()
+	if err = params.Apply(env); err != nil {
+		log.Printf("At %s: %s\n", inputF, err.Error())
+		return
+	}
+	sol.Config = op.SolverConfig{Command: "tsp", Backend: mip.SelectedBackend() + " " + env.Version()}
 
-	err = json.Unmarshal(instStr, &pInst)
+	startTime := time.Now()
+	var (
+		tour   []int32
+		length float64
+	)
+	if op.IsSymmetric(op.Matrix(edgeDist)) {
+		start, end := pInst.GetDepots()
+		tour, length, _ = tsp.SolveTSPPath(env, inputF, edgeDist, start, end)
+	} else {
+		tour, length = tsp.SolveATSP(env, inputF, edgeDist)
+	}
+	if tour == nil || length < 0 {
+		log.Printf("At %s: couldn't compute the tsp tour\n", inputF)
+		return
+	}
+	sol.Time = time.Since(startTime).String()
+	log.Printf("The calculated tour with length %g: %v", length, tour)
 
+	//the tour visits every node, so it's kept as the tsp length of the instance and not verified against its budget
+	sol.Route = make([]int, len(tour))
+	for i := range tour {
+		sol.Route[i] = int(tour[i])
+	}
+	sol.Obj, sol.RouteCost = length, length
+	pInst.TSPLength = length
+	pInst.AddSolution(&sol)
+	err = op.SaveInstanceFile(inputF, &pInst)
 	if err != nil {
-		log.Printf("At %s: %s\n", os.Args[1], err.Error())
-		return
+		log.Printf("At %s: %s\n", inputF, err.Error())
 	}
-	edgeDist := op.CalcEdgeDist(pInst.NodeCoordinates, pInst.EdgeWeightType)
-	tour, length, _ := tsp.SolveTSP(edgeDist)
-	sol.PInstance = pInst
-	log.Printf("The calculated tour with length %d: %v",length, tour)
 }
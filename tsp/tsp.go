@@ -2,23 +2,18 @@ package tsp
 
 import (
 	"fmt"
-	"git.solver4all.com/azaryc2s/gorobi/gurobi"
+	"git.solver4all.com/azaryc2s/op/mip"
 	"git.solver4all.com/azaryc2s/op"
 	"log"
 	"math"
-	"os"
-)
-
-var (
-	gurobiEnv *gurobi.Env
-	subtours  [][]int32
-	varCount  int
 )
 
 /* Define structure to pass data to the callback function */
 
 type SubData struct {
-	N int32
+	N        int32
+	varCount int
+	subtours [][]int32 //the subtours cut off by the callback
 }
 
 /* Given an integer-feasible solution 'sol', find the smallest sub-tour.  Result is returned in 'tour', and length is returned in 'tourlenP'. */
@@ -68,18 +63,19 @@ func findsubtour(edges [][]int) (result []int32) {
 
 /* Subtour elimination callback.  Whenever a feasible solution is found, find the shortest subtour and then add the subtour elimination constraint if that tour doesn't visit every node. */
 
-func subtourelimTSP(model *gurobi.Model, cbdata gurobi.CPVoid, where int32, usrdata interface{}) int32 {
-	n := usrdata.(SubData).N
+func subtourelimTSP(model mip.Model, cbdata mip.CallbackData, where int32, usrdata interface{}) int32 {
+	cbData := usrdata.(*SubData)
+	n := cbData.N
 
-	if where == gurobi.CB_MIPSOL {
-		sol, err := gurobi.CbGetDblArray(cbdata, where, gurobi.CB_MIPSOL_SOL, varCount)
+	if where == mip.CB_MIPSOL {
+		sol, err := cbdata.GetDblArray(mip.CB_MIPSOL_SOL, cbData.varCount)
 		if err != nil {
 			log.Println(err)
 		}
 		solA := extractEdgeMatrix(sol, int(n))
 		tour := findsubtour(solA)
 		if int32(len(tour)) < n {
-			subtours = append(subtours, tour)
+			cbData.subtours = append(cbData.subtours, tour)
 			var (
 				ind []int32
 				val []float64
@@ -95,7 +91,7 @@ func subtourelimTSP(model *gurobi.Model, cbdata gurobi.CPVoid, where int32, usrd
 				val = append(val, 1.0)
 			}
 
-			err = gurobi.CbLazy(cbdata, len(ind), ind, val, gurobi.LESS_EQUAL, float64(len(tour)-1))
+			err = cbdata.Lazy(ind, val, mip.LESS_EQUAL, float64(len(tour)-1))
 			if err != nil {
 				log.Println(err)
 			}
@@ -142,37 +138,42 @@ func distance(x, y []float64, i, j int) float64 {
 	return count
 }*/
 
-func SolveTSP(d [][]int) ([]int32, int, [][]int32) {
-	/* Reset variables and create the gurobi environment */
-	var err error
-	subtours = make([][]int32, 0)
-	gurobiEnv, err = gurobi.LoadEnv("tsp_gurobi.log")
-	varCount = 0
-	N := len(d)
-	if err != nil {
-		log.Println(err)
-		return nil, -1, nil
-	}
-	defer gurobiEnv.Free()
+func SolveTSP(env mip.Env, name string, d [][]float64) ([]int32, float64, [][]int32) {
+	return SolveTSPPath(env, name, d, 0, 0)
+}
 
-	gurobiEnv.SetIntParam("LogToConsole", int32(0))
-	defer gurobiEnv.SetIntParam("LogToConsole", int32(1))
+// SolveTSPPath calculates the shortest hamiltonian path from start to end. If both are the same, it's the tsp-tour
+// through start. The path is closed by the fixed edge between end and start, which doesn't count to its length.
+// The model is created in env with its parameters and doesn't log to the console. Errors are logged with the name
+// of the instance.
+func SolveTSPPath(env mip.Env, name string, d [][]float64, start, end int) ([]int32, float64, [][]int32) {
+	varCount := 0
+	N := len(d)
 
 	/* Create an empty model */
 
-	model, err := gurobiEnv.NewModel("tsp", 0, nil, nil, nil, nil, nil)
+	model, err := env.NewModel("tsp")
 	if err != nil {
-		log.Println(err)
+		log.Printf("At %s: %s\n", name, err.Error())
 		return nil, -1, nil
 	}
 	defer model.Free()
+	err = model.SetIntParam(mip.INT_PAR_LOGTOCONSOLE, 0)
+	if err != nil {
+		log.Printf("At %s: %s\n", name, err.Error())
+		return nil, -1, nil
+	}
 
 	/* Add variables X_ij - one for every pair of nodes where j > i weighted by the distance in the obj function*/
 	{
 		for i := 0; i < N; i++ {
 			for j := i + 1; j < N; j++ {
-				name := fmt.Sprintf("Y_%d_%d", i, j)
-				err = model.AddVar(nil, nil, float64(d[i][j]), 0.0, 1.0, gurobi.BINARY, name)
+				varName := fmt.Sprintf("Y_%d_%d", i, j)
+				obj, lb := d[i][j], 0.0
+				if start != end && ((i == start && j == end) || (i == end && j == start)) {
+					obj, lb = 0.0, 1.0
+				}
+				err = model.AddVar(obj, lb, 1.0, mip.BINARY, varName)
 				if err != nil {
 					log.Println(err)
 					return nil, -1, nil
@@ -197,10 +198,10 @@ func SolveTSP(d [][]int) ([]int32, int, [][]int32) {
 				ind = append(ind, int32(op.GetEdgeIndex(j, i, N, 0)))
 				val = append(val, 1.0)
 			}
-			err = model.AddConstr(ind, val, gurobi.EQUAL, 2.0, fmt.Sprintf("node_2_%d", i))
+			err = model.AddConstr(ind, val, mip.EQUAL, 2.0, fmt.Sprintf("node_2_%d", i))
 			if err != nil {
 				log.Printf("Error adding node_2_%d\n", i)
-				log.Printf("At %s: %s\n", os.Args[1], err.Error())
+				log.Printf("At %s: %s\n", name, err.Error())
 				return nil, -1, nil
 			}
 		}
@@ -208,7 +209,8 @@ func SolveTSP(d [][]int) ([]int32, int, [][]int32) {
 
 	/* Set callback function */
 
-	err = model.SetCallbackFuncGo(subtourelimTSP, SubData{N: int32(N)})
+	cbData := SubData{N: int32(N), varCount: varCount, subtours: make([][]int32, 0)}
+	err = model.SetCallbackFunc(subtourelimTSP, &cbData)
 	if err != nil {
 		log.Println(err)
 		return nil, -1, nil
@@ -216,7 +218,7 @@ func SolveTSP(d [][]int) ([]int32, int, [][]int32) {
 
 	/* Must set LazyConstraints parameter when using lazy constraints */
 
-	err = model.SetIntParam(gurobi.INT_PAR_LAZYCONSTRAINTS, 1)
+	err = model.SetIntParam(mip.INT_PAR_LAZYCONSTRAINTS, 1)
 	if err != nil {
 		log.Println(err)
 		return nil, -1, nil
@@ -231,25 +233,32 @@ func SolveTSP(d [][]int) ([]int32, int, [][]int32) {
 	}
 
 	/* Extract solution */
-	solcount, err := model.GetIntAttr(gurobi.INT_ATTR_SOLCOUNT)
+	solcount, err := model.GetIntAttr(mip.INT_ATTR_SOLCOUNT)
 	if err != nil {
 		log.Println(err)
 		return nil, -1, nil
 	}
 	if solcount > 0 {
-		sol, err := model.GetDblAttrArray(gurobi.DBL_ATTR_X, 0, int32(varCount))
+		sol, err := model.GetDblAttrArray(mip.DBL_ATTR_X, 0, int32(varCount))
 		if err != nil {
 			log.Println(err)
 			return nil, -1, nil
 		}
 		solA := extractEdgeMatrix(sol, N)
 		tour := findsubtour(solA)
-		length := 0
+		route := make([]int, len(tour))
 		for i := 0; i < len(tour); i++ {
-			j := (i + 1) % len(tour)
-			length += d[int(tour[i])][int(tour[j])]
+			route[i] = int(tour[i])
+		}
+		op.OrientRoute(route, start, end)
+		length := 0.0
+		for i := 0; i < len(route); i++ {
+			tour[i] = int32(route[i])
+			if i > 0 || start == end {
+				length += d[route[(i+len(route)-1)%len(route)]][route[i]]
+			}
 		}
-		return tour, length, subtours
+		return tour, length, cbData.subtours
 	}
 	return nil, -1, nil
 }
@@ -0,0 +1,138 @@
+package tsp
+
+import (
+	"git.solver4all.com/azaryc2s/op"
+	"sort"
+)
+
+/* Define structure to pass data through the search for a time-feasible sequence */
+
+type twSearch struct {
+	d        [][]float64
+	shortest [][]float64
+	service  []float64
+	windows  [][]float64
+	tmax     float64
+	start    int
+	end      int
+	visited  []byte
+	//the earliest time at which the service at a node has begun after visiting a set of nodes
+	earliest map[string]float64
+	sequence []int32
+}
+
+// SolveTSPTW looks for a sequence of all nodes from start to end (or back to start, if both are the same), that begins
+// the service at every node i within its time window [windows[i][0], windows[i][1]] and arrives at the end at most tmax
+// after the start opens. The vehicle waits at nodes that aren't open yet and spends service[i] at every node.
+// The search stops at the first such sequence, which is returned together with its duration. If there is none, the
+// returned sequence is nil.
+func SolveTSPTW(d [][]float64, service []float64, windows [][]float64, tmax float64, start, end int) ([]int32, float64) {
+	N := len(d)
+	//the shortest paths between the nodes are lower bounds for the arrival, even without the triangle inequality
+	shortest := make([][]float64, N)
+	for i := range d {
+		shortest[i] = append([]float64{}, d[i]...)
+	}
+	for k := 0; k < N; k++ {
+		for i := 0; i < N; i++ {
+			for j := 0; j < N; j++ {
+				if shortest[i][k]+shortest[k][j] < shortest[i][j] {
+					shortest[i][j] = shortest[i][k] + shortest[k][j]
+				}
+			}
+		}
+	}
+
+	s := twSearch{d: d, shortest: shortest, service: service, windows: windows, tmax: tmax, start: start, end: end,
+		visited: make([]byte, (N+7)/8), earliest: make(map[string]float64)}
+	s.visit(start)
+	s.sequence = append(s.sequence, int32(start))
+	duration, found := s.extend(start, windows[start][0], 1)
+	if !found {
+		return nil, -1
+	}
+	return s.sequence, duration
+}
+
+func (s *twSearch) isVisited(node int) bool {
+	return s.visited[node/8]&(1<<(node%8)) != 0
+}
+
+func (s *twSearch) visit(node int) {
+	s.visited[node/8] |= 1 << (node % 8)
+}
+
+func (s *twSearch) leave(node int) {
+	s.visited[node/8] &^= 1 << (node % 8)
+}
+
+//the earliest time the service at the node can begin when leaving last at time t
+func (s *twSearch) arrival(last int, t float64, node int, d [][]float64) float64 {
+	arrival := t + s.service[last] + d[last][node]
+	if arrival < s.windows[node][0] {
+		return s.windows[node][0]
+	}
+	return arrival
+}
+
+//extend the sequence ending at last, where the service began at time t, by the remaining nodes
+func (s *twSearch) extend(last int, t float64, count int) (float64, bool) {
+	N := len(s.d)
+	open := s.windows[s.start][0]
+	remaining := N - count
+	if s.start != s.end {
+		//the end depot is always the last node
+		remaining--
+	}
+	if remaining == 0 {
+		arrival := s.arrival(last, t, s.end, s.d)
+		if op.Exceeds(arrival, s.windows[s.end][1]) || op.Exceeds(arrival-open, s.tmax) {
+			return -1, false
+		}
+		if s.start != s.end {
+			s.sequence = append(s.sequence, int32(s.end))
+		}
+		return arrival - open, true
+	}
+
+	//every remaining node and the end have to be reachable in time, otherwise the sequence can't be completed
+	if bound := s.arrival(last, t, s.end, s.shortest); op.Exceeds(bound, s.windows[s.end][1]) || op.Exceeds(bound-open, s.tmax) {
+		return -1, false
+	}
+	var candidates []int
+	for node := 0; node < N; node++ {
+		if s.isVisited(node) || node == s.end {
+			continue
+		}
+		if op.Exceeds(s.arrival(last, t, node, s.shortest), s.windows[node][1]) {
+			return -1, false
+		}
+		candidates = append(candidates, node)
+	}
+	//nodes that close early are tried first
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return s.windows[candidates[a]][1] < s.windows[candidates[b]][1]
+	})
+
+	for _, node := range candidates {
+		arrival := s.arrival(last, t, node, s.d)
+		if op.Exceeds(arrival, s.windows[node][1]) {
+			continue
+		}
+		s.visit(node)
+		//reaching the same nodes later than before can't lead to a sequence the earlier visit didn't find
+		key := string(s.visited) + string(rune(node))
+		if best, ok := s.earliest[key]; ok && !op.Exceeds(best, arrival) {
+			s.leave(node)
+			continue
+		}
+		s.earliest[key] = arrival
+		s.sequence = append(s.sequence, int32(node))
+		if duration, found := s.extend(node, arrival, count+1); found {
+			return duration, true
+		}
+		s.sequence = s.sequence[:len(s.sequence)-1]
+		s.leave(node)
+	}
+	return -1, false
+}
@@ -1,34 +1,133 @@
 package op
 
 type Instance struct {
+	Version int    `json:"version"`
 	Name    string `json:"name"`
 	Comment string `json:"comment"`
 	Type    string `json:"type"`
 
-	Dimension       int         `json:"dimension"`
-	DisplayDataType string      `json:"display_data_type"`
-	EdgeWeightType  string      `json:"edge_weight_type"`
-	Depots          []int       `json:"depots"`
-	NodeCoordinates [][]float64 `json:"node_coordinates"`
-	EdgeWeights     [][]int     `json:"edge_weights"`
-	Prices          []int       `json:"prices"`
-	TMax            int         `json:"tmax"`
-	TSPLength       int         `json:"tsp_length"`
+	Dimension        int         `json:"dimension"`
+	DisplayDataType  string      `json:"display_data_type"`
+	EdgeWeightType   string      `json:"edge_weight_type"`
+	EdgeWeightFormat string      `json:"edge_weight_format"`
+	Depots           []int       `json:"depots"`
+	Vehicles         int         `json:"vehicles"`
+	NodeCoordinates  [][]float64 `json:"node_coordinates"`
+	EdgeWeights      [][]float64 `json:"edge_weights"`
+	Prices           []float64   `json:"prices"`
+	TMax             float64     `json:"tmax"`
+	TSPLength        float64     `json:"tsp_length"`
+	TimeWindows      [][]float64 `json:"time_windows"`
+	ServiceTimes     []float64   `json:"service_times"`
+	Clusters         [][]int     `json:"clusters"`
+	ClusterPrices    []float64   `json:"cluster_prices"`
 
-	Solution *Solution
+	Solutions []*Solution `json:"solutions"`
+	//the single solution of the format version 1, which is moved to Solutions when the file is loaded
+	Solution *Solution `json:"Solution,omitempty"`
 }
 
 type Solution struct {
-	Obj       int   `json:"obj"`
-	LBound    int   `json:"lbound"`
-	UBound    int   `json:"ubound"`
-	Optimal   bool  `json:"optimal"`
-	RouteCost int   `json:"route_cost"`
-	Route     []int `json:"route"`
-
-	Time    string  `json:"time"`
-	System  SysInfo `json:"system"`
-	Comment string  `json:"comment"`
+	Config     SolverConfig      `json:"config"`
+	Obj        float64           `json:"obj"`
+	LBound     float64           `json:"lbound"`
+	UBound     float64           `json:"ubound"`
+	Optimal    bool              `json:"optimal"`
+	RouteCost  float64           `json:"route_cost"`
+	Route      []int             `json:"route"`
+	Routes     [][]int           `json:"routes"`
+	Objective  string            `json:"objective"`
+	MinPrize   float64           `json:"min_prize"`
+	Lambda     float64           `json:"lambda"`
+	Front      []FrontPoint      `json:"front"`
+	Reduction  *Reduction        `json:"reduction"`
+	Statistics *Statistics       `json:"statistics"`
+	Trajectory []TrajectoryPoint `json:"trajectory"`
+	Params     string            `json:"params"` //the parameter file of the backend as it was read
+	Status     string            `json:"status"`
+
+	Time         string        `json:"time"`
+	System       SysInfo       `json:"system"`
+	Comment      string        `json:"comment"`
+	Verification *Verification `json:"verification"`
+}
+
+// STATUS_INTERRUPTED is the status of a solution, whose solve was stopped by a signal. It keeps the best route and
+// bound found until then
+const STATUS_INTERRUPTED = "interrupted"
+
+// SolverConfig is the configuration of the solver, that computed a solution. The solutions of an instance are kept
+// apart by it, see Solution.Key
+type SolverConfig struct {
+	Command  string   `json:"command"`
+	Strategy string   `json:"strategy"`
+	SubStrat string   `json:"sub_strategy"`
+	Cuts     []string `json:"cuts"`
+	YBounds  string   `json:"y_bounds"`
+	Sweep    bool     `json:"sweep"`
+	Backend  string   `json:"backend"`
+}
+
+// FrontPoint is a breakpoint of the Pareto front of the prize versus the budget. Every budget from Budget up to the
+// budget of the next breakpoint collects the same prize with the route of this one
+type FrontPoint struct {
+	Budget    float64 `json:"budget"`
+	Prize     float64 `json:"prize"`
+	RouteCost float64 `json:"route_cost"`
+	Route     []int   `json:"route"`
+	Optimal   bool    `json:"optimal"`
+	Time      string  `json:"time"`
+}
+
+// Reduction reports what the preprocessing removed from the model, because no route within the budget can visit the
+// nodes or use the edges. The removed edges include the edges of the removed nodes.
+type Reduction struct {
+	Nodes        int   `json:"nodes"`
+	Edges        int   `json:"edges"`
+	RemovedNodes []int `json:"removed_nodes"`
+	RemovedEdges int   `json:"removed_edges"`
+}
+
+// Statistics describes the course of the solve. The bounds are the ones of the objective of the solution, the times
+// are measured from the start of the optimization.
+type Statistics struct {
+	Cuts            []CutCount `json:"cuts"`
+	MasterCallbacks int        `json:"master_callbacks"` //integer master solutions checked in the callback
+	NodeCallbacks   int        `json:"node_callbacks"`
+	MasterSolves    int        `json:"master_solves"` //the LBBD solves the master once per iteration
+	Subproblems     int        `json:"subproblems"`   //TSPs, or OPs and TOPs, solved for the nodes of master solutions
+	SubproblemTime  string     `json:"subproblem_time"`
+	Nodes           int64      `json:"nodes"`
+	RootBound       float64    `json:"root_bound"`
+	FirstIncumbent  string     `json:"first_incumbent"`
+	BestIncumbent   string     `json:"best_incumbent"`
+	Flags           []string   `json:"flags"`
+	Threads         int        `json:"threads"`
+	Backend         string     `json:"backend"`
+}
+
+// TrajectoryPoint is the state of the solve at the time in seconds since the start of the optimization. The incumbent
+// and the bound are the ones of the objective of the solution. Before the first solution was found, HasIncumbent is false.
+type TrajectoryPoint struct {
+	Time         float64 `json:"time"`
+	Incumbent    float64 `json:"incumbent"`
+	HasIncumbent bool    `json:"has_incumbent"`
+	Bound        float64 `json:"bound"`
+	Nodes        int64   `json:"nodes"`
+}
+
+// CutCount is the number of constraints of a family of cuts, that were added to the model
+type CutCount struct {
+	Family string `json:"family"`
+	Count  int    `json:"count"`
+}
+
+// Sidecar holds the solutions of an instance in a file of its own. The hash ties it to the content of the instance.
+type Sidecar struct {
+	Version      int         `json:"version"`
+	InstanceName string      `json:"instance_name"`
+	InstanceHash string      `json:"instance_hash"`
+	Solutions    []*Solution `json:"solutions"`
 }
 
 // SysInfo saves the basic system information
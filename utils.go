@@ -2,9 +2,7 @@ package op
 
 import (
 	"fmt"
-	"git.solver4all.com/azaryc2s/gorobi/gurobi"
-	"math"
-	"regexp"
+	"git.solver4all.com/azaryc2s/op/mip"
 )
 
 
@@ -21,27 +19,6 @@ func GetEdgeIndex(i, j, N, start int) int {
 	return count
 }
 
-func CalcEdgeDist(coordinates [][]float64, distType string) [][]int {
-	n := len(coordinates)
-	result := make([][]int, n)
-	for node := 0; node < n; node++ {
-		result[node] = make([]int, n)
-		for node2 := 0; node2 < node; node2++ {
-			xDist := coordinates[node][0] - coordinates[node2][0]
-			yDist := coordinates[node][1] - coordinates[node2][1]
-			var distance int
-			if distType == "EUC_2D" {
-				distance = int(math.Sqrt(math.Pow(xDist, 2)+math.Pow(yDist, 2)) + 0.5)
-			} else if distType == "CEIL_2D" {
-				distance = int(math.Ceil(math.Sqrt(math.Pow(xDist, 2) + math.Pow(yDist, 2))))
-			}
-			result[node][node2] = distance
-			result[node2][node] = distance
-		}
-	}
-	return result
-}
-
 func GetSECs(subtours [][]int32, N int, start int) (secInd [][]int32, secVal [][]float64, op int8, rhs []float64) {
 	for _, stour := range subtours {
 		var (
@@ -68,28 +45,15 @@ func GetSECs(subtours [][]int32, N int, start int) (secInd [][]int32, secVal [][
 		secVal = append(secVal, val)
 		rhs = append(rhs, float64(len(stour)-1))
 	}
-	return secInd, secVal, gurobi.LESS_EQUAL, rhs
+	return secInd, secVal, mip.LESS_EQUAL, rhs
 }
 
 
-func Print2DArray(a [][]int) {
+func Print2DArray(a [][]float64) {
 	for _, x := range a {
 		for _, y := range x {
-			fmt.Printf("%d,", y)
+			fmt.Printf("%g,", y)
 		}
 		fmt.Println("")
 	}
 }
-
-func SanitizeJsonArrayLineBreaks(json string) string {
-	res := fmt.Sprintf("%s", json)
-	var numbers = regexp.MustCompile(`\s*([0-9]+),\s+([0-9]+)(,)?`)
-	var brackets = regexp.MustCompile(`\[(([0-9]+,)+[0-9]+)\s+\](,?)(\s+)`)
-	for numbers.MatchString(res) {
-		res = numbers.ReplaceAllString(res, "$1,$2$3")
-	}
-	for brackets.MatchString(res) {
-		res = brackets.ReplaceAllString(res, "[$1]$3$4")
-	}
-	return res
-}
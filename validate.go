@@ -0,0 +1,191 @@
+package op
+
+import (
+	"fmt"
+	"math"
+)
+
+// Problem is a single finding of Validate. Field is the path of the offending value in the json
+// representation of the instance, e.g. "prices[3]" or "node_coordinates[2][1]".
+type Problem struct {
+	Field   string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Field, p.Message)
+}
+
+// Validate checks the instance for inconsistencies that would otherwise only show up as a crash or
+// a wrong result deep inside the solver. An empty result means the instance can be solved.
+func (inst *Instance) Validate() []Problem {
+	var problems []Problem
+	add := func(field string, format string, a ...interface{}) {
+		problems = append(problems, Problem{Field: field, Message: fmt.Sprintf(format, a...)})
+	}
+	n := inst.Dimension
+
+	if n < 3 {
+		add("dimension", "must be at least 3, is %d", n)
+	}
+	if len(inst.Prices) != n {
+		add("prices", "has %d entries for dimension %d", len(inst.Prices), n)
+	}
+	for i, p := range inst.Prices {
+		if math.IsNaN(p) || math.IsInf(p, 0) {
+			add(fmt.Sprintf("prices[%d]", i), "is %v", p)
+		} else if p < 0 {
+			add(fmt.Sprintf("prices[%d]", i), "negative price %g", p)
+		}
+	}
+	if math.IsNaN(inst.TMax) || math.IsInf(inst.TMax, 0) {
+		add("tmax", "is %v", inst.TMax)
+	} else if inst.TMax < 0 {
+		add("tmax", "negative travel budget %g", inst.TMax)
+	}
+	if inst.Vehicles < 0 {
+		add("vehicles", "negative number of vehicles %d", inst.Vehicles)
+	}
+	if len(inst.ServiceTimes) > 0 && len(inst.ServiceTimes) != n {
+		add("service_times", "has %d entries for dimension %d", len(inst.ServiceTimes), n)
+	}
+	for i, s := range inst.ServiceTimes {
+		if math.IsNaN(s) || math.IsInf(s, 0) {
+			add(fmt.Sprintf("service_times[%d]", i), "is %v", s)
+		} else if s < 0 {
+			add(fmt.Sprintf("service_times[%d]", i), "negative service time %g", s)
+		}
+	}
+	if inst.HasTimeWindows() && len(inst.TimeWindows) != n {
+		add("time_windows", "has %d entries for dimension %d", len(inst.TimeWindows), n)
+	}
+	for i, w := range inst.TimeWindows {
+		if len(w) != 2 {
+			add(fmt.Sprintf("time_windows[%d]", i), "has %d values instead of the opening and closing time", len(w))
+		} else if math.IsNaN(w[0]) || math.IsNaN(w[1]) || math.IsInf(w[0], 0) {
+			add(fmt.Sprintf("time_windows[%d]", i), "is %v", w)
+		} else if w[0] > w[1] {
+			add(fmt.Sprintf("time_windows[%d]", i), "opens at %g after it closes at %g", w[0], w[1])
+		}
+	}
+
+	if len(inst.ClusterPrices) != len(inst.Clusters) {
+		add("cluster_prices", "has %d entries for %d clusters", len(inst.ClusterPrices), len(inst.Clusters))
+	}
+	for c, p := range inst.ClusterPrices {
+		if math.IsNaN(p) || math.IsInf(p, 0) {
+			add(fmt.Sprintf("cluster_prices[%d]", c), "is %v", p)
+		} else if p < 0 {
+			add(fmt.Sprintf("cluster_prices[%d]", c), "negative price %g", p)
+		}
+	}
+	for c, cluster := range inst.Clusters {
+		if len(cluster) == 0 {
+			add(fmt.Sprintf("clusters[%d]", c), "has no nodes")
+		}
+		for k, node := range cluster {
+			if node < 0 || node >= n {
+				add(fmt.Sprintf("clusters[%d][%d]", c, k), "node %d is not a node of the instance", node)
+			}
+		}
+	}
+
+	if len(inst.Depots) > 2 {
+		add("depots", "at most a start and an end depot can be given, got %d", len(inst.Depots))
+	}
+	depotsValid := true
+	for i, d := range inst.Depots {
+		if d < 0 || d >= n {
+			add(fmt.Sprintf("depots[%d]", i), "depot %d is not a node of the instance", d)
+			depotsValid = false
+		}
+	}
+	if depotsValid {
+		start, end := inst.GetDepots()
+		depots := []int{start}
+		if end != start {
+			depots = append(depots, end)
+		}
+		for _, d := range depots {
+			if d < len(inst.Prices) && inst.Prices[d] != 0 {
+				add(fmt.Sprintf("prices[%d]", d), "depot %d has the price %g instead of 0", d, inst.Prices[d])
+			}
+		}
+	}
+
+	if len(inst.EdgeWeights) == 0 || len(inst.NodeCoordinates) > 0 {
+		if len(inst.NodeCoordinates) != n {
+			add("node_coordinates", "has %d entries for dimension %d", len(inst.NodeCoordinates), n)
+		}
+		for i, c := range inst.NodeCoordinates {
+			if len(c) < 2 || len(c) > 3 {
+				add(fmt.Sprintf("node_coordinates[%d]", i), "has %d coordinates instead of 2 or 3", len(c))
+			}
+			for k, v := range c {
+				if math.IsNaN(v) || math.IsInf(v, 0) {
+					add(fmt.Sprintf("node_coordinates[%d][%d]", i, k), "is %v", v)
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		//the distances can't be calculated safely
+		return problems
+	}
+
+	d, err := inst.GetDistanceMatrix()
+	if err != nil {
+		if len(inst.EdgeWeights) > 0 {
+			add("edge_weights", "%s", err.Error())
+		} else {
+			add("edge_weight_type", "%s", err.Error())
+		}
+		return problems
+	}
+	if d.Len() != n {
+		add("edge_weights", "describes %d nodes for dimension %d", d.Len(), n)
+		return problems
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if dist := d.Dist(i, j); math.IsNaN(dist) || math.IsInf(dist, 0) {
+				add("edge_weights", "distance from node %d to %d is %v", i, j, dist)
+			} else if i != j && dist < 0 {
+				add("edge_weights", "negative distance %g from node %d to %d", dist, i, j)
+			}
+		}
+	}
+
+	if shortest := inst.shortestRoute(d); Exceeds(shortest, inst.TMax) {
+		add("tmax", "travel budget %g is smaller than the shortest route through the depots with duration %g", inst.TMax, shortest)
+	}
+	return problems
+}
+
+// shortestRoute returns the duration of the shortest route the models can build - a tour through the depot and two
+// other nodes, or a path from the start to the end depot through one other node
+func (inst *Instance) shortestRoute(d DistanceMatrix) float64 {
+	start, end := inst.GetDepots()
+	shortest := math.Inf(1)
+	for i := 0; i < d.Len(); i++ {
+		if i == start || i == end {
+			continue
+		}
+		if start != end {
+			if l := d.Dist(start, i) + d.Dist(i, end) + inst.GetServiceTime(start) + inst.GetServiceTime(i); l < shortest {
+				shortest = l
+			}
+			continue
+		}
+		for j := 0; j < d.Len(); j++ {
+			if j == start || j == i {
+				continue
+			}
+			service := inst.GetServiceTime(start) + inst.GetServiceTime(i) + inst.GetServiceTime(j)
+			if l := d.Dist(start, i) + d.Dist(i, j) + d.Dist(j, start) + service; l < shortest {
+				shortest = l
+			}
+		}
+	}
+	return shortest
+}
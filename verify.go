@@ -0,0 +1,131 @@
+package op
+
+import "fmt"
+
+// Verification is the report of VerifySolution. Length and Prize are recomputed from the routes,
+// the other fields list what is wrong with the solution.
+type Verification struct {
+	Valid          bool      `json:"valid"`
+	Length         float64   `json:"length"`
+	Prize          float64   `json:"prize"`
+	RouteLengths   []float64 `json:"route_lengths"`
+	ObjMismatch    bool      `json:"obj_mismatch"`
+	LengthMismatch bool      `json:"length_mismatch"`
+	BudgetExceeded bool      `json:"budget_exceeded"`
+	PrizeMissing   bool      `json:"prize_missing"`
+	DuplicateNodes []int     `json:"duplicate_nodes"`
+	InvalidNodes   []int     `json:"invalid_nodes"`
+	MissingDepots  []int     `json:"missing_depots"`
+	LateNodes      []int     `json:"late_nodes"`
+	Problems       []string  `json:"problems"`
+}
+
+// VerifySolution recomputes the length and prize of the routes in the solution and checks them against
+// the instance: at most one route per vehicle, every node except the depots at most once, every route
+// begins at the start depot (and ends at the end depot for a path) and keeps the travel budget, every node
+// is served within its time window, and the reported Obj and RouteCost (the length of all routes together)
+// are correct. The budget limits the duration of a route, which includes the service times and the waiting
+// for time windows to open. Obj is checked for the objective of the solution, which for the PCTSP
+// requires the minimum prize and for the PCTSP and PTP ignores the travel budget.
+func VerifySolution(inst *Instance, sol *Solution) Verification {
+	v := Verification{}
+	problem := func(format string, a ...interface{}) {
+		v.Problems = append(v.Problems, fmt.Sprintf(format, a...))
+	}
+	d, err := inst.GetDistanceMatrix()
+	if err != nil {
+		problem("Couldn't calculate the distances: %s", err.Error())
+		return v
+	}
+	routes := sol.GetRoutes()
+	if len(routes) == 0 {
+		problem("The route is empty")
+		return v
+	}
+	if len(routes) > inst.GetVehicles() {
+		problem("The solution has %d routes but only %d vehicles are available", len(routes), inst.GetVehicles())
+	}
+
+	start, end := inst.GetDepots()
+	seen := make([]bool, d.Len())
+	for r, solRoute := range routes {
+		name := "route"
+		if len(routes) > 1 {
+			name = fmt.Sprintf("route %d", r)
+		}
+		if len(solRoute) == 0 {
+			problem("The %s is empty", name)
+			continue
+		}
+		var route []int
+		inRoute := make(map[int]bool)
+		for _, node := range solRoute {
+			if node < 0 || node >= d.Len() {
+				v.InvalidNodes = append(v.InvalidNodes, node)
+				continue
+			}
+			//the depots are part of every route
+			if inRoute[node] || (seen[node] && node != start && node != end) {
+				v.DuplicateNodes = append(v.DuplicateNodes, node)
+				continue
+			}
+			if !seen[node] && node < len(inst.Prices) {
+				v.Prize += inst.Prices[node]
+			}
+			inRoute[node] = true
+			seen[node] = true
+			route = append(route, node)
+		}
+		length := inst.GetRouteLength(route, d)
+		v.RouteLengths = append(v.RouteLengths, length)
+		v.Length += length
+
+		var missing []int
+		if start >= 0 && start < d.Len() && !inRoute[start] {
+			missing = append(missing, start)
+		}
+		if end != start && end >= 0 && end < d.Len() && !inRoute[end] {
+			missing = append(missing, end)
+		}
+		if len(missing) > 0 {
+			v.MissingDepots = append(v.MissingDepots, missing...)
+			problem("Depots %v are not visited by the %s", missing, name)
+		} else if solRoute[0] != start || (end != start && solRoute[len(solRoute)-1] != end) {
+			problem("The %s doesn't start at depot %d and end at depot %d", name, start, end)
+		}
+		if _, duration := inst.ScheduleRoute(route, d); sol.HasTravelBudget() && Exceeds(duration, inst.TMax) {
+			v.BudgetExceeded = true
+			if NearlyEqual(duration, length) {
+				problem("The %s has a length of %g, which exceeds the max allowed length %g", name, length, inst.TMax)
+			} else {
+				problem("The %s has a duration of %g, which exceeds the max allowed duration %g", name, duration, inst.TMax)
+			}
+		}
+		if late := inst.TimeWindowViolations(route, d); len(late) > 0 {
+			v.LateNodes = append(v.LateNodes, late...)
+			problem("The %s reaches nodes %v after their time windows close", name, late)
+		}
+	}
+	v.Prize += inst.GetClusterPrize(seen)
+	if len(v.InvalidNodes) > 0 {
+		problem("Nodes %v are not part of the instance", v.InvalidNodes)
+	}
+	if len(v.DuplicateNodes) > 0 {
+		problem("Nodes %v are visited more than once", v.DuplicateNodes)
+	}
+
+	if sol.GetObjective() == OBJ_PCTSP && Exceeds(sol.MinPrize, v.Prize) {
+		v.PrizeMissing = true
+		problem("Route collects a prize of %g, which is less than the min prize %g", v.Prize, sol.MinPrize)
+	}
+	if obj := sol.ObjectiveValue(v.Prize, v.Length); !NearlyEqual(obj, sol.Obj) {
+		v.ObjMismatch = true
+		problem("Route has a obj value of %g but the solution says %g", obj, sol.Obj)
+	}
+	if !NearlyEqual(v.Length, sol.RouteCost) {
+		v.LengthMismatch = true
+		problem("Route has a length of %g but the solution says %g", v.Length, sol.RouteCost)
+	}
+	v.Valid = len(v.Problems) == 0
+	return v
+}